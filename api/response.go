@@ -1,7 +1,9 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/simple-crud-go/internal/models"
@@ -13,6 +15,12 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 }
 
+type ValidationErrorResponse struct {
+	Error   bool                `json:"error"`
+	Message string              `json:"message"`
+	Fields  map[string][]string `json:"fields"`
+}
+
 type NoDataResponse struct {
 	Error   bool   `json:"error"`
 	Message string `json:"message"`
@@ -23,6 +31,97 @@ type RegisterSuccessResponse struct {
 	User  *models.User `json:"user"`
 }
 
+type APIKeyCreatedResponse struct {
+	// Key the plain API key, it is only ever returned once, when the key is created.
+	Key    string         `json:"key"`
+	APIKey *models.APIKey `json:"api_key"`
+}
+
+type OAuthClientCreatedResponse struct {
+	// ClientSecret only returned once, when the client is registered. Public clients don't have one.
+	ClientSecret string              `json:"client_secret,omitempty"`
+	Client       *models.OAuthClient `json:"client"`
+}
+
+type OAuthAuthorizationResponse struct {
+	Client         *models.OAuthClient `json:"client"`
+	Scopes         []string            `json:"scopes"`
+	ConsentGranted bool                `json:"consent_granted"`
+}
+
+type OAuthRedirectResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// OAuthTokenResponse successful token response (RFC 6749 section 5.1).
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthIntrospectionResponse token introspection response (RFC 7662 section 2.2).
+type OAuthIntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// OAuthErrorResponse error response of the OAuth endpoints (RFC 6749 section 5.2).
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+type NotificationsResponse struct {
+	Notifications []models.Notification `json:"notifications"`
+	UnreadCount   int64                 `json:"unread_count"`
+}
+
+type WebhookCreatedResponse struct {
+	// Secret signs the payloads, it is only ever returned once, when the webhook is created.
+	Secret  string          `json:"secret"`
+	Webhook *models.Webhook `json:"webhook"`
+}
+
+const (
+	HealthOK = "ok"
+	// HealthDegraded a replica is down, its reads go to the primary.
+	HealthDegraded = "degraded"
+	// HealthUnavailable the primary database doesn't answer.
+	HealthUnavailable = "unavailable"
+)
+
+type HealthResponse struct {
+	Status    string           `json:"status"`
+	Databases []DatabaseHealth `json:"databases"`
+}
+
+// DatabaseHealth the replicas are reported as of their last health check.
+type DatabaseHealth struct {
+	Name    string    `json:"name"`
+	Healthy bool      `json:"healthy"`
+	Pool    PoolStats `json:"pool"`
+}
+
+// PoolStats of the connections to a database, the counts of waits and closed connections add up since the start.
+type PoolStats struct {
+	MaxOpen           int   `json:"max_open"`
+	Open              int   `json:"open"`
+	InUse             int   `json:"in_use"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"wait_count"`
+	WaitDurationMs    int64 `json:"wait_duration_ms"`
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
 type GenericSuccessResponse[T any] struct {
 	Error bool `json:"error"`
 	Data  T    `json:"data"`
@@ -45,9 +144,48 @@ var (
 		writeError(w, err.Error(), code)
 	}
 	InternalErrorHandler = func(w http.ResponseWriter, err any) {
+		if err, ok := err.(error); ok && (errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)) {
+			// The deadline of the route passed or the client went away, the query was cancelled on purpose
+			logrus.Warn(err)
+			writeError(w, "The request took too long.", http.StatusServiceUnavailable)
+			return
+		}
+
 		logrus.Error(err)
 		writeError(w, "An Unexpected Error Occured.", http.StatusInternalServerError)
 	}
+	ValidationErrorHandler = func(w http.ResponseWriter, fields map[string][]string) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+
+		json.NewEncoder(w).Encode(ValidationErrorResponse{
+			Error:   true,
+			Message: "Some fields are invalid",
+			Fields:  fields,
+		})
+	}
+)
+
+// writeOAuthResponse OAuth clients expect the bare RFC 6749 JSON bodies instead of our usual envelope.
+func writeOAuthResponse(w http.ResponseWriter, code int, response interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	json.NewEncoder(w).Encode(response)
+}
+
+var (
+	OAuthResponseHandler = func(w http.ResponseWriter, response any) {
+		writeOAuthResponse(w, http.StatusOK, response)
+	}
+	OAuthErrorHandler = func(w http.ResponseWriter, code int, errorCode string, description string) {
+		if code == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+
+		writeOAuthResponse(w, code, OAuthErrorResponse{Error: errorCode, ErrorDescription: description})
+	}
 )
 
 func writeSuccessResponse(w http.ResponseWriter, code int, response interface{}) {
@@ -0,0 +1,16 @@
+// Package cache the caches the repositories keep their lookups in.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores encoded values under a key for a limited time. An external cache only has to implement it to replace
+// the in-process LRU, the values are already encoded so they can be sent as is.
+type Cache interface {
+	// Get the value under `key`, false when there is none or it expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
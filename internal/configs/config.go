@@ -3,6 +3,9 @@ package configs
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -22,6 +25,95 @@ func GetPort() string {
 	return getEnv("PORT", "5000")
 }
 
+// GetRequestTimeout how long a request may take before its database queries are cancelled, 0 disables the deadline.
+func GetRequestTimeout() time.Duration {
+	return time.Duration(getEnvInt("REQUEST_TIMEOUT_SECONDS", 15)) * time.Second
+}
+
+// GetRouteTimeouts overrides of the request timeout, formatted "GET /api/post=5,POST /api/login=10" with the
+// path template of the route and a number of seconds, 0 disables the deadline of the route.
+func GetRouteTimeouts() map[string]time.Duration {
+	timeouts := map[string]time.Duration{}
+
+	for _, entry := range strings.Split(getEnv("ROUTE_TIMEOUTS", ""), ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		route, rawSeconds, _ := strings.Cut(entry, "=")
+		seconds, err := strconv.Atoi(strings.TrimSpace(rawSeconds))
+		if err != nil || seconds < 0 {
+			logrus.Warn(fmt.Sprintf("ENV Variable 'ROUTE_TIMEOUTS' has an invalid entry '%v', ignoring it", entry))
+			continue
+		}
+
+		timeouts[strings.Join(strings.Fields(route), " ")] = time.Duration(seconds) * time.Second
+	}
+
+	return timeouts
+}
+
+// GetDBMigrateOnStart whether the server applies the pending migrations before starting, otherwise it refuses to
+// start until they are applied with `migrate up`.
+func GetDBMigrateOnStart() bool {
+	return getEnvBool("DB_MIGRATE_ON_START", false)
+}
+
+// GetDBMigrationLockTimeout how long to wait for another instance that is migrating the database.
+func GetDBMigrationLockTimeout() time.Duration {
+	return time.Duration(getEnvInt("DB_MIGRATION_LOCK_TIMEOUT_SECONDS", 60)) * time.Second
+}
+
+// GetDBMaxOpenConns the most connections open to the database at once, queries wait for a free one beyond that.
+// 0 is unlimited.
+func GetDBMaxOpenConns() int {
+	return getEnvInt("DB_MAX_OPEN_CONNS", 25)
+}
+
+// GetDBMaxIdleConns how many unused connections are kept open.
+func GetDBMaxIdleConns() int {
+	return getEnvInt("DB_MAX_IDLE_CONNS", 10)
+}
+
+// GetDBConnMaxLifetime how long a connection is reused before it is replaced, 0 keeps them forever.
+func GetDBConnMaxLifetime() time.Duration {
+	return time.Duration(getEnvInt("DB_CONN_MAX_LIFETIME_SECONDS", 1800)) * time.Second
+}
+
+// GetDBConnMaxIdleTime how long an unused connection stays open, 0 keeps them forever.
+func GetDBConnMaxIdleTime() time.Duration {
+	return time.Duration(getEnvInt("DB_CONN_MAX_IDLE_SECONDS", 300)) * time.Second
+}
+
+// GetDBConnectTimeout how long the server waits at startup for the database to accept connections.
+func GetDBConnectTimeout() time.Duration {
+	return time.Duration(getEnvInt("DB_CONNECT_TIMEOUT_SECONDS", 60)) * time.Second
+}
+
+// GetDBReplicaDSNs the read replicas, comma separated DSNs in the format of the driver (the path of the file for SQLite).
+// Every query goes to the primary when empty.
+func GetDBReplicaDSNs() []string {
+	var dsns []string
+
+	for _, dsn := range strings.Split(getEnv("DB_REPLICA_DSNS", ""), ",") {
+		if dsn = strings.TrimSpace(dsn); dsn != "" {
+			dsns = append(dsns, dsn)
+		}
+	}
+
+	return dsns
+}
+
+// GetDBReplicaHealthInterval how often the replicas are pinged, a replica that doesn't answer gets no reads until it does.
+func GetDBReplicaHealthInterval() time.Duration {
+	return time.Duration(getEnvInt("DB_REPLICA_HEALTH_SECONDS", 5)) * time.Second
+}
+
+// GetDBDriver one of mysql, postgres or sqlite.
+func GetDBDriver() string {
+	return getEnv("DB_DRIVER", "mysql")
+}
+
 func GetDBHOST() string {
 	return getEnv("DB_HOST", "localhost")
 }
@@ -42,6 +134,170 @@ func GetDBNAME() string {
 	return getEnv("DB_NAME", "simple")
 }
 
+// GetDBSSLMode sslmode of the PostgreSQL connection.
+func GetDBSSLMode() string {
+	return getEnv("DB_SSLMODE", "disable")
+}
+
+// GetDBPath the SQLite database file.
+func GetDBPath() string {
+	return getEnv("DB_PATH", "simple.db")
+}
+
 func GetJWTSecret() string {
 	return getEnv("JWT_SECRET", "")
 }
+
+func GetJWTIssuer() string {
+	return getEnv("JWT_ISSUER", "simple-crud")
+}
+
+func GetJWTAudience() string {
+	return getEnv("JWT_AUDIENCE", "simple-crud-api")
+}
+
+// GetJWTLeeway tolerated clock skew when checking the expiry of a token.
+func GetJWTLeeway() time.Duration {
+	return time.Duration(getEnvInt("JWT_LEEWAY_SECONDS", 30)) * time.Second
+}
+
+// GetJWTAcceptLegacyTokens whether tokens issued before `sub`/`iss`/`aud` were used are still accepted,
+// disable it once the last of them expired (they were valid for 7 days).
+func GetJWTAcceptLegacyTokens() bool {
+	return getEnvBool("JWT_ACCEPT_LEGACY_TOKENS", true)
+}
+
+func getEnvInt(name string, defaultValue int) int {
+	res, err := strconv.Atoi(getEnv(name, strconv.Itoa(defaultValue)))
+	if err != nil {
+		logrus.Warn(fmt.Sprintf("ENV Variable '%v' is not a valid number, using '%v' instead", name, defaultValue))
+		return defaultValue
+	}
+
+	return res
+}
+
+func getEnvBool(name string, defaultValue bool) bool {
+	res, err := strconv.ParseBool(getEnv(name, strconv.FormatBool(defaultValue)))
+	if err != nil {
+		logrus.Warn(fmt.Sprintf("ENV Variable '%v' is not a valid boolean, using '%v' instead", name, defaultValue))
+		return defaultValue
+	}
+
+	return res
+}
+
+func GetPasswordMinLength() int {
+	return getEnvInt("PASSWORD_MIN_LENGTH", 8)
+}
+
+func GetPasswordRequireUpper() bool {
+	return getEnvBool("PASSWORD_REQUIRE_UPPER", true)
+}
+
+func GetPasswordRequireLower() bool {
+	return getEnvBool("PASSWORD_REQUIRE_LOWER", true)
+}
+
+func GetPasswordRequireDigit() bool {
+	return getEnvBool("PASSWORD_REQUIRE_DIGIT", true)
+}
+
+func GetPasswordRequireSymbol() bool {
+	return getEnvBool("PASSWORD_REQUIRE_SYMBOL", false)
+}
+
+func GetPasswordDisallowPersonalInfo() bool {
+	return getEnvBool("PASSWORD_DISALLOW_PERSONAL_INFO", true)
+}
+
+// GetPasswordBreachedFile path to a file of SHA-1 password hashes, when empty the bundled list is used.
+func GetPasswordBreachedFile() string {
+	return getEnv("PASSWORD_BREACHED_FILE", "")
+}
+
+// GetOIDCIssuerURL OpenID Connect social login is disabled while this is empty.
+func GetOIDCIssuerURL() string {
+	return getEnv("OIDC_ISSUER_URL", "")
+}
+
+func GetOIDCProviderName() string {
+	return getEnv("OIDC_PROVIDER_NAME", "oidc")
+}
+
+func GetOIDCClientID() string {
+	return getEnv("OIDC_CLIENT_ID", "")
+}
+
+func GetOIDCClientSecret() string {
+	return getEnv("OIDC_CLIENT_SECRET", "")
+}
+
+func GetOIDCRedirectURL() string {
+	return getEnv("OIDC_REDIRECT_URL", "http://localhost:5000/api/auth/oidc/callback")
+}
+
+// GetStreamBufferSize number of events kept in memory for the stream clients resuming with Last-Event-ID.
+func GetStreamBufferSize() int {
+	return getEnvInt("STREAM_BUFFER_SIZE", 1000)
+}
+
+// GetStreamHeartbeat interval of the comments sent on idle streams so proxies don't close them.
+func GetStreamHeartbeat() time.Duration {
+	return time.Duration(getEnvInt("STREAM_HEARTBEAT_SECONDS", 15)) * time.Second
+}
+
+// GetWebhookTimeout how long a webhook endpoint has to answer a delivery.
+func GetWebhookTimeout() time.Duration {
+	return time.Duration(getEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second
+}
+
+// GetWebhookMaxAttempts a delivery is given up after that many attempts.
+func GetWebhookMaxAttempts() int {
+	return getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8)
+}
+
+// GetWebhookRetryBase delay before the first retry of a delivery, doubled after each failed attempt.
+func GetWebhookRetryBase() time.Duration {
+	return time.Duration(getEnvInt("WEBHOOK_RETRY_BASE_SECONDS", 30)) * time.Second
+}
+
+// GetWebhookDisableAfter consecutive failed attempts after which a webhook is disabled, 0 never disables them.
+func GetWebhookDisableAfter() int {
+	return getEnvInt("WEBHOOK_DISABLE_AFTER", 20)
+}
+
+// GetWebhookAllowPrivateNetworks lets the webhooks reach loopback, private and link-local addresses, for development.
+func GetWebhookAllowPrivateNetworks() bool {
+	return getEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
+}
+
+// GetWebhookPollInterval how often the queued deliveries are checked.
+func GetWebhookPollInterval() time.Duration {
+	return time.Duration(getEnvInt("WEBHOOK_POLL_SECONDS", 5)) * time.Second
+}
+
+// GetOutboxPollInterval how often the outbox is checked for events that weren't dispatched right after their transaction.
+func GetOutboxPollInterval() time.Duration {
+	return time.Duration(getEnvInt("OUTBOX_POLL_SECONDS", 5)) * time.Second
+}
+
+// GetOutboxRetention how long the dispatched events are kept in the outbox.
+func GetOutboxRetention() time.Duration {
+	return time.Duration(getEnvInt("OUTBOX_RETENTION_HOURS", 72)) * time.Hour
+}
+
+// GetCacheSize how many users and posts are kept in the in-process cache, 0 disables the cache.
+func GetCacheSize() int {
+	return getEnvInt("CACHE_SIZE", 10000)
+}
+
+// GetCacheTTL how long a cached user or post is served before it is looked up again.
+func GetCacheTTL() time.Duration {
+	return time.Duration(getEnvInt("CACHE_TTL_SECONDS", 60)) * time.Second
+}
+
+// GetCacheNegativeTTL how long a lookup that found nothing is remembered, 0 doesn't remember them.
+func GetCacheNegativeTTL() time.Duration {
+	return time.Duration(getEnvInt("CACHE_NEGATIVE_TTL_SECONDS", 10)) * time.Second
+}
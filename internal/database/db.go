@@ -1,34 +1,77 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"time"
 
+	"github.com/glebarez/sqlite"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/simple-crud-go/internal/configs"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	// DriverSQLite pure Go, it builds without cgo.
+	DriverSQLite = "sqlite"
+)
+
+// databaseName the names the database is created with, anything else would need quoting rules of its own.
+var databaseName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
 var DB *gorm.DB
 
-func InitDB() *gorm.DB {
-	db, err := gorm.Open(mysql.Open(
-		fmt.Sprintf("%v:%v@tcp(%v:%v)/", configs.GetDBUSER(), configs.GetDBPASS(), configs.GetDBHOST(), configs.GetDBPORT()),
-	), &gorm.Config{})
+// Config where to connect, Host/Port/User/Password/Name are ignored by SQLite which only uses Path.
+type Config struct {
+	Driver   string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	// SSLMode PostgreSQL only.
+	SSLMode string
+	// Path of the SQLite database file, ":memory:" for an in-memory database.
+	Path string
+	Pool PoolConfig
+}
 
-	if err != nil {
-		panic("failed to connect database")
+func ConfigFromEnv() Config {
+	return Config{
+		Driver:   configs.GetDBDriver(),
+		Host:     configs.GetDBHOST(),
+		Port:     configs.GetDBPORT(),
+		User:     configs.GetDBUSER(),
+		Password: configs.GetDBPASS(),
+		Name:     configs.GetDBNAME(),
+		SSLMode:  configs.GetDBSSLMode(),
+		Path:     configs.GetDBPath(),
+		Pool:     poolConfigFromEnv(),
 	}
+}
 
-	_ = db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %v;", configs.GetDBNAME()))
-
-	normalDSN := fmt.Sprintf("%v:%v@tcp(%v:%v)/%v?charset=utf8mb4&parseTime=True&loc=Local", configs.GetDBUSER(), configs.GetDBPASS(), configs.GetDBHOST(), configs.GetDBPORT(), configs.GetDBNAME())
-	db, err = gorm.Open(
-		mysql.Open(normalDSN),
-		&gorm.Config{},
-	)
+func poolConfigFromEnv() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    configs.GetDBMaxOpenConns(),
+		MaxIdleConns:    configs.GetDBMaxIdleConns(),
+		ConnMaxLifetime: configs.GetDBConnMaxLifetime(),
+		ConnMaxIdleTime: configs.GetDBConnMaxIdleTime(),
+	}
+}
 
+// InitDB waits up to DB_CONNECT_TIMEOUT_SECONDS for the database to come up.
+func InitDB() *gorm.DB {
+	db, err := Connect(ConfigFromEnv(), configs.GetDBConnectTimeout())
 	if err != nil {
-		panic(err.Error())
+		panic(fmt.Sprintf("failed to connect database, error: %v", err))
 	}
 
 	DB = db
@@ -38,3 +81,168 @@ func InitDB() *gorm.DB {
 func GetDBGorm() *gorm.DB {
 	return DB
 }
+
+// Open connects to the database, MySQL and PostgreSQL databases are created when they don't exist yet.
+func Open(config Config) (*gorm.DB, error) {
+	if config.Driver != DriverSQLite {
+		if !databaseName.MatchString(config.Name) {
+			return nil, fmt.Errorf("invalid database name '%v', only letters, digits and underscores are allowed", config.Name)
+		}
+
+		if err := createDatabase(config); err != nil {
+			return nil, err
+		}
+	}
+
+	dialector, err := Dialector(config, config.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The errors of every driver are translated to the gorm ones, like gorm.ErrDuplicatedKey for unique violations
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = registerWriteTracking(db); err != nil {
+		return nil, err
+	}
+
+	if err = configurePool(db, config.Pool); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// InitReplicas the read replicas of DB_REPLICA_DSNS, nil when there are none. The replicas that can't be reached yet
+// are used once a health check finds them healthy.
+func InitReplicas() *Replicas {
+	dsns := configs.GetDBReplicaDSNs()
+	if len(dsns) == 0 {
+		return nil
+	}
+
+	dbs := make([]*gorm.DB, 0, len(dsns))
+	for _, dsn := range dsns {
+		db, err := OpenReplica(configs.GetDBDriver(), dsn, poolConfigFromEnv())
+		if err != nil {
+			panic(fmt.Sprintf("failed to open the database replica, error: %v", err))
+		}
+
+		dbs = append(dbs, db)
+	}
+
+	replicas := NewReplicas(dbs)
+	replicas.CheckHealth(context.Background())
+
+	return replicas
+}
+
+// OpenReplica a read replica with the DSN of the driver, the path of the file for SQLite. It isn't connected to
+// until it is queried.
+func OpenReplica(driver string, dsn string, pool PoolConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+
+	switch driver {
+	case DriverMySQL:
+		// Reading the server version needs a connection
+		dialector = mysql.New(mysql.Config{DSN: dsn, SkipInitializeWithVersion: true})
+	case DriverPostgres:
+		dialector = postgres.Open(dsn)
+	case DriverSQLite:
+		dialector = sqlite.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver '%v', must be one of %v, %v or %v", driver, DriverMySQL, DriverPostgres, DriverSQLite)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true, DisableAutomaticPing: true})
+	if err != nil {
+		return nil, err
+	}
+
+	if err = configurePool(db, pool); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Dialector for the driver of the config, connected to the database `name` (the server itself when empty).
+func Dialector(config Config, name string) (gorm.Dialector, error) {
+	switch config.Driver {
+	case DriverMySQL:
+		dsn := mysqlDriver.NewConfig()
+		dsn.User = config.User
+		dsn.Passwd = config.Password
+		dsn.Net = "tcp"
+		dsn.Addr = net.JoinHostPort(config.Host, config.Port)
+		dsn.DBName = name
+		dsn.ParseTime = true
+		dsn.Loc = time.Local
+		dsn.Params = map[string]string{"charset": "utf8mb4"}
+
+		return mysql.Open(dsn.FormatDSN()), nil
+	case DriverPostgres:
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(config.User, config.Password),
+			Host:     net.JoinHostPort(config.Host, config.Port),
+			Path:     "/" + name,
+			RawQuery: url.Values{"sslmode": {config.SSLMode}}.Encode(),
+		}
+
+		return postgres.Open(dsn.String()), nil
+	case DriverSQLite:
+		if config.Path == "" {
+			return nil, errors.New("the SQLite database path is empty")
+		}
+
+		// Foreign keys are off by default, and concurrent writers wait for the lock instead of failing right away. Transactions
+		// take the write lock when they begin, a read lock can't wait to be upgraded without deadlocking
+		return sqlite.Open(config.Path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_txlock=immediate"), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver '%v', must be one of %v, %v or %v", config.Driver, DriverMySQL, DriverPostgres, DriverSQLite)
+	}
+}
+
+// createDatabase the name was validated, it is safe to put in the statement which can't take it as a parameter.
+func createDatabase(config Config) error {
+	server := ""
+	if config.Driver == DriverPostgres {
+		// PostgreSQL always connects to a database, this one exists on every server
+		server = "postgres"
+	}
+
+	dialector, err := Dialector(config, server)
+	if err != nil {
+		return err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	if config.Driver == DriverMySQL {
+		return db.Exec(fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%v`", config.Name)).Error
+	}
+
+	var exists bool
+	if err = db.Raw("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = ?)", config.Name).Scan(&exists).Error; err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	return db.Exec(fmt.Sprintf(`CREATE DATABASE "%v"`, config.Name)).Error
+}
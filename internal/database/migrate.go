@@ -0,0 +1,361 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSchemaNotMigrated the database is missing migrations known to this build.
+var ErrSchemaNotMigrated = errors.New("the database schema is not migrated")
+
+const (
+	// migrationLockName MySQL named lock and PostgreSQL advisory lock key held while migrating.
+	migrationLockName = "simple_crud_schema_migrations"
+	migrationLockKey  = int64(7_240_316_011)
+	// sqliteLockStaleAfter SQLite has no session locks, a lock row this old was left by a migrator that died.
+	sqliteLockStaleAfter = 10 * time.Minute
+	lockRetryInterval    = 500 * time.Millisecond
+)
+
+// Migration a versioned schema change. Versions are timestamps (20060102150405) so migrations written on different
+// branches don't collide, they are applied in increasing order.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	// Down reverts Up, nil when the migration can't be reverted.
+	Down func(tx *gorm.DB) error
+}
+
+// SchemaMigration a migration applied to the database.
+type SchemaMigration struct {
+	Version   int64  `gorm:"primarykey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	AppliedAt time.Time
+}
+
+// MigrationStatus `AppliedAt` is nil for pending migrations, `Unknown` migrations were applied by another build.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+type schemaMigrationLock struct {
+	ID       uint `gorm:"primarykey;autoIncrement:false"`
+	LockedAt time.Time
+}
+
+// Migrator applies the migrations while holding a database lock, so replicas starting together don't run them twice.
+// Each migration runs in a transaction with its version record, except on MySQL where DDL statements commit on their own.
+type Migrator struct {
+	db          *gorm.DB
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+func NewMigrator(db *gorm.DB, migrations []Migration, lockTimeout time.Duration) *Migrator {
+	sorted := append([]Migration{}, migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &Migrator{
+		db:          db,
+		migrations:  sorted,
+		lockTimeout: lockTimeout,
+	}
+}
+
+// Up applies the first `steps` pending migrations, all of them when `steps` is 0.
+func (m *Migrator) Up(steps int) ([]Migration, error) {
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err := m.withLock(func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if steps > 0 && len(done) == steps {
+				break
+			}
+
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+
+			err = conn.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Up(tx); err != nil {
+					return err
+				}
+
+				return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %v failed: %w", migrationName(migration.Version, migration.Name), err)
+			}
+
+			logrus.WithField("version", migration.Version).Info("Applied migration ", migration.Name)
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Down reverts the last `steps` applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, errors.New("the number of migrations to revert must be at least 1")
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]Migration, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = migration
+	}
+
+	var done []Migration
+	err := m.withLock(func(conn *gorm.DB) error {
+		var records []SchemaMigration
+		if err := conn.Order("version DESC").Limit(steps).Find(&records).Error; err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			migration, ok := known[record.Version]
+			if !ok {
+				return fmt.Errorf("migration %v isn't known to this build", migrationName(record.Version, record.Name))
+			}
+
+			if migration.Down == nil {
+				return fmt.Errorf("migration %v can't be reverted", migrationName(migration.Version, migration.Name))
+			}
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := migration.Down(tx); err != nil {
+					return err
+				}
+
+				return tx.Delete(&SchemaMigration{}, migration.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %v failed: %w", migrationName(migration.Version, migration.Name), err)
+			}
+
+			logrus.WithField("version", migration.Version).Info("Reverted migration ", migration.Name)
+			done = append(done, migration)
+		}
+
+		return nil
+	})
+
+	return done, err
+}
+
+// Status every migration known to this build or applied to the database, oldest first.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, migration.Version)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for _, record := range applied {
+		statuses = append(statuses, MigrationStatus{Version: record.Version, Name: record.Name, AppliedAt: &record.AppliedAt, Unknown: true})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses, nil
+}
+
+// Check fails with ErrSchemaNotMigrated when a migration of this build wasn't applied. Migrations applied by a newer
+// build are fine, they are expected to stay compatible with the previous release.
+func (m *Migrator) Check() error {
+	statuses, err := m.Status()
+	if err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending = append(pending, migrationName(status.Version, status.Name))
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("%w, %d pending migrations: %v", ErrSchemaNotMigrated, len(pending), pending)
+	}
+
+	return nil
+}
+
+func (m *Migrator) validate() error {
+	for i, migration := range m.migrations {
+		if migration.Up == nil {
+			return fmt.Errorf("migration %v has no Up", migrationName(migration.Version, migration.Name))
+		}
+
+		if i > 0 && m.migrations[i-1].Version == migration.Version {
+			return fmt.Errorf("migrations %v and %v have the same version", m.migrations[i-1].Name, migration.Name)
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) applied(db *gorm.DB) (map[int64]SchemaMigration, error) {
+	applied := map[int64]SchemaMigration{}
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return applied, nil
+	}
+
+	var records []SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	return applied, nil
+}
+
+// withLock runs `fn` on a single connection holding the migration lock, MySQL and PostgreSQL locks belong to the session.
+func (m *Migrator) withLock(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		// Every chain starts from a clean statement on the same connection
+		conn = conn.Session(&gorm.Session{NewDB: true})
+
+		var (
+			unlock func() error
+			err    error
+		)
+
+		switch conn.Dialector.Name() {
+		case DriverMySQL:
+			unlock, err = m.lockMySQL(conn)
+		case DriverPostgres:
+			unlock, err = m.lockPostgres(conn)
+		default:
+			unlock, err = m.lockTable(conn)
+		}
+		if err != nil {
+			return err
+		}
+
+		defer func() {
+			if err := unlock(); err != nil {
+				logrus.Error(err)
+			}
+		}()
+
+		if err = conn.AutoMigrate(&SchemaMigration{}); err != nil {
+			return err
+		}
+
+		return fn(conn)
+	})
+}
+
+func (m *Migrator) lockMySQL(conn *gorm.DB) (func() error, error) {
+	var locked sql.NullInt64
+	if err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, int(m.lockTimeout.Seconds())).Scan(&locked).Error; err != nil {
+		return nil, err
+	}
+
+	if locked.Int64 != 1 {
+		return nil, fmt.Errorf("timed out waiting for the migration lock after %v", m.lockTimeout)
+	}
+
+	return func() error {
+		return conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName).Error
+	}, nil
+}
+
+func (m *Migrator) lockPostgres(conn *gorm.DB) (func() error, error) {
+	err := m.retryLock(func() (bool, error) {
+		var locked bool
+		err := conn.Raw("SELECT pg_try_advisory_lock(?)", migrationLockKey).Scan(&locked).Error
+		return locked, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey).Error
+	}, nil
+}
+
+// lockTable a row in a lock table, SQLite has nothing like a session lock.
+func (m *Migrator) lockTable(conn *gorm.DB) (func() error, error) {
+	if err := conn.Exec("CREATE TABLE IF NOT EXISTS schema_migration_locks (id INTEGER PRIMARY KEY, locked_at DATETIME)").Error; err != nil {
+		return nil, err
+	}
+
+	err := m.retryLock(func() (bool, error) {
+		if err := conn.Where("locked_at < ?", time.Now().Add(-sqliteLockStaleAfter)).Delete(&schemaMigrationLock{}).Error; err != nil {
+			return false, err
+		}
+
+		result := conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&schemaMigrationLock{ID: 1, LockedAt: time.Now()})
+		return result.RowsAffected > 0, result.Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return conn.Delete(&schemaMigrationLock{}, 1).Error
+	}, nil
+}
+
+func (m *Migrator) retryLock(try func() (bool, error)) error {
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		locked, err := try()
+		if err != nil || locked {
+			return err
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the migration lock after %v", m.lockTimeout)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+func migrationName(version int64, name string) string {
+	return fmt.Sprintf("%d_%v", version, name)
+}
@@ -0,0 +1,266 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/simple-crud-go/internal/database"
+	"gorm.io/gorm"
+)
+
+// The schema AutoMigrate used to create at startup. The models are copied as they were so later changes to
+// internal/models don't change what this migration does, and a database created by AutoMigrate is adopted as is.
+
+type initialUser struct {
+	ID        uint `gorm:"primarykey"`
+	Name      string
+	Username  string
+	Password  string
+	Role      string         `gorm:"size:20;default:user"`
+	Posts     *[]initialPost `gorm:"foreignKey:UserID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+type initialPost struct {
+	ID        uint `gorm:"primarykey;index:idx_posts_user_id_id,priority:2"`
+	Title     string
+	Body      string
+	UserID    uint         `gorm:"index:idx_posts_user_id_id,priority:1"`
+	User      *initialUser `gorm:"foreignKey:UserID"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+type initialAPIKey struct {
+	ID         uint         `gorm:"primarykey"`
+	UserID     uint         `gorm:"index"`
+	User       *initialUser `gorm:"foreignKey:UserID"`
+	Label      string
+	Prefix     string   `gorm:"size:16;uniqueIndex"`
+	KeyHash    string   `gorm:"size:64"`
+	Scopes     []string `gorm:"serializer:json"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type initialIdentity struct {
+	ID        uint         `gorm:"primarykey"`
+	UserID    uint         `gorm:"index"`
+	User      *initialUser `gorm:"foreignKey:UserID"`
+	Provider  string       `gorm:"size:64;uniqueIndex:idx_identities_provider_subject"`
+	Subject   string       `gorm:"size:255;uniqueIndex:idx_identities_provider_subject"`
+	Email     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type initialOAuthClient struct {
+	ID           uint   `gorm:"primarykey"`
+	ClientID     string `gorm:"size:64;uniqueIndex"`
+	SecretHash   string `gorm:"size:64"`
+	Name         string
+	RedirectURIs []string `gorm:"serializer:json"`
+	Scopes       []string `gorm:"serializer:json"`
+	Confidential bool
+	UserID       uint         `gorm:"index"`
+	User         *initialUser `gorm:"foreignKey:UserID"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type initialOAuthConsent struct {
+	ID            uint                `gorm:"primarykey"`
+	UserID        uint                `gorm:"uniqueIndex:idx_oauth_consents_user_client"`
+	OAuthClientID uint                `gorm:"column:oauth_client_id;uniqueIndex:idx_oauth_consents_user_client"`
+	OAuthClient   *initialOAuthClient `gorm:"foreignKey:OAuthClientID"`
+	Scopes        []string            `gorm:"serializer:json"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type initialOAuthAuthorizationCode struct {
+	ID            uint   `gorm:"primarykey"`
+	CodeHash      string `gorm:"size:64;uniqueIndex"`
+	OAuthClientID uint   `gorm:"column:oauth_client_id"`
+	UserID        uint
+	RedirectURI   string
+	Scopes        []string `gorm:"serializer:json"`
+	CodeChallenge string
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+	CreatedAt     time.Time
+}
+
+type initialOAuthToken struct {
+	ID            uint     `gorm:"primarykey"`
+	TokenID       string   `gorm:"size:64;uniqueIndex"`
+	OAuthClientID uint     `gorm:"index"`
+	UserID        uint     `gorm:"index"`
+	Scopes        []string `gorm:"serializer:json"`
+	ExpiresAt     time.Time
+	RevokedAt     *time.Time
+	CreatedAt     time.Time
+}
+
+type initialSession struct {
+	ID         uint         `gorm:"primarykey"`
+	UserID     uint         `gorm:"index"`
+	User       *initialUser `gorm:"foreignKey:UserID"`
+	UserAgent  string
+	IP         string   `gorm:"size:45"`
+	Scopes     []string `gorm:"serializer:json"`
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+type initialAuditEvent struct {
+	ID         uint   `gorm:"primarykey"`
+	Action     string `gorm:"size:64;index"`
+	ActorID    *uint  `gorm:"index"`
+	TargetType string `gorm:"size:32;index:idx_audit_events_target"`
+	TargetID   string `gorm:"size:64;index:idx_audit_events_target"`
+	IP         string `gorm:"size:45"`
+	UserAgent  string
+	Before     map[string]string `gorm:"serializer:json"`
+	After      map[string]string `gorm:"serializer:json"`
+	CreatedAt  time.Time         `gorm:"index"`
+}
+
+type initialFollow struct {
+	ID         uint         `gorm:"primarykey"`
+	FollowerID uint         `gorm:"not null;uniqueIndex:idx_follows_follower_followee,priority:1"`
+	Follower   *initialUser `gorm:"foreignKey:FollowerID;constraint:OnDelete:CASCADE"`
+	FolloweeID uint         `gorm:"not null;uniqueIndex:idx_follows_follower_followee,priority:2;index"`
+	Followee   *initialUser `gorm:"foreignKey:FolloweeID;constraint:OnDelete:CASCADE"`
+	CreatedAt  time.Time
+}
+
+type initialReaction struct {
+	ID        uint   `gorm:"primarykey"`
+	PostID    uint   `gorm:"not null;uniqueIndex:idx_reactions_post_user_kind,priority:1"`
+	UserID    uint   `gorm:"not null;uniqueIndex:idx_reactions_post_user_kind,priority:2;index"`
+	Kind      string `gorm:"size:16;not null;uniqueIndex:idx_reactions_post_user_kind,priority:3"`
+	CreatedAt time.Time
+}
+
+type initialBookmark struct {
+	ID         uint         `gorm:"primarykey"`
+	UserID     uint         `gorm:"not null;uniqueIndex:idx_bookmarks_user_post,priority:1;index:idx_bookmarks_user_collection,priority:1"`
+	PostID     uint         `gorm:"not null;uniqueIndex:idx_bookmarks_user_post,priority:2;index"`
+	Post       *initialPost `gorm:"foreignKey:PostID"`
+	Collection string       `gorm:"size:64;not null;default:'';index:idx_bookmarks_user_collection,priority:2"`
+	CreatedAt  time.Time
+}
+
+type initialNotification struct {
+	ID        uint   `gorm:"primarykey;index:idx_notifications_user_id_id,priority:2"`
+	UserID    uint   `gorm:"not null;index:idx_notifications_user_id_id,priority:1;index:idx_notifications_user_read,priority:1"`
+	Type      string `gorm:"size:32;not null"`
+	ActorID   *uint
+	Actor     *initialUser `gorm:"foreignKey:ActorID"`
+	PostID    *uint
+	ReadAt    *time.Time `gorm:"index:idx_notifications_user_read,priority:2"`
+	CreatedAt time.Time
+}
+
+type initialNotificationPreference struct {
+	UserID  uint   `gorm:"primarykey"`
+	Type    string `gorm:"primarykey;size:32"`
+	Enabled bool
+}
+
+type initialWebhook struct {
+	ID                  uint     `gorm:"primarykey"`
+	UserID              uint     `gorm:"index"`
+	URL                 string   `gorm:"size:2048;not null"`
+	Secret              string   `gorm:"size:64"`
+	Events              []string `gorm:"serializer:json"`
+	Global              bool
+	ConsecutiveFailures int
+	DisabledAt          *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type initialWebhookDelivery struct {
+	ID             uint            `gorm:"primarykey;index:idx_webhook_deliveries_webhook_id_id,priority:2"`
+	WebhookID      uint            `gorm:"not null;index:idx_webhook_deliveries_webhook_id_id,priority:1"`
+	Webhook        *initialWebhook `gorm:"foreignKey:WebhookID"`
+	Event          string          `gorm:"size:64;not null"`
+	Payload        string          `gorm:"type:text"`
+	Status         string          `gorm:"size:16;not null;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int
+	NextAttemptAt  *time.Time `gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	LastAttemptAt  *time.Time
+	ResponseStatus *int
+	Error          string `gorm:"size:512"`
+	RedeliveryOf   *uint
+	CreatedAt      time.Time
+}
+
+type initialOutboxEvent struct {
+	ID           uint   `gorm:"primarykey;index:idx_outbox_events_pending,priority:2"`
+	Type         string `gorm:"size:64;not null"`
+	ActorID      uint
+	UserID       uint
+	PostID       uint
+	Kind         string `gorm:"size:32"`
+	Data         string `gorm:"type:text"`
+	Attempts     int
+	LastError    string     `gorm:"size:512"`
+	DispatchedAt *time.Time `gorm:"index:idx_outbox_events_pending,priority:1"`
+	CreatedAt    time.Time
+}
+
+func (initialUser) TableName() string                   { return "users" }
+func (initialPost) TableName() string                   { return "posts" }
+func (initialAPIKey) TableName() string                 { return "api_keys" }
+func (initialIdentity) TableName() string               { return "identities" }
+func (initialOAuthClient) TableName() string            { return "oauth_clients" }
+func (initialOAuthConsent) TableName() string           { return "oauth_consents" }
+func (initialOAuthAuthorizationCode) TableName() string { return "oauth_authorization_codes" }
+func (initialOAuthToken) TableName() string             { return "oauth_tokens" }
+func (initialSession) TableName() string                { return "sessions" }
+func (initialAuditEvent) TableName() string             { return "audit_events" }
+func (initialFollow) TableName() string                 { return "follows" }
+func (initialReaction) TableName() string               { return "reactions" }
+func (initialBookmark) TableName() string               { return "bookmarks" }
+func (initialNotification) TableName() string           { return "notifications" }
+func (initialNotificationPreference) TableName() string { return "notification_preferences" }
+func (initialWebhook) TableName() string                { return "webhooks" }
+func (initialWebhookDelivery) TableName() string        { return "webhook_deliveries" }
+func (initialOutboxEvent) TableName() string            { return "outbox_events" }
+
+// initialTables in creation order, the referenced tables first.
+var initialTables = []interface{}{
+	&initialUser{}, &initialPost{}, &initialAPIKey{}, &initialIdentity{}, &initialOAuthClient{}, &initialOAuthConsent{},
+	&initialOAuthAuthorizationCode{}, &initialOAuthToken{}, &initialSession{}, &initialAuditEvent{}, &initialFollow{},
+	&initialReaction{}, &initialBookmark{}, &initialNotification{}, &initialNotificationPreference{}, &initialWebhook{},
+	&initialWebhookDelivery{}, &initialOutboxEvent{},
+}
+
+func init() {
+	register(database.Migration{
+		Version: 20261019120000,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(initialTables...)
+		},
+		Down: func(tx *gorm.DB) error {
+			for i := len(initialTables) - 1; i >= 0; i-- {
+				if err := tx.Migrator().DropTable(initialTables[i]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/simple-crud-go/internal/database"
+	"gorm.io/gorm"
+)
+
+// usernameIndex keeps the usernames of the users that aren't deleted unique, whatever their case, so a deleted user's
+// username can be taken again.
+const usernameIndex = "idx_users_username_lower"
+
+func init() {
+	register(database.Migration{
+		Version: 20261019130000,
+		Name:    "users_username_unique",
+		Up: func(tx *gorm.DB) error {
+			var duplicates []string
+			err := tx.Raw("SELECT LOWER(username) FROM users WHERE deleted_at IS NULL GROUP BY LOWER(username) HAVING COUNT(*) > 1").
+				Scan(&duplicates).Error
+			if err != nil {
+				return err
+			}
+
+			if len(duplicates) > 0 {
+				return fmt.Errorf("the usernames %v are used by more than one user, rename them before applying this migration", strings.Join(duplicates, ", "))
+			}
+
+			if tx.Dialector.Name() == database.DriverMySQL {
+				// MySQL can't index a TEXT column and has no partial indexes, the deleted users are indexed as NULL
+				// which doesn't conflict with anything
+				if err := tx.Exec("ALTER TABLE users MODIFY username varchar(191)").Error; err != nil {
+					return err
+				}
+
+				return tx.Exec("CREATE UNIQUE INDEX " + usernameIndex + " ON users ((CASE WHEN deleted_at IS NULL THEN LOWER(username) END))").Error
+			}
+
+			return tx.Exec("CREATE UNIQUE INDEX " + usernameIndex + " ON users (LOWER(username)) WHERE deleted_at IS NULL").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == database.DriverMySQL {
+				if err := tx.Exec("DROP INDEX " + usernameIndex + " ON users").Error; err != nil {
+					return err
+				}
+
+				return tx.Exec("ALTER TABLE users MODIFY username longtext").Error
+			}
+
+			return tx.Exec("DROP INDEX " + usernameIndex).Error
+		},
+	})
+}
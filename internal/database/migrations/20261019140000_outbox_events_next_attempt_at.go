@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/simple-crud-go/internal/database"
+	"gorm.io/gorm"
+)
+
+// outboxEventRetry the column added to the outbox events, an event whose dispatch failed waits until then.
+type outboxEventRetry struct {
+	NextAttemptAt *time.Time
+}
+
+func (outboxEventRetry) TableName() string { return "outbox_events" }
+
+func init() {
+	register(database.Migration{
+		Version: 20261019140000,
+		Name:    "outbox_events_next_attempt_at",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&outboxEventRetry{}, "NextAttemptAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&outboxEventRetry{}, "NextAttemptAt")
+		},
+	})
+}
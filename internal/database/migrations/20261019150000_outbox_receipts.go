@@ -0,0 +1,30 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/simple-crud-go/internal/database"
+	"gorm.io/gorm"
+)
+
+// outboxReceipt the subscribers that received an event not dispatched yet.
+type outboxReceipt struct {
+	EventID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	Subscriber string `gorm:"primaryKey;size:64"`
+	CreatedAt  time.Time
+}
+
+func (outboxReceipt) TableName() string { return "outbox_receipts" }
+
+func init() {
+	register(database.Migration{
+		Version: 20261019150000,
+		Name:    "outbox_receipts",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&outboxReceipt{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&outboxReceipt{})
+		},
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/simple-crud-go/internal/database"
+	"gorm.io/gorm"
+)
+
+// outboxEventFailure the column added to the outbox events, set once the dispatcher gave the event up.
+type outboxEventFailure struct {
+	FailedAt *time.Time
+}
+
+func (outboxEventFailure) TableName() string { return "outbox_events" }
+
+func init() {
+	register(database.Migration{
+		Version: 20261019160000,
+		Name:    "outbox_events_failed_at",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&outboxEventFailure{}, "FailedAt"); err != nil {
+				return err
+			}
+
+			// The dispatcher used to leave the events alone after 10 failed dispatches
+			return tx.Exec("UPDATE outbox_events SET failed_at = CURRENT_TIMESTAMP WHERE dispatched_at IS NULL AND attempts >= 10").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&outboxEventFailure{}, "FailedAt")
+		},
+	})
+}
@@ -0,0 +1,17 @@
+// Package migrations the versioned schema changes, one file per migration created with `go run . migrate create <name>`.
+package migrations
+
+import (
+	"github.com/simple-crud-go/internal/database"
+)
+
+var registered []database.Migration
+
+func register(migration database.Migration) {
+	registered = append(registered, migration)
+}
+
+// All every migration, the migrator sorts them by version.
+func All() []database.Migration {
+	return append([]database.Migration{}, registered...)
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// connectRetryBase the delay before retrying to connect the first time, doubled after every attempt.
+	connectRetryBase = 500 * time.Millisecond
+	connectRetryMax  = 10 * time.Second
+
+	// pingTimeout how long a health check waits for the database to answer.
+	pingTimeout = 2 * time.Second
+)
+
+// PoolConfig of the connections kept open to the database, zero values keep the defaults of database/sql.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func configurePool(db *gorm.DB, pool PoolConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	if pool.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	}
+
+	return nil
+}
+
+// Connect opens the database like Open, retrying with a growing delay while the database can't be reached yet, for
+// as long as `timeout`. Any other error is returned right away.
+func Connect(config Config, timeout time.Duration) (*gorm.DB, error) {
+	var (
+		deadline = time.Now().Add(timeout)
+		delay    = connectRetryBase
+	)
+
+	for attempt := 1; ; attempt++ {
+		db, err := Open(config)
+		if err == nil || !isConnectionError(err) || time.Now().Add(delay).After(deadline) {
+			return db, err
+		}
+
+		logrus.Warn(fmt.Sprintf("The database can't be reached yet (attempt %d), retrying in %v: %v", attempt, delay, err))
+		time.Sleep(delay)
+		delay = min(delay*2, connectRetryMax)
+	}
+}
+
+// PoolStats of the connections to the database.
+func PoolStats(db *gorm.DB) (sql.DBStats, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return sql.DBStats{}, err
+	}
+
+	return sqlDB.Stats(), nil
+}
+
+// Ping whether the database answers within a couple of seconds.
+func Ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	return sqlDB.PingContext(ctx)
+}
+
+// isConnectionError whether the query failed because the database couldn't be reached, or isn't accepting
+// connections yet, rather than because of the query itself.
+func isConnectionError(err error) bool {
+	var (
+		netErr net.Error
+		pgErr  *pgconn.PgError
+	)
+
+	if errors.As(err, &pgErr) {
+		// cannot_connect_now, the server is starting up
+		return pgErr.Code == "57P03"
+	}
+
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, mysqlDriver.ErrInvalidConn) ||
+		errors.As(err, &netErr)
+}
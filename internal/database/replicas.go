@@ -0,0 +1,175 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Replicas routes the lookups that can live with replication lag to the read replicas, round robin over the ones
+// answering the health checks. The lookups stay on the primary when no replica is healthy, and when the request
+// already wrote something so it reads its own writes.
+type Replicas struct {
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	db      *gorm.DB
+	name    string
+	healthy atomic.Bool
+}
+
+// NewReplicas the replicas get no reads until a health check found them healthy.
+func NewReplicas(dbs []*gorm.DB) *Replicas {
+	replicas := &Replicas{}
+	for i, db := range dbs {
+		replicas.replicas = append(replicas.replicas, &replica{db: db, name: fmt.Sprintf("replica %d", i+1)})
+	}
+
+	return replicas
+}
+
+// Read runs `fn` on a healthy replica, or on `primary` when there is none. `fn` is run again on `primary` when the
+// replica can't be reached, the replica is left out until the next health check finds it healthy. A nil Replicas
+// always reads from `primary`.
+func (r *Replicas) Read(ctx context.Context, primary *gorm.DB, fn func(db *gorm.DB) error) error {
+	replica := r.pick(ctx)
+	if replica == nil {
+		return fn(primary.WithContext(ctx))
+	}
+
+	err := fn(replica.db.WithContext(ctx))
+	if err == nil || ctx.Err() != nil || !isConnectionError(err) {
+		return err
+	}
+
+	if replica.healthy.Swap(false) {
+		logrus.WithField("replica", replica.name).Warn(fmt.Sprintf("Replica is down, reading from the primary: %v", err))
+	}
+
+	return fn(primary.WithContext(ctx))
+}
+
+func (r *Replicas) pick(ctx context.Context) *replica {
+	if r == nil || len(r.replicas) == 0 || hasWritten(ctx) {
+		return nil
+	}
+
+	start := r.next.Add(1)
+	for i := range r.replicas {
+		replica := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if replica.healthy.Load() {
+			return replica
+		}
+	}
+
+	return nil
+}
+
+// Run checks the health of the replicas every `interval` until the context is done.
+func (r *Replicas) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.CheckHealth(ctx)
+		}
+	}
+}
+
+// CheckHealth pings every replica, the ones that don't answer get no reads until they do again.
+func (r *Replicas) CheckHealth(ctx context.Context) {
+	for _, replica := range r.replicas {
+		err := Ping(ctx, replica.db)
+		healthy := err == nil
+
+		if replica.healthy.Swap(healthy) != healthy {
+			if healthy {
+				logrus.WithField("replica", replica.name).Info("Replica is healthy again")
+			} else {
+				logrus.WithField("replica", replica.name).Warn(fmt.Sprintf("Replica is down, reading from the primary: %v", err))
+			}
+		}
+	}
+}
+
+// ReplicaStatus the state of a replica as of the last health check.
+type ReplicaStatus struct {
+	Name    string
+	Healthy bool
+	Pool    sql.DBStats
+}
+
+// Status of every replica, without checking their health again.
+func (r *Replicas) Status() []ReplicaStatus {
+	if r == nil {
+		return nil
+	}
+
+	statuses := make([]ReplicaStatus, 0, len(r.replicas))
+	for _, replica := range r.replicas {
+		pool, _ := PoolStats(replica.db)
+		statuses = append(statuses, ReplicaStatus{Name: replica.name, Healthy: replica.healthy.Load(), Pool: pool})
+	}
+
+	return statuses
+}
+
+type writesKey struct{}
+
+// TrackWrites a context remembering whether a query wrote through it, the lookups done with it afterwards read from
+// the primary so they see what was written.
+func TrackWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writesKey{}, new(atomic.Bool))
+}
+
+// ReadFromPrimary a context whose lookups read from the primary, for the reads whose result outlives the request.
+func ReadFromPrimary(ctx context.Context) context.Context {
+	written := new(atomic.Bool)
+	written.Store(true)
+
+	return context.WithValue(ctx, writesKey{}, written)
+}
+
+func hasWritten(ctx context.Context) bool {
+	written, ok := ctx.Value(writesKey{}).(*atomic.Bool)
+	return ok && written.Load()
+}
+
+// registerWriteTracking marks the contexts of TrackWrites once a query wrote through them, in a transaction as well.
+func registerWriteTracking(db *gorm.DB) error {
+	markWritten := func(db *gorm.DB) {
+		if db.Error != nil || db.Statement.Context == nil {
+			return
+		}
+
+		if written, ok := db.Statement.Context.Value(writesKey{}).(*atomic.Bool); ok {
+			written.Store(true)
+		}
+	}
+
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().After("gorm:create").Register("replicas:track_writes", markWritten),
+		callbacks.Update().After("gorm:update").Register("replicas:track_writes", markWritten),
+		callbacks.Delete().After("gorm:delete").Register("replicas:track_writes", markWritten),
+		// Exec goes through the raw callbacks
+		callbacks.Raw().After("gorm:raw").Register("replicas:track_writes", markWritten),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,54 +1,223 @@
 package handlers
 
 import (
+	"context"
+	"fmt"
+	"maps"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/internal/cache"
+	"github.com/simple-crud-go/internal/configs"
+	"github.com/simple-crud-go/internal/database"
 	"github.com/simple-crud-go/internal/handlers/controller"
 	"github.com/simple-crud-go/internal/helper"
 	"github.com/simple-crud-go/internal/middleware"
 	"github.com/simple-crud-go/internal/repository"
 	"github.com/simple-crud-go/internal/services"
+	"github.com/sirupsen/logrus"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"gorm.io/gorm"
 )
 
-func RouteHandler(r *mux.Router, db *gorm.DB) {
+// RouteHandler `replicas` may be nil, every query goes to `db` then.
+func RouteHandler(r *mux.Router, db *gorm.DB, replicas *database.Replicas) {
 	var (
 		bcryptPassCrypto = helper.BcryptPasswordCrypto{}
+		passwordPolicy   = helper.NewDefaultPasswordPolicy()
 		jwtHelper        = helper.NewDefaultJWTHelper()
 
-		userRepository = repository.NewUserRepository(db)
-		postRepository = repository.NewPostRepository(db)
+		apiKeyRepository       = repository.NewAPIKeyRepository(db)
+		oauthRepository        = repository.NewOAuthRepository(db)
+		sessionRepository      = repository.NewSessionRepository(db)
+		auditRepository        = repository.NewAuditRepository(db)
+		followRepository       = repository.NewFollowRepository(db)
+		reactionRepository     = repository.NewReactionRepository(db)
+		bookmarkRepository     = repository.NewBookmarkRepository(db)
+		notificationRepository = repository.NewNotificationRepository(db)
+		webhookRepository      = repository.NewWebhookRepository(db)
+		outboxRepository       = repository.NewOutboxRepository(db)
 
-		userService = services.NewUserService(userRepository, bcryptPassCrypto)
-		postService = services.NewPostService(postRepository, userRepository)
-		authService = services.NewAuthService(userRepository, bcryptPassCrypto, jwtHelper)
+		eventBus         = services.NewEventBus()
+		outboxDispatcher = services.NewOutboxDispatcher(outboxRepository, eventBus)
 
-		userController = controller.UserController{Service: userService}
-		postController = controller.PostController{Service: postService}
-		authController = controller.AuthController{Service: authService}
+		userRepository, postRepository, transactor = withCache(
+			repository.NewUserRepository(db, replicas),
+			repository.NewPostRepository(db, replicas),
+			repository.NewTransactor(db, outboxDispatcher.Wake),
+		)
+
+		streamService       = services.NewStreamService(configs.GetStreamBufferSize())
+		notificationService = services.NewNotificationService(notificationRepository, transactor)
+		collaborationHub    = services.NewCollaborationHub(postRepository)
+		webhookService      = services.NewWebhookService(webhookRepository, services.WebhookConfig{
+			MaxAttempts:          configs.GetWebhookMaxAttempts(),
+			RetryBase:            configs.GetWebhookRetryBase(),
+			DisableAfter:         configs.GetWebhookDisableAfter(),
+			Timeout:              configs.GetWebhookTimeout(),
+			AllowPrivateNetworks: configs.GetWebhookAllowPrivateNetworks(),
+		})
+
+		userService     = services.NewUserService(userRepository, followRepository, auditRepository, bcryptPassCrypto, passwordPolicy, transactor)
+		postService     = services.NewPostService(postRepository, userRepository, reactionRepository, auditRepository, transactor)
+		authService     = services.NewAuthService(userRepository, sessionRepository, auditRepository, bcryptPassCrypto, passwordPolicy, jwtHelper, transactor)
+		apiKeyService   = services.NewAPIKeyService(apiKeyRepository)
+		oauthService    = services.NewOAuthService(oauthRepository, jwtHelper)
+		sessionService  = services.NewSessionService(sessionRepository)
+		auditService    = services.NewAuditService(auditRepository)
+		adminService    = services.NewAdminService(userRepository, postRepository, sessionRepository, auditRepository, transactor)
+		reactionService = services.NewReactionService(reactionRepository, postRepository, transactor)
+		bookmarkService = services.NewBookmarkService(bookmarkRepository, postRepository)
+		followService   = services.NewFollowService(followRepository, userRepository, postRepository, reactionRepository, transactor)
+		healthService   = services.NewHealthService(db, replicas)
+
+		auth = middleware.NewAuth(jwtHelper, userService, apiKeyService, oauthService, sessionService)
+
+		userController          = controller.UserController{Service: userService}
+		postController          = controller.PostController{Service: postService}
+		authController          = controller.AuthController{Service: authService}
+		apiKeyController        = controller.APIKeyController{Service: apiKeyService}
+		oauthController         = controller.OAuthController{Service: oauthService}
+		sessionController       = controller.SessionController{Service: sessionService}
+		adminController         = controller.AdminController{Service: adminService}
+		auditController         = controller.AuditController{Service: auditService}
+		followController        = controller.FollowController{Service: followService}
+		reactionController      = controller.ReactionController{Service: reactionService}
+		bookmarkController      = controller.BookmarkController{Service: bookmarkService}
+		notificationController  = controller.NotificationController{Service: notificationService}
+		streamController        = controller.StreamController{Service: streamService, Heartbeat: configs.GetStreamHeartbeat()}
+		collaborationController = controller.CollaborationController{Hub: collaborationHub}
+		webhookController       = controller.WebhookController{Service: webhookService}
+		healthController        = controller.HealthController{Service: healthService}
 	)
 
+	eventBus.Subscribe("notifications", notificationService, services.EventUserFollowed, services.EventPostReacted)
+	eventBus.Subscribe("stream", streamService)
+	eventBus.Subscribe("collaboration", collaborationHub, services.EventPostUpdated, services.EventPostDeleted)
+	eventBus.Subscribe("webhooks", webhookService)
+
+	go outboxDispatcher.Run(context.Background(), configs.GetOutboxPollInterval(), configs.GetOutboxRetention())
+	go webhookService.Run(context.Background(), configs.GetWebhookPollInterval())
+	if replicas != nil {
+		go replicas.Run(context.Background(), configs.GetDBReplicaHealthInterval())
+	}
+
 	r.PathPrefix("/docs").Handler(httpSwagger.WrapHandler)
+	r.HandleFunc("/health", healthController.Health).Methods("GET")
+	r.HandleFunc("/metrics", healthController.Metrics).Methods("GET")
 
 	r = r.PathPrefix("/api").Subrouter()
 
+	routeTimeouts := map[string]time.Duration{
+		// The streams stay open as long as the client is connected
+		"GET /api/stream":      0,
+		"GET /api/collaborate": 0,
+	}
+	maps.Copy(routeTimeouts, configs.GetRouteTimeouts())
+	r.Use(middleware.RequestDeadline(configs.GetRequestTimeout(), routeTimeouts))
+	r.Use(middleware.ReadYourWrites)
+
 	r.HandleFunc("/login", authController.Login).Methods("POST")
 	r.HandleFunc("/register", authController.Register).Methods("POST")
+	r.HandleFunc("/stream", auth.OptionalAuthMiddleware(http.HandlerFunc(streamController.Stream)).ServeHTTP).Methods("GET")
+	r.HandleFunc("/collaborate", middleware.WebSocketTokenQuery(auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsRead)(http.HandlerFunc(collaborationController.Collaborate)))).ServeHTTP).Methods("GET")
 
 	userPrefix := r.PathPrefix("/user").Subrouter()
 	userPrefix.HandleFunc("/{username}", userController.UserByUsername).Methods("GET")
 	userPrefix.HandleFunc("", userController.Users).Methods("GET")
+	userPrefix.HandleFunc("/{username}/followers", followController.Followers).Methods("GET")
+	userPrefix.HandleFunc("/{username}/following", followController.Following).Methods("GET")
+	userPrefix.HandleFunc("/{username}/follow", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeUsersWrite)(http.HandlerFunc(followController.Follow))).ServeHTTP).Methods("POST")
+	userPrefix.HandleFunc("/{username}/follow", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeUsersWrite)(http.HandlerFunc(followController.Unfollow))).ServeHTTP).Methods("DELETE")
 	// userPrefix.HandleFunc("", userController.CreateUser).Methods("POST")
-	userPrefix.HandleFunc("/{id}", middleware.AuthMiddleware(http.HandlerFunc(userController.UpdateUser)).ServeHTTP).Methods("PUT")
-	userPrefix.HandleFunc("", middleware.AuthMiddleware(http.HandlerFunc(userController.DeleteUserById)).ServeHTTP).Methods("DELETE")
+	userPrefix.HandleFunc("/{id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeUsersWrite)(http.HandlerFunc(userController.UpdateUser))).ServeHTTP).Methods("PUT")
+	userPrefix.HandleFunc("", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeUsersWrite)(http.HandlerFunc(userController.DeleteUserById))).ServeHTTP).Methods("DELETE")
 
 	postPrefix := r.PathPrefix("/post").Subrouter()
-	postPrefix.HandleFunc("", postController.GetPosts).Methods("GET")
-	postPrefix.HandleFunc("/{id}", postController.GetPostById).Methods("GET")
-	postPrefix.HandleFunc("", middleware.AuthMiddleware(http.HandlerFunc(postController.CreatePost)).ServeHTTP).Methods("POST")
-	postPrefix.HandleFunc("/{id}", middleware.AuthMiddleware(http.HandlerFunc(postController.UpdatePost)).ServeHTTP).Methods("PUT")
-	postPrefix.HandleFunc("/{id}", middleware.AuthMiddleware(http.HandlerFunc(postController.DeletePostById)).ServeHTTP).Methods("DELETE")
+	postPrefix.HandleFunc("", auth.OptionalAuthMiddleware(http.HandlerFunc(postController.GetPosts)).ServeHTTP).Methods("GET")
+	postPrefix.HandleFunc("/{id}", auth.OptionalAuthMiddleware(http.HandlerFunc(postController.GetPostById)).ServeHTTP).Methods("GET")
+	postPrefix.HandleFunc("", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsWrite)(http.HandlerFunc(postController.CreatePost))).ServeHTTP).Methods("POST")
+	postPrefix.HandleFunc("/{id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsWrite)(http.HandlerFunc(postController.UpdatePost))).ServeHTTP).Methods("PUT")
+	postPrefix.HandleFunc("/{id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsWrite)(http.HandlerFunc(postController.DeletePostById))).ServeHTTP).Methods("DELETE")
+	postPrefix.HandleFunc("/{id}/reactions/{kind}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsWrite)(http.HandlerFunc(reactionController.React))).ServeHTTP).Methods("PUT")
+	postPrefix.HandleFunc("/{id}/reactions/{kind}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsWrite)(http.HandlerFunc(reactionController.Unreact))).ServeHTTP).Methods("DELETE")
+	postPrefix.HandleFunc("/{id}/bookmark", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsWrite)(http.HandlerFunc(bookmarkController.Bookmark))).ServeHTTP).Methods("PUT")
+	postPrefix.HandleFunc("/{id}/bookmark", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsWrite)(http.HandlerFunc(bookmarkController.RemoveBookmark))).ServeHTTP).Methods("DELETE")
+
+	mePrefix := r.PathPrefix("/me").Subrouter()
+	mePrefix.HandleFunc("/feed", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsRead)(http.HandlerFunc(followController.Feed))).ServeHTTP).Methods("GET")
+	mePrefix.HandleFunc("/bookmarks", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopePostsRead)(http.HandlerFunc(bookmarkController.Bookmarks))).ServeHTTP).Methods("GET")
+	mePrefix.HandleFunc("/notifications", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeUsersRead)(http.HandlerFunc(notificationController.Notifications))).ServeHTTP).Methods("GET")
+	mePrefix.HandleFunc("/notifications/read", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeUsersWrite)(http.HandlerFunc(notificationController.MarkAllRead))).ServeHTTP).Methods("POST")
+	mePrefix.HandleFunc("/notifications/{id}/read", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeUsersWrite)(http.HandlerFunc(notificationController.MarkRead))).ServeHTTP).Methods("POST")
+	mePrefix.HandleFunc("/notification-preferences", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeUsersRead)(http.HandlerFunc(notificationController.Preferences))).ServeHTTP).Methods("GET")
+	mePrefix.HandleFunc("/notification-preferences/{type}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeUsersWrite)(http.HandlerFunc(notificationController.SetPreference))).ServeHTTP).Methods("PUT")
+	mePrefix.HandleFunc("/webhooks", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(webhookController.Webhooks))).ServeHTTP).Methods("GET")
+	mePrefix.HandleFunc("/webhooks", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(webhookController.CreateWebhook))).ServeHTTP).Methods("POST")
+	mePrefix.HandleFunc("/webhooks/{id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(webhookController.UpdateWebhook))).ServeHTTP).Methods("PUT")
+	mePrefix.HandleFunc("/webhooks/{id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(webhookController.DeleteWebhook))).ServeHTTP).Methods("DELETE")
+	mePrefix.HandleFunc("/webhooks/{id}/deliveries", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(webhookController.Deliveries))).ServeHTTP).Methods("GET")
+	mePrefix.HandleFunc("/webhooks/{id}/deliveries/{delivery_id}/redeliver", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(webhookController.Redeliver))).ServeHTTP).Methods("POST")
+	mePrefix.HandleFunc("/api-keys", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(apiKeyController.APIKeys))).ServeHTTP).Methods("GET")
+	mePrefix.HandleFunc("/api-keys", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(apiKeyController.CreateAPIKey))).ServeHTTP).Methods("POST")
+	mePrefix.HandleFunc("/api-keys/{id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(apiKeyController.UpdateAPIKey))).ServeHTTP).Methods("PUT")
+	mePrefix.HandleFunc("/api-keys/{id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(apiKeyController.RevokeAPIKey))).ServeHTTP).Methods("DELETE")
+	mePrefix.HandleFunc("/tokens", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(authController.CreateScopedToken))).ServeHTTP).Methods("POST")
+	mePrefix.HandleFunc("/sessions", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(sessionController.Sessions))).ServeHTTP).Methods("GET")
+	mePrefix.HandleFunc("/sessions", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(sessionController.RevokeOtherSessions))).ServeHTTP).Methods("DELETE")
+	mePrefix.HandleFunc("/sessions/{id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(sessionController.RevokeSession))).ServeHTTP).Methods("DELETE")
+	mePrefix.HandleFunc("/consents", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(oauthController.Consents))).ServeHTTP).Methods("GET")
+	mePrefix.HandleFunc("/consents/{client_id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(oauthController.RevokeConsent))).ServeHTTP).Methods("DELETE")
+
+	adminPrefix := r.PathPrefix("/admin").Subrouter()
+	adminPrefix.HandleFunc("/user/{username}/role", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeAdmin)(http.HandlerFunc(adminController.SetUserRole))).ServeHTTP).Methods("PUT")
+	adminPrefix.HandleFunc("/audit", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeAdmin)(http.HandlerFunc(auditController.AuditEvents))).ServeHTTP).Methods("GET")
+	adminPrefix.HandleFunc("/webhooks", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeAdmin)(http.HandlerFunc(webhookController.CreateGlobalWebhook))).ServeHTTP).Methods("POST")
+	adminPrefix.HandleFunc("/post/{id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeAdmin)(http.HandlerFunc(adminController.DeletePost))).ServeHTTP).Methods("DELETE")
+
+	oauthPrefix := r.PathPrefix("/oauth").Subrouter()
+	oauthPrefix.HandleFunc("/clients", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(oauthController.Clients))).ServeHTTP).Methods("GET")
+	oauthPrefix.HandleFunc("/clients", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(oauthController.RegisterClient))).ServeHTTP).Methods("POST")
+	oauthPrefix.HandleFunc("/clients/{client_id}", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(oauthController.DeleteClient))).ServeHTTP).Methods("DELETE")
+	oauthPrefix.HandleFunc("/authorize", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(oauthController.AuthorizationInfo))).ServeHTTP).Methods("GET")
+	oauthPrefix.HandleFunc("/authorize", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(oauthController.Authorize))).ServeHTTP).Methods("POST")
+	oauthPrefix.HandleFunc("/token", oauthController.Token).Methods("POST")
+	oauthPrefix.HandleFunc("/introspect", oauthController.Introspect).Methods("POST")
+	oauthPrefix.HandleFunc("/revoke", oauthController.Revoke).Methods("POST")
+
+	if configs.GetOIDCIssuerURL() != "" {
+		oidcService, err := services.NewOIDCService(services.OIDCConfig{
+			ProviderName: configs.GetOIDCProviderName(),
+			IssuerURL:    configs.GetOIDCIssuerURL(),
+			ClientID:     configs.GetOIDCClientID(),
+			ClientSecret: configs.GetOIDCClientSecret(),
+			RedirectURL:  configs.GetOIDCRedirectURL(),
+		}, repository.NewIdentityRepository(db), userRepository, sessionRepository, auditRepository, bcryptPassCrypto, jwtHelper, transactor)
+
+		if err != nil {
+			logrus.Error(fmt.Sprintf("OpenID Connect login disabled, failed to reach the provider, error: %v", err))
+		} else {
+			oidcController := controller.OIDCController{Service: oidcService}
+
+			r.HandleFunc("/auth/oidc/login", oidcController.Login).Methods("GET")
+			r.HandleFunc("/auth/oidc/callback", oidcController.Callback).Methods("GET")
+			mePrefix.HandleFunc("/identities", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(oidcController.Identities))).ServeHTTP).Methods("GET")
+			mePrefix.HandleFunc("/identities/oidc", auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeCredentials)(http.HandlerFunc(oidcController.LinkIdentity))).ServeHTTP).Methods("POST")
+		}
+	}
+}
+
+// withCache the lookups of the users and posts go through the cache, unless CACHE_SIZE is 0.
+func withCache(users repository.UserRepo, posts repository.PostRepo, transactor repository.Transactor) (repository.UserRepo, repository.PostRepo, repository.Transactor) {
+	size := configs.GetCacheSize()
+	if size <= 0 {
+		return users, posts, transactor
+	}
+
+	repositoryCache := repository.NewRepositoryCache(cache.NewLRU(size), configs.GetCacheTTL(), configs.GetCacheNegativeTTL())
 
+	return repository.NewCachedUserRepository(users, repositoryCache),
+		repository.NewCachedPostRepository(posts, repositoryCache),
+		repository.NewCachingTransactor(transactor, repositoryCache)
 }
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
+)
+
+type AdminController struct {
+	Service *services.AdminService
+}
+
+// SetUserRole Change the role of a user
+// @summary Change the role of a user
+// @description Change the role of a user, the user is logged out of every session to pick up the new role
+// @tags Admin
+// @id admin-set-user-role
+// @accept mpfd
+// @produce json
+// @param username path string true "Username"
+// @param role formData string true "Role (user or admin)"
+// @success 200 {object} api.NoDataResponse "Role updated"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 403 {object} api.ErrorResponse "Forbidden"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /admin/user/{username}/role [put]
+// @security Bearer
+func (c *AdminController) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	var (
+		username = mux.Vars(r)["username"]
+		role     = r.FormValue("role")
+	)
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Service.SetRole(r.Context(), principal.UserID, username, role, clientInfo(r)); err != nil {
+		var validationErr *services.ValidationError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RequestErrorHandler(w, fmt.Errorf("User with username = %v doesn't exist", username), http.StatusNotFound)
+			return
+		} else if errors.As(err, &validationErr) {
+			api.ValidationErrorHandler(w, validationErr.Fields)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Role of %v successfully updated", username))
+}
+
+// DeletePost Delete any post
+// @summary Delete any post
+// @description Delete a post regardless of its author
+// @tags Admin
+// @id admin-delete-post
+// @produce json
+// @param id path int true "Post ID"
+// @success 200 {object} api.NoDataResponse "Post deleted"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 403 {object} api.ErrorResponse "Forbidden"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /admin/post/{id} [delete]
+// @security Bearer
+func (c *AdminController) DeletePost(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.DeletePost(r.Context(), principal.UserID, id, clientInfo(r)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RequestErrorHandler(w, fmt.Errorf("Post with id = %d doesn't exist", id), http.StatusNotFound)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Post with id %v successfully deleted", id))
+}
@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
+)
+
+type APIKeyController struct {
+	Service *services.APIKeyService
+}
+
+// CreateAPIKey Create a personal API key
+// @summary Create a personal API key
+// @description Create a long-lived API key for the authenticated user, the key is only shown in this response
+// @tags API Key
+// @id create-api-key
+// @accept mpfd
+// @produce json
+// @param label formData string false "Label"
+// @param scopes formData string false "Space or comma separated scopes, those of the current token by default"
+// @param expires_at formData string false "Expiry date (RFC 3339)"
+// @success 201 {object} api.GenericSuccessResponse[api.APIKeyCreatedResponse] "API key created"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/api-keys [post]
+// @security Bearer
+func (c *APIKeyController) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var (
+		label      = r.FormValue("label")
+		scopes     = helper.ParseScopes(r.FormValue("scopes"))
+		expiresAtS = r.FormValue("expires_at")
+		expiresAt  *time.Time
+	)
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if expiresAtS != "" {
+		t, err := time.Parse(time.RFC3339, expiresAtS)
+		if err != nil {
+			api.RequestErrorHandler(w, errors.New("expires_at must be a RFC 3339 date"), http.StatusBadRequest)
+			return
+		}
+
+		expiresAt = &t
+	}
+
+	data, err := c.Service.CreateKey(r.Context(), principal.UserID, principal.Scopes, label, scopes, expiresAt)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			api.ValidationErrorHandler(w, validationErr.Fields)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusCreated, data)
+}
+
+// APIKeys List the personal API keys
+// @summary List the personal API keys
+// @description List the API keys of the authenticated user, including revoked and expired keys
+// @tags API Key
+// @id get-api-keys
+// @produce json
+// @success 200 {object} api.GenericSuccessResponse[[]models.APIKey] "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/api-keys [get]
+// @security Bearer
+func (c *APIKeyController) APIKeys(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := c.Service.GetKeys(r.Context(), principal.UserID)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, keys)
+}
+
+// UpdateAPIKey Label a personal API key
+// @summary Label a personal API key
+// @description Change the label of an API key owned by the authenticated user
+// @tags API Key
+// @id update-api-key
+// @accept mpfd
+// @produce json
+// @param id path int true "API Key ID"
+// @param label formData string true "Label"
+// @success 200 {object} api.NoDataResponse "API key updated"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/api-keys/{id} [put]
+// @security Bearer
+func (c *APIKeyController) UpdateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var (
+		label   = r.FormValue("label")
+		id, err = strconv.Atoi(mux.Vars(r)["id"])
+	)
+
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.UpdateLabel(r.Context(), principal.UserID, id, label); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RequestErrorHandler(w, fmt.Errorf("API key with id = %d doesn't exist", id), http.StatusNotFound)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("API key with id %v successfully updated", id))
+}
+
+// RevokeAPIKey Revoke a personal API key
+// @summary Revoke a personal API key
+// @description Revoke an API key owned by the authenticated user, it can't be used anymore afterwards
+// @tags API Key
+// @id revoke-api-key
+// @produce json
+// @param id path int true "API Key ID"
+// @success 200 {object} api.NoDataResponse "API key revoked"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/api-keys/{id} [delete]
+// @security Bearer
+func (c *APIKeyController) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	var (
+		id, err = strconv.Atoi(mux.Vars(r)["id"])
+	)
+
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.RevokeKey(r.Context(), principal.UserID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RequestErrorHandler(w, fmt.Errorf("API key with id = %d doesn't exist", id), http.StatusNotFound)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("API key with id %v successfully revoked", id))
+}
@@ -0,0 +1,91 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/simple-crud-go/internal/services"
+)
+
+type AuditController struct {
+	Service *services.AuditService
+}
+
+// AuditEvents Search the audit log
+// @summary Search the audit log
+// @description List the audit events matching the filters, newest first. Pass the id of the last event as before_id to get the next page
+// @tags Admin
+// @id admin-audit-events
+// @produce json
+// @param action query string false "Action (e.g. auth.login_failed)"
+// @param actor_id query int false "ID of the user who did the action"
+// @param target_type query string false "Target type (user or post)"
+// @param target_id query string false "Target ID"
+// @param since query string false "Events at or after this date (RFC 3339)"
+// @param until query string false "Events before this date (RFC 3339)"
+// @param before_id query int false "Events older than this event"
+// @param limit query int false "Page size, 50 by default and 200 at most"
+// @success 200 {object} api.GenericSuccessResponse[[]models.AuditEvent] "Success"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 403 {object} api.ErrorResponse "Forbidden"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /admin/audit [get]
+// @security Bearer
+func (c *AuditController) AuditEvents(w http.ResponseWriter, r *http.Request) {
+	var (
+		query  = r.URL.Query()
+		filter = repository.AuditFilter{
+			Action:     query.Get("action"),
+			TargetType: query.Get("target_type"),
+			TargetID:   query.Get("target_id"),
+		}
+	)
+
+	for name, target := range map[string]*uint{"actor_id": &filter.ActorID, "before_id": &filter.BeforeID} {
+		if value := query.Get(name); value != "" {
+			id, err := strconv.ParseUint(value, 10, 0)
+			if err != nil {
+				api.RequestErrorHandler(w, fmt.Errorf("%v must be a positive number", name), http.StatusBadRequest)
+				return
+			}
+
+			*target = uint(id)
+		}
+	}
+
+	for name, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				api.RequestErrorHandler(w, fmt.Errorf("%v must be a RFC 3339 date", name), http.StatusBadRequest)
+				return
+			}
+
+			*target = &t
+		}
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			api.RequestErrorHandler(w, errors.New("limit must be a number"), http.StatusBadRequest)
+			return
+		}
+
+		filter.Limit = n
+	}
+
+	events, err := c.Service.Search(r.Context(), filter)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, events)
+}
@@ -2,9 +2,14 @@ package controller
 
 import (
 	"errors"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/middleware"
 	"github.com/simple-crud-go/internal/services"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -39,7 +44,7 @@ func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := c.Service.Login(username, password)
+	token, err := c.Service.Login(r.Context(), username, password, clientInfo(r))
 	if err != nil {
 		if !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) && !errors.Is(err, gorm.ErrRecordNotFound) {
 			api.InternalErrorHandler(w, err)
@@ -66,6 +71,7 @@ func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 // @success 200 {object} api.GenericSuccessResponse[api.RegisterSuccessResponse] "User Registered"
 // @failure 400 {object} api.ErrorResponse "Bad Request"
 // @failure 409 {object} api.ErrorResponse "Conflict"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
 // @failure 500 {object} api.ErrorResponse "Internal Server Error"
 // @router /register [post]
 func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
@@ -80,11 +86,15 @@ func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := c.Service.Register(name, username, password)
+	data, err := c.Service.Register(r.Context(), name, username, password, clientInfo(r))
 	if err != nil {
+		var validationErr *services.ValidationError
 		if errors.Is(err, services.ErrUserExist) {
 			api.RequestErrorHandler(w, err, http.StatusConflict)
 			return
+		} else if errors.As(err, &validationErr) {
+			api.ValidationErrorHandler(w, validationErr.Fields)
+			return
 		}
 
 		api.InternalErrorHandler(w, err)
@@ -93,3 +103,79 @@ func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
 
 	api.GenericResponseHandler(w, 200, data)
 }
+
+// CreateScopedToken Create a reduced-scope token
+// @summary Create a reduced-scope token
+// @description Create a token for an integration, limited to some of the scopes of the current token. The token has its own session and can be revoked from the sessions
+// @tags Authentication
+// @id create-scoped-token
+// @accept mpfd
+// @produce json
+// @param scopes formData string true "Space or comma separated scopes"
+// @param expires_in formData int false "Lifetime in seconds, defaults to a day"
+// @success 201 {object} api.GenericSuccessResponse[string] "JWT Token"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/tokens [post]
+// @security Bearer
+func (c *AuthController) CreateScopedToken(w http.ResponseWriter, r *http.Request) {
+	var (
+		scopes     = helper.ParseScopes(r.FormValue("scopes"))
+		expiresInS = r.FormValue("expires_in")
+		expiresIn  time.Duration
+	)
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if expiresInS != "" {
+		seconds, err := strconv.Atoi(expiresInS)
+		if err != nil {
+			api.RequestErrorHandler(w, errors.New("expires_in must be a number of seconds"), http.StatusBadRequest)
+			return
+		}
+
+		expiresIn = time.Duration(seconds) * time.Second
+	}
+
+	token, err := c.Service.CreateScopedToken(r.Context(), principal.UserID, principal.Scopes, scopes, expiresIn, clientInfo(r))
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			api.ValidationErrorHandler(w, validationErr.Fields)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusCreated, token)
+}
+
+// clientInfo describes the device making the request, recorded on the session created at login.
+func clientInfo(r *http.Request) services.ClientInfo {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return services.ClientInfo{
+		IP:        ip,
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// authenticated returns the caller stored by the auth middleware, replying 401 when the request is anonymous.
+func authenticated(w http.ResponseWriter, r *http.Request) (*middleware.Principal, bool) {
+	principal, ok := middleware.PrincipalFrom(r.Context())
+	if !ok {
+		api.RequestErrorHandler(w, errors.New("Missing authentication"), http.StatusUnauthorized)
+	}
+
+	return principal, ok
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
+)
+
+type BookmarkController struct {
+	Service *services.BookmarkService
+}
+
+// Bookmark Bookmark a post
+// @summary Bookmark a post
+// @description Save a post to read later, optionally in a named collection. Bookmarking a post again moves it to the given collection
+// @tags Bookmark
+// @id bookmark-post
+// @accept mpfd
+// @produce json
+// @param id path int true "Post ID"
+// @param collection formData string false "Collection name"
+// @success 200 {object} api.NoDataResponse "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /post/{id}/bookmark [put]
+// @security Bearer
+func (c *BookmarkController) Bookmark(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.Bookmark(r.Context(), principal.UserID, id, r.FormValue("collection")); err != nil {
+		var validationErr *services.ValidationError
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RequestErrorHandler(w, fmt.Errorf("Post with id = %d doesn't exist", id), http.StatusNotFound)
+		} else if errors.As(err, &validationErr) {
+			api.ValidationErrorHandler(w, validationErr.Fields)
+		} else {
+			api.InternalErrorHandler(w, err)
+		}
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, "Post bookmarked")
+}
+
+// RemoveBookmark Remove a bookmark
+// @summary Remove a bookmark
+// @description Remove a post from the bookmarks, removing a post that isn't bookmarked is not an error
+// @tags Bookmark
+// @id remove-bookmark
+// @produce json
+// @param id path int true "Post ID"
+// @success 200 {object} api.NoDataResponse "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /post/{id}/bookmark [delete]
+// @security Bearer
+func (c *BookmarkController) RemoveBookmark(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.RemoveBookmark(r.Context(), principal.UserID, id); err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, "Bookmark removed")
+}
+
+// Bookmarks List the bookmarks
+// @summary List the bookmarks
+// @description List the bookmarked posts of the authenticated user, newest first. Pass the id of the last bookmark as before_id to get the next page
+// @tags Bookmark
+// @id get-bookmarks
+// @produce json
+// @param collection query string false "Only the bookmarks of this collection, pass an empty value for the bookmarks without collection"
+// @param before_id query int false "Bookmarks older than this bookmark"
+// @param limit query int false "Page size, 20 by default and 100 at most"
+// @success 200 {object} api.GenericSuccessResponse[[]models.Bookmark] "Success"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/bookmarks [get]
+// @security Bearer
+func (c *BookmarkController) Bookmarks(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	beforeId, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	var collection *string
+	if values, ok := r.URL.Query()["collection"]; ok {
+		collection = &values[0]
+	}
+
+	bookmarks, err := c.Service.GetBookmarks(r.Context(), principal.UserID, collection, beforeId, limit)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, bookmarks)
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	collaborationWriteWait = 10 * time.Second
+	// collaborationPongWait a client not answering the pings for this long is disconnected.
+	collaborationPongWait   = 60 * time.Second
+	collaborationPingPeriod = collaborationPongWait * 9 / 10
+	collaborationMaxMessage = 64 * 1024
+)
+
+type CollaborationController struct {
+	Hub      *services.CollaborationHub
+	Upgrader websocket.Upgrader
+}
+
+// Collaborate Live post collaboration
+// @summary Live post collaboration
+// @description WebSocket endpoint. Send {"type":"subscribe","post_id":1} to receive the presence, typing indicators and live edits of a post, then "typing" and "edit" (author only) messages of your own. Browsers can pass the token as the access_token query parameter
+// @tags Collaboration
+// @id collaborate
+// @param access_token query string false "JWT, for clients that can't set the Authorization header"
+// @success 101 {string} string "Switching Protocols"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @router /collaborate [get]
+// @security Bearer
+func (c *CollaborationController) Collaborate(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	// The upgrader writes the error response itself
+	conn, err := c.Upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	client := c.Hub.Connect(uint(principal.UserID), principal.Username)
+
+	go c.writeMessages(conn, client)
+
+	conn.SetReadLimit(collaborationMaxMessage)
+	conn.SetReadDeadline(time.Now().Add(collaborationPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(collaborationPongWait))
+	})
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logrus.Error(err)
+			}
+			break
+		}
+
+		c.Hub.Handle(r.Context(), client, message)
+	}
+
+	// Closes client.Send, which stops the writer
+	c.Hub.Disconnect(client)
+}
+
+// writeMessages the only goroutine writing to the connection, it closes it once the client is disconnected.
+func (c *CollaborationController) writeMessages(conn *websocket.Conn, client *services.CollaborationClient) {
+	ping := time.NewTicker(collaborationPingPeriod)
+	defer func() {
+		ping.Stop()
+		conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-client.Send:
+			conn.SetWriteDeadline(time.Now().Add(collaborationWriteWait))
+
+			if !ok {
+				code, reason := websocket.CloseNormalClosure, ""
+				if client.Slow {
+					code, reason = websocket.CloseTryAgainLater, "too slow to keep up"
+				}
+
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason))
+				return
+			}
+
+			if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				// The read loop fails as well and disconnects the client
+				conn.Close()
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(collaborationWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}
+}
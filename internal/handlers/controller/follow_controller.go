@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
+)
+
+type FollowController struct {
+	Service *services.FollowService
+}
+
+// Follow Follow a user
+// @summary Follow a user
+// @description Follow a user, their posts show up in the home feed. Following a user twice is not an error
+// @tags Follow
+// @id follow-user
+// @produce json
+// @param username path string true "Username"
+// @success 200 {object} api.NoDataResponse "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /user/{username}/follow [post]
+// @security Bearer
+func (c *FollowController) Follow(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Service.Follow(r.Context(), principal.UserID, username); err != nil {
+		followErrorHandler(w, username, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Following %v", username))
+}
+
+// Unfollow Unfollow a user
+// @summary Unfollow a user
+// @description Unfollow a user, unfollowing a user who isn't followed is not an error
+// @tags Follow
+// @id unfollow-user
+// @produce json
+// @param username path string true "Username"
+// @success 200 {object} api.NoDataResponse "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /user/{username}/follow [delete]
+// @security Bearer
+func (c *FollowController) Unfollow(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Service.Unfollow(r.Context(), principal.UserID, username); err != nil {
+		followErrorHandler(w, username, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Unfollowed %v", username))
+}
+
+// Followers List the followers of a user
+// @summary List the followers of a user
+// @description List the followers of a user, newest first. Pass the id of the last follow as before_id to get the next page
+// @tags Follow
+// @id get-followers
+// @produce json
+// @param username path string true "Username"
+// @param before_id query int false "Follows older than this follow"
+// @param limit query int false "Page size, 20 by default and 100 at most"
+// @success 200 {object} api.GenericSuccessResponse[[]models.Follow] "Success"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /user/{username}/followers [get]
+func (c *FollowController) Followers(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	beforeId, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	follows, err := c.Service.GetFollowers(r.Context(), username, beforeId, limit)
+	if err != nil {
+		followErrorHandler(w, username, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, follows)
+}
+
+// Following List the users a user follows
+// @summary List the users a user follows
+// @description List the users a user follows, newest first. Pass the id of the last follow as before_id to get the next page
+// @tags Follow
+// @id get-following
+// @produce json
+// @param username path string true "Username"
+// @param before_id query int false "Follows older than this follow"
+// @param limit query int false "Page size, 20 by default and 100 at most"
+// @success 200 {object} api.GenericSuccessResponse[[]models.Follow] "Success"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /user/{username}/following [get]
+func (c *FollowController) Following(w http.ResponseWriter, r *http.Request) {
+	username := mux.Vars(r)["username"]
+
+	beforeId, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	follows, err := c.Service.GetFollowing(r.Context(), username, beforeId, limit)
+	if err != nil {
+		followErrorHandler(w, username, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, follows)
+}
+
+// Feed Home feed
+// @summary Home feed
+// @description Posts of the users the authenticated user follows, newest first. Pass the id of the last post as before_id to get the next page
+// @tags Follow
+// @id get-feed
+// @produce json
+// @param before_id query int false "Posts older than this post"
+// @param limit query int false "Page size, 20 by default and 100 at most"
+// @success 200 {object} api.GenericSuccessResponse[[]models.Post] "Success"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/feed [get]
+// @security Bearer
+func (c *FollowController) Feed(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	beforeId, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	posts, err := c.Service.GetFeed(r.Context(), principal.UserID, beforeId, limit)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, posts)
+}
+
+func followErrorHandler(w http.ResponseWriter, username string, err error) {
+	var validationErr *services.ValidationError
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		api.RequestErrorHandler(w, fmt.Errorf("User with %s not found", username), http.StatusNotFound)
+	} else if errors.As(err, &validationErr) {
+		api.ValidationErrorHandler(w, validationErr.Fields)
+	} else {
+		api.InternalErrorHandler(w, err)
+	}
+}
+
+// pagination reads the `before_id` and `limit` query parameters, a bad request response is written when they are invalid.
+func pagination(w http.ResponseWriter, r *http.Request) (uint, int, bool) {
+	var (
+		query    = r.URL.Query()
+		beforeId uint64
+		limit    int
+		err      error
+	)
+
+	if value := query.Get("before_id"); value != "" {
+		if beforeId, err = strconv.ParseUint(value, 10, 0); err != nil {
+			api.RequestErrorHandler(w, errors.New("before_id must be a positive number"), http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			api.RequestErrorHandler(w, errors.New("limit must be a number"), http.StatusBadRequest)
+			return 0, 0, false
+		}
+	}
+
+	return uint(beforeId), limit, true
+}
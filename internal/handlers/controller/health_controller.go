@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+)
+
+type HealthController struct {
+	Service *services.HealthService
+}
+
+// poolMetrics exported by Metrics for every database, labelled with the name of the database.
+var poolMetrics = []struct {
+	name  string
+	kind  string
+	help  string
+	value func(database api.DatabaseHealth) float64
+}{
+	{"db_up", "gauge", "Whether the database answers, the replicas as of their last health check.", func(d api.DatabaseHealth) float64 {
+		if d.Healthy {
+			return 1
+		}
+		return 0
+	}},
+	{"db_pool_max_open_connections", "gauge", "The most connections open at once, 0 is unlimited.", func(d api.DatabaseHealth) float64 { return float64(d.Pool.MaxOpen) }},
+	{"db_pool_open_connections", "gauge", "Connections open, in use or idle.", func(d api.DatabaseHealth) float64 { return float64(d.Pool.Open) }},
+	{"db_pool_in_use_connections", "gauge", "Connections running a query.", func(d api.DatabaseHealth) float64 { return float64(d.Pool.InUse) }},
+	{"db_pool_idle_connections", "gauge", "Connections kept open for the next query.", func(d api.DatabaseHealth) float64 { return float64(d.Pool.Idle) }},
+	{"db_pool_wait_count_total", "counter", "Queries that had to wait for a free connection.", func(d api.DatabaseHealth) float64 { return float64(d.Pool.WaitCount) }},
+	{"db_pool_wait_duration_seconds_total", "counter", "Time spent waiting for a free connection.", func(d api.DatabaseHealth) float64 { return float64(d.Pool.WaitDurationMs) / 1000 }},
+	{"db_pool_max_idle_closed_total", "counter", "Connections closed because of the limit of idle connections.", func(d api.DatabaseHealth) float64 { return float64(d.Pool.MaxIdleClosed) }},
+	{"db_pool_max_idle_time_closed_total", "counter", "Connections closed after being idle for too long.", func(d api.DatabaseHealth) float64 { return float64(d.Pool.MaxIdleTimeClosed) }},
+	{"db_pool_max_lifetime_closed_total", "counter", "Connections closed at the end of their lifetime.", func(d api.DatabaseHealth) float64 { return float64(d.Pool.MaxLifetimeClosed) }},
+}
+
+// Health the state of the databases and their connection pools, 503 when the primary database doesn't answer. It is
+// served outside of /api so load balancers can reach it without credentials.
+func (c *HealthController) Health(w http.ResponseWriter, r *http.Request) {
+	health := c.Service.Check(r.Context())
+
+	code := http.StatusOK
+	if health.Status == api.HealthUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	api.GenericResponseHandler(w, code, health)
+}
+
+// Metrics the connection pool statistics in the Prometheus text format.
+func (c *HealthController) Metrics(w http.ResponseWriter, r *http.Request) {
+	health := c.Service.Check(r.Context())
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+	for _, metric := range poolMetrics {
+		fmt.Fprintf(w, "# HELP %v %v\n# TYPE %v %v\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, database := range health.Databases {
+			fmt.Fprintf(w, "%v{database=%q} %v\n", metric.name, database.Name, metric.value(database))
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
+)
+
+type NotificationController struct {
+	Service *services.NotificationService
+}
+
+// Notifications List the notifications
+// @summary List the notifications
+// @description List the notifications of the authenticated user, newest first, with the number of unread notifications. Pass the id of the last notification as before_id to get the next page
+// @tags Notification
+// @id get-notifications
+// @produce json
+// @param unread query bool false "Only the unread notifications"
+// @param before_id query int false "Notifications older than this notification"
+// @param limit query int false "Page size, 20 by default and 100 at most"
+// @success 200 {object} api.GenericSuccessResponse[api.NotificationsResponse] "Success"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/notifications [get]
+// @security Bearer
+func (c *NotificationController) Notifications(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	beforeId, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	var unreadOnly bool
+	if value := r.URL.Query().Get("unread"); value != "" {
+		var err error
+		if unreadOnly, err = strconv.ParseBool(value); err != nil {
+			api.RequestErrorHandler(w, errors.New("unread must be true or false"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	data, err := c.Service.GetNotifications(r.Context(), principal.UserID, unreadOnly, beforeId, limit)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, data)
+}
+
+// MarkRead Mark a notification as read
+// @summary Mark a notification as read
+// @description Mark a notification of the authenticated user as read
+// @tags Notification
+// @id mark-notification-read
+// @produce json
+// @param id path int true "Notification ID"
+// @success 200 {object} api.NoDataResponse "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/notifications/{id}/read [post]
+// @security Bearer
+func (c *NotificationController) MarkRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.MarkRead(r.Context(), principal.UserID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RequestErrorHandler(w, fmt.Errorf("Notification with id = %d doesn't exist", id), http.StatusNotFound)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, "Notification marked as read")
+}
+
+// MarkAllRead Mark every notification as read
+// @summary Mark every notification as read
+// @description Mark every notification of the authenticated user as read
+// @tags Notification
+// @id mark-all-notifications-read
+// @produce json
+// @success 200 {object} api.NoDataResponse "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/notifications/read [post]
+// @security Bearer
+func (c *NotificationController) MarkAllRead(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Service.MarkAllRead(r.Context(), principal.UserID); err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, "Notifications marked as read")
+}
+
+// Preferences Get the notification preferences
+// @summary Get the notification preferences
+// @description Whether each notification type is enabled for the authenticated user
+// @tags Notification
+// @id get-notification-preferences
+// @produce json
+// @success 200 {object} api.GenericSuccessResponse[map[string]bool] "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/notification-preferences [get]
+// @security Bearer
+func (c *NotificationController) Preferences(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	preferences, err := c.Service.GetPreferences(r.Context(), principal.UserID)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, preferences)
+}
+
+// SetPreference Turn a notification type on or off
+// @summary Turn a notification type on or off
+// @description Turn a notification type on or off for the authenticated user
+// @tags Notification
+// @id set-notification-preference
+// @accept mpfd
+// @produce json
+// @param type path string true "Notification type" Enums(follow, reaction)
+// @param enabled formData bool true "Whether the notifications of this type are sent"
+// @success 200 {object} api.NoDataResponse "Success"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/notification-preferences/{type} [put]
+// @security Bearer
+func (c *NotificationController) SetPreference(w http.ResponseWriter, r *http.Request) {
+	enabled, err := strconv.ParseBool(r.FormValue("enabled"))
+	if err != nil {
+		api.RequestErrorHandler(w, errors.New("enabled must be true or false"), http.StatusBadRequest)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.SetPreference(r.Context(), principal.UserID, mux.Vars(r)["type"], enabled); err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			api.ValidationErrorHandler(w, validationErr.Fields)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, "Notification preference saved")
+}
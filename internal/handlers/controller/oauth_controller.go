@@ -0,0 +1,392 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
+)
+
+type OAuthController struct {
+	Service *services.OAuthService
+}
+
+// RegisterClient Register an OAuth client
+// @summary Register an OAuth client
+// @description Register a third-party application owned by the authenticated user, the client secret is only shown in this response
+// @tags OAuth
+// @id register-oauth-client
+// @accept mpfd
+// @produce json
+// @param name formData string true "Application name"
+// @param redirect_uris formData string true "Space separated redirect URIs"
+// @param scopes formData string true "Space separated scopes the client may request"
+// @param confidential formData bool false "Whether the client can keep a secret (server side application)"
+// @success 201 {object} api.GenericSuccessResponse[api.OAuthClientCreatedResponse] "Client registered"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /oauth/clients [post]
+// @security Bearer
+func (c *OAuthController) RegisterClient(w http.ResponseWriter, r *http.Request) {
+	var (
+		name         = r.FormValue("name")
+		redirectURIs = strings.Fields(strings.Join(r.Form["redirect_uris"], " "))
+		scopes       = helper.ParseScopes(r.FormValue("scopes"))
+		confidential = r.FormValue("confidential") == "true"
+	)
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := c.Service.RegisterClient(r.Context(), principal.UserID, principal.Scopes, name, redirectURIs, scopes, confidential)
+	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			api.ValidationErrorHandler(w, validationErr.Fields)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusCreated, data)
+}
+
+// Clients List the OAuth clients
+// @summary List the OAuth clients
+// @description List the OAuth clients registered by the authenticated user
+// @tags OAuth
+// @id get-oauth-clients
+// @produce json
+// @success 200 {object} api.GenericSuccessResponse[[]models.OAuthClient] "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /oauth/clients [get]
+// @security Bearer
+func (c *OAuthController) Clients(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	clients, err := c.Service.GetClients(r.Context(), principal.UserID)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, clients)
+}
+
+// DeleteClient Delete an OAuth client
+// @summary Delete an OAuth client
+// @description Delete an OAuth client owned by the authenticated user, revoking every token issued to it
+// @tags OAuth
+// @id delete-oauth-client
+// @produce json
+// @param client_id path string true "Client ID"
+// @success 200 {object} api.NoDataResponse "Client deleted"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /oauth/clients/{client_id} [delete]
+// @security Bearer
+func (c *OAuthController) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	var (
+		clientId = mux.Vars(r)["client_id"]
+	)
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Service.DeleteClient(r.Context(), principal.UserID, clientId); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RequestErrorHandler(w, fmt.Errorf("Client %v doesn't exist", clientId), http.StatusNotFound)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Client %v successfully deleted", clientId))
+}
+
+// AuthorizationInfo Describe an authorization request
+// @summary Describe an authorization request
+// @description Validates an authorization code request and returns what the authenticated user is asked to consent to
+// @tags OAuth
+// @id describe-oauth-authorization
+// @produce json
+// @param response_type query string true "Must be code"
+// @param client_id query string true "Client ID"
+// @param redirect_uri query string true "Redirect URI"
+// @param scope query string false "Space separated scopes"
+// @param state query string false "State"
+// @param code_challenge query string true "PKCE code challenge"
+// @param code_challenge_method query string true "Must be S256"
+// @success 200 {object} api.GenericSuccessResponse[api.OAuthAuthorizationResponse] "Success"
+// @failure 400 {object} api.OAuthErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /oauth/authorize [get]
+// @security Bearer
+func (c *OAuthController) AuthorizationInfo(w http.ResponseWriter, r *http.Request) {
+	var (
+		req = authorizationRequest(r)
+	)
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := c.Service.DescribeAuthorization(r.Context(), principal.UserID, principal.Scopes, req)
+	if err != nil {
+		oauthErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, data)
+}
+
+// Authorize Approve or deny an authorization request
+// @summary Approve or deny an authorization request
+// @description Records the decision of the authenticated user and returns where to redirect the user agent, with the authorization code or the access_denied error
+// @tags OAuth
+// @id oauth-authorize
+// @accept mpfd
+// @produce json
+// @param response_type formData string true "Must be code"
+// @param client_id formData string true "Client ID"
+// @param redirect_uri formData string true "Redirect URI"
+// @param scope formData string false "Space separated scopes"
+// @param state formData string false "State"
+// @param code_challenge formData string true "PKCE code challenge"
+// @param code_challenge_method formData string true "Must be S256"
+// @param approve formData bool true "Whether the user approved the request"
+// @success 200 {object} api.GenericSuccessResponse[api.OAuthRedirectResponse] "Success"
+// @failure 400 {object} api.OAuthErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /oauth/authorize [post]
+// @security Bearer
+func (c *OAuthController) Authorize(w http.ResponseWriter, r *http.Request) {
+	var (
+		approved = r.FormValue("approve") == "true"
+		req      = authorizationRequest(r)
+	)
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	redirectTo, err := c.Service.Authorize(r.Context(), principal.UserID, principal.Scopes, req, approved)
+	if err != nil {
+		oauthErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, api.OAuthRedirectResponse{RedirectTo: redirectTo})
+}
+
+// Token Issue an access token
+// @summary Issue an access token
+// @description Token endpoint supporting the authorization_code (with PKCE) and client_credentials grants, clients authenticate with HTTP Basic or the client_id/client_secret fields
+// @tags OAuth
+// @id oauth-token
+// @accept x-www-form-urlencoded
+// @produce json
+// @param grant_type formData string true "authorization_code or client_credentials"
+// @param code formData string false "Authorization code"
+// @param redirect_uri formData string false "Redirect URI used to get the code"
+// @param code_verifier formData string false "PKCE code verifier"
+// @param scope formData string false "Space separated scopes (client_credentials)"
+// @param client_id formData string false "Client ID"
+// @param client_secret formData string false "Client secret"
+// @success 200 {object} api.OAuthTokenResponse "Access token"
+// @failure 400 {object} api.OAuthErrorResponse "Bad Request"
+// @failure 401 {object} api.OAuthErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /oauth/token [post]
+func (c *OAuthController) Token(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret := clientCredentials(r)
+
+	data, err := c.Service.Token(r.Context(), services.TokenRequest{
+		GrantType:    r.FormValue("grant_type"),
+		ClientID:     clientId,
+		ClientSecret: clientSecret,
+		Code:         r.FormValue("code"),
+		RedirectURI:  r.FormValue("redirect_uri"),
+		CodeVerifier: r.FormValue("code_verifier"),
+		Scopes:       helper.ParseScopes(r.FormValue("scope")),
+	})
+	if err != nil {
+		oauthErrorHandler(w, err)
+		return
+	}
+
+	api.OAuthResponseHandler(w, data)
+}
+
+// Introspect Introspect an access token
+// @summary Introspect an access token
+// @description Token introspection (RFC 7662) for tokens issued to the authenticated client
+// @tags OAuth
+// @id oauth-introspect
+// @accept x-www-form-urlencoded
+// @produce json
+// @param token formData string true "Access token"
+// @param client_id formData string false "Client ID"
+// @param client_secret formData string false "Client secret"
+// @success 200 {object} api.OAuthIntrospectionResponse "Token information"
+// @failure 401 {object} api.OAuthErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /oauth/introspect [post]
+func (c *OAuthController) Introspect(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret := clientCredentials(r)
+
+	data, err := c.Service.Introspect(r.Context(), clientId, clientSecret, r.FormValue("token"))
+	if err != nil {
+		oauthErrorHandler(w, err)
+		return
+	}
+
+	api.OAuthResponseHandler(w, data)
+}
+
+// Revoke Revoke an access token
+// @summary Revoke an access token
+// @description Token revocation (RFC 7009) for tokens issued to the authenticated client
+// @tags OAuth
+// @id oauth-revoke
+// @accept x-www-form-urlencoded
+// @produce json
+// @param token formData string true "Access token"
+// @param client_id formData string false "Client ID"
+// @param client_secret formData string false "Client secret"
+// @success 200 "Token revoked"
+// @failure 401 {object} api.OAuthErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /oauth/revoke [post]
+func (c *OAuthController) Revoke(w http.ResponseWriter, r *http.Request) {
+	clientId, clientSecret := clientCredentials(r)
+
+	if err := c.Service.Revoke(r.Context(), clientId, clientSecret, r.FormValue("token")); err != nil {
+		oauthErrorHandler(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// Consents List the consents given to OAuth clients
+// @summary List the consents given to OAuth clients
+// @description List the applications the authenticated user principal.Scopes access to
+// @tags OAuth
+// @id get-oauth-consents
+// @produce json
+// @success 200 {object} api.GenericSuccessResponse[[]models.OAuthConsent] "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/consents [get]
+// @security Bearer
+func (c *OAuthController) Consents(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	consents, err := c.Service.GetConsents(r.Context(), principal.UserID)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, consents)
+}
+
+// RevokeConsent Revoke the consent given to an OAuth client
+// @summary Revoke the consent given to an OAuth client
+// @description Withdraw the access principal.Scopes to an application, revoking the tokens it got
+// @tags OAuth
+// @id revoke-oauth-consent
+// @produce json
+// @param client_id path string true "Client ID"
+// @success 200 {object} api.NoDataResponse "Consent revoked"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/consents/{client_id} [delete]
+// @security Bearer
+func (c *OAuthController) RevokeConsent(w http.ResponseWriter, r *http.Request) {
+	var (
+		clientId = mux.Vars(r)["client_id"]
+	)
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Service.RevokeConsent(r.Context(), principal.UserID, clientId); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RequestErrorHandler(w, fmt.Errorf("No consent was given to client %v", clientId), http.StatusNotFound)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Consent given to client %v successfully revoked", clientId))
+}
+
+func authorizationRequest(r *http.Request) services.AuthorizationRequest {
+	return services.AuthorizationRequest{
+		ResponseType:        r.FormValue("response_type"),
+		ClientID:            r.FormValue("client_id"),
+		RedirectURI:         r.FormValue("redirect_uri"),
+		Scopes:              helper.ParseScopes(r.FormValue("scope")),
+		State:               r.FormValue("state"),
+		CodeChallenge:       r.FormValue("code_challenge"),
+		CodeChallengeMethod: r.FormValue("code_challenge_method"),
+	}
+}
+
+// clientCredentials reads the client credentials from HTTP Basic authentication, falling back to the form fields.
+func clientCredentials(r *http.Request) (string, string) {
+	if clientId, clientSecret, ok := r.BasicAuth(); ok {
+		return clientId, clientSecret
+	}
+
+	return r.FormValue("client_id"), r.FormValue("client_secret")
+}
+
+func oauthErrorHandler(w http.ResponseWriter, err error) {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	code := http.StatusBadRequest
+	if oauthErr.Code == services.ErrOAuthInvalidClient.Code {
+		code = http.StatusUnauthorized
+	}
+
+	api.OAuthErrorHandler(w, code, oauthErr.Code, oauthErr.Description)
+}
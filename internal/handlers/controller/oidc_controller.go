@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+)
+
+// oidcStateCookie ties a login to the browser that started it, the callback is refused without it.
+const oidcStateCookie = "oidc_state"
+
+type OIDCController struct {
+	Service *services.OIDCService
+}
+
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, value string, maxAge int) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https"),
+		// Lax still sends it along with the redirect back from the provider
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Login Log in with the OpenID Connect provider
+// @summary Log in with the OpenID Connect provider
+// @description Redirects the user to the OpenID Connect provider, the provider sends the user back to the callback
+// @tags Authentication
+// @id oidc-login
+// @success 302 "Redirect to the provider"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /auth/oidc/login [get]
+func (c *OIDCController) Login(w http.ResponseWriter, r *http.Request) {
+	url, signedState, err := c.Service.AuthCodeURL(0)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	setOIDCStateCookie(w, r, signedState, int(services.OIDCLoginTTL.Seconds()))
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
+// Callback Finish the OpenID Connect login
+// @summary Finish the OpenID Connect login
+// @description Exchanges the authorization code, creates the user on the first login and returns a JWT, only in the browser that started the login
+// @tags Authentication
+// @id oidc-callback
+// @produce json
+// @param state query string true "State"
+// @param code query string true "Authorization code"
+// @success 200 {object} api.GenericSuccessResponse[api.RegisterSuccessResponse] "Logged in"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 409 {object} api.ErrorResponse "Conflict"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /auth/oidc/callback [get]
+func (c *OIDCController) Callback(w http.ResponseWriter, r *http.Request) {
+	var (
+		query         = r.URL.Query()
+		state         = query.Get("state")
+		code          = query.Get("code")
+		providerError = query.Get("error")
+	)
+
+	if providerError != "" {
+		api.RequestErrorHandler(w, fmt.Errorf("The provider refused the login: %v", providerError), http.StatusBadRequest)
+		return
+	}
+
+	if state == "" || code == "" {
+		api.RequestErrorHandler(w, errors.New("state and code query parameters are required"), http.StatusBadRequest)
+		return
+	}
+
+	var signedState string
+	if cookie, err := r.Cookie(oidcStateCookie); err == nil {
+		signedState = cookie.Value
+	}
+
+	// The state can only be used once, whatever the outcome
+	setOIDCStateCookie(w, r, "", -1)
+
+	data, err := c.Service.HandleCallback(r.Context(), state, signedState, code, clientInfo(r))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidOIDCState) {
+			api.RequestErrorHandler(w, err, http.StatusBadRequest)
+			return
+		} else if errors.Is(err, services.ErrIdentityAlreadyLinked) {
+			api.RequestErrorHandler(w, err, http.StatusConflict)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, data)
+}
+
+// LinkIdentity Link an OpenID Connect account
+// @summary Link an OpenID Connect account
+// @description Returns the provider URL the authenticated user has to visit, in the same browser, to link their external account
+// @tags Authentication
+// @id oidc-link
+// @produce json
+// @success 200 {object} api.GenericSuccessResponse[string] "Provider URL"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/identities/oidc [post]
+// @security Bearer
+func (c *OIDCController) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	url, signedState, err := c.Service.AuthCodeURL(principal.UserID)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	setOIDCStateCookie(w, r, signedState, int(services.OIDCLoginTTL.Seconds()))
+
+	api.GenericResponseHandler(w, http.StatusOK, url)
+}
+
+// Identities List the linked OpenID Connect accounts
+// @summary List the linked OpenID Connect accounts
+// @description List the external accounts linked to the authenticated user
+// @tags Authentication
+// @id get-identities
+// @produce json
+// @success 200 {object} api.GenericSuccessResponse[[]models.Identity] "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/identities [get]
+// @security Bearer
+func (c *OIDCController) Identities(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	identities, err := c.Service.GetIdentities(r.Context(), principal.UserID)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, identities)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/simple-crud-go/api"
 	"github.com/simple-crud-go/internal/middleware"
+	"github.com/simple-crud-go/internal/models"
 	"github.com/simple-crud-go/internal/services"
 	"gorm.io/gorm"
 )
@@ -35,7 +36,7 @@ func (c *PostController) GetPostById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	post, err := c.Service.GetPostById(id)
+	post, err := c.Service.GetPostById(r.Context(), id, viewerID(r))
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -44,6 +45,8 @@ func (c *PostController) GetPostById(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	markEditable(r, post)
+
 	api.GenericResponseHandler(w, http.StatusOK, post)
 }
 
@@ -53,16 +56,28 @@ func (c *PostController) GetPostById(w http.ResponseWriter, r *http.Request) {
 // @tags Post
 // @id get-all-posts
 // @produce json
+// @param sort query string false "most_reacted to list the posts with the most reactions first"
 // @success 200 {object} api.GenericSuccessResponse[[]models.Post] "Success"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
 // @failure 500 {object} api.ErrorResponse "Internal Server Error"
 // @router /post [get]
 func (c *PostController) GetPosts(w http.ResponseWriter, r *http.Request) {
-	posts, err := c.Service.GetAllPost()
+	posts, err := c.Service.GetAllPost(r.Context(), r.URL.Query().Get("sort"), viewerID(r))
 	if err != nil {
+		var validationErr *services.ValidationError
+		if errors.As(err, &validationErr) {
+			api.ValidationErrorHandler(w, validationErr.Fields)
+			return
+		}
+
 		api.InternalErrorHandler(w, err)
 		return
 	}
 
+	for i := range posts {
+		markEditable(r, &posts[i])
+	}
+
 	api.GenericResponseHandler(w, http.StatusOK, posts)
 }
 
@@ -82,15 +97,12 @@ func (c *PostController) GetPosts(w http.ResponseWriter, r *http.Request) {
 // @security Bearer
 func (c *PostController) CreatePost(w http.ResponseWriter, r *http.Request) {
 	var (
-		title     = r.FormValue("title")
-		body      = r.FormValue("body")
-		ctx       = r.Context()
-		authorIdS = ctx.Value(middleware.UserIdKey).(string)
+		title = r.FormValue("title")
+		body  = r.FormValue("body")
 	)
 
-	authorId, err := strconv.Atoi(authorIdS)
-	if err != nil {
-		api.InternalErrorHandler(w, err)
+	principal, ok := authenticated(w, r)
+	if !ok {
 		return
 	}
 
@@ -99,7 +111,7 @@ func (c *PostController) CreatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := c.Service.CreatePost(authorId, title, body); err != nil {
+	if err := c.Service.CreatePost(r.Context(), principal.UserID, title, body, clientInfo(r)); err != nil {
 		api.InternalErrorHandler(w, err)
 		return
 	}
@@ -129,8 +141,6 @@ func (c *PostController) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		title   = r.FormValue("title")
 		body    = r.FormValue("body")
 		id, err = strconv.Atoi(mux.Vars(r)["id"])
-		ctx     = r.Context()
-		authIdS = ctx.Value(middleware.UserIdKey).(string)
 	)
 
 	if err != nil {
@@ -138,13 +148,12 @@ func (c *PostController) UpdatePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authId, err := strconv.Atoi(authIdS)
-	if err != nil {
-		api.InternalErrorHandler(w, err)
+	principal, ok := authenticated(w, r)
+	if !ok {
 		return
 	}
 
-	if err = c.Service.UpdatePost(authId, id, title, body); err != nil {
+	if err = c.Service.UpdatePost(r.Context(), principal.UserID, id, title, body, clientInfo(r)); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			api.RequestErrorHandler(w, fmt.Errorf("Post with id = %d doesn't exist", id), 404)
 			return
@@ -176,8 +185,6 @@ func (c *PostController) UpdatePost(w http.ResponseWriter, r *http.Request) {
 func (c *PostController) DeletePostById(w http.ResponseWriter, r *http.Request) {
 	var (
 		id, err = strconv.Atoi(mux.Vars(r)["id"])
-		ctx     = r.Context()
-		authIdS = ctx.Value(middleware.UserIdKey).(string)
 	)
 
 	if err != nil {
@@ -185,13 +192,12 @@ func (c *PostController) DeletePostById(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	authId, err := strconv.Atoi(authIdS)
-	if err != nil {
-		api.InternalErrorHandler(w, err)
+	principal, ok := authenticated(w, r)
+	if !ok {
 		return
 	}
 
-	if err = c.Service.DeletePostById(authId, id); err != nil {
+	if err = c.Service.DeletePostById(r.Context(), principal.UserID, id, clientInfo(r)); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			api.RequestErrorHandler(w, fmt.Errorf("Post with id = %d doesn't exist", id), 404)
 			return
@@ -206,3 +212,19 @@ func (c *PostController) DeletePostById(w http.ResponseWriter, r *http.Request)
 
 	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Post with id %v successfully deleted", id))
 }
+
+// viewerID the authenticated caller, 0 for anonymous requests.
+func viewerID(r *http.Request) int {
+	if principal, ok := middleware.PrincipalFrom(r.Context()); ok {
+		return principal.UserID
+	}
+
+	return 0
+}
+
+// markEditable flags the posts written by the caller, requests go through OptionalAuthMiddleware.
+func markEditable(r *http.Request, post *models.Post) {
+	if principal, ok := middleware.PrincipalFrom(r.Context()); ok && post != nil {
+		post.Editable = post.UserID == uint(principal.UserID)
+	}
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
+)
+
+type ReactionController struct {
+	Service *services.ReactionService
+}
+
+// React React to a post
+// @summary React to a post
+// @description React to a post, reacting twice with the same kind is not an error
+// @tags Reaction
+// @id react-to-post
+// @produce json
+// @param id path int true "Post ID"
+// @param kind path string true "Reaction kind" Enums(like, love, laugh, wow, sad)
+// @success 200 {object} api.NoDataResponse "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /post/{id}/reactions/{kind} [put]
+// @security Bearer
+func (c *ReactionController) React(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.React(r.Context(), principal.UserID, id, mux.Vars(r)["kind"]); err != nil {
+		reactionErrorHandler(w, id, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, "Reaction added")
+}
+
+// Unreact Remove a reaction from a post
+// @summary Remove a reaction from a post
+// @description Remove a reaction from a post, removing a reaction that wasn't made is not an error
+// @tags Reaction
+// @id unreact-to-post
+// @produce json
+// @param id path int true "Post ID"
+// @param kind path string true "Reaction kind" Enums(like, love, laugh, wow, sad)
+// @success 200 {object} api.NoDataResponse "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /post/{id}/reactions/{kind} [delete]
+// @security Bearer
+func (c *ReactionController) Unreact(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.Unreact(r.Context(), principal.UserID, id, mux.Vars(r)["kind"]); err != nil {
+		reactionErrorHandler(w, id, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, "Reaction removed")
+}
+
+func reactionErrorHandler(w http.ResponseWriter, postId int, err error) {
+	var validationErr *services.ValidationError
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		api.RequestErrorHandler(w, fmt.Errorf("Post with id = %d doesn't exist", postId), http.StatusNotFound)
+	} else if errors.As(err, &validationErr) {
+		api.ValidationErrorHandler(w, validationErr.Fields)
+	} else {
+		api.InternalErrorHandler(w, err)
+	}
+}
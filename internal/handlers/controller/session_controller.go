@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
+)
+
+type SessionController struct {
+	Service *services.SessionService
+}
+
+// Sessions List the active sessions
+// @summary List the active sessions
+// @description List the devices the authenticated user is logged in on, the session making the request is flagged as current
+// @tags Session
+// @id get-sessions
+// @produce json
+// @success 200 {object} api.GenericSuccessResponse[[]models.Session] "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/sessions [get]
+// @security Bearer
+func (c *SessionController) Sessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	sessions, err := c.Service.GetSessions(r.Context(), principal.UserID, principal.SessionID)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, sessions)
+}
+
+// RevokeSession Log out a session
+// @summary Log out a session
+// @description Revoke a session of the authenticated user, the tokens issued with it stop working
+// @tags Session
+// @id revoke-session
+// @produce json
+// @param id path int true "Session ID"
+// @success 200 {object} api.NoDataResponse "Session revoked"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/sessions/{id} [delete]
+// @security Bearer
+func (c *SessionController) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	var (
+		id, err = strconv.Atoi(mux.Vars(r)["id"])
+	)
+
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.RevokeSession(r.Context(), principal.UserID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			api.RequestErrorHandler(w, fmt.Errorf("Session with id = %d doesn't exist", id), http.StatusNotFound)
+			return
+		}
+
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Session with id %v successfully revoked", id))
+}
+
+// RevokeOtherSessions Log out every other session
+// @summary Log out every other session
+// @description Revoke every session of the authenticated user except the one making the request
+// @tags Session
+// @id revoke-other-sessions
+// @produce json
+// @success 200 {object} api.NoDataResponse "Sessions revoked"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/sessions [delete]
+// @security Bearer
+func (c *SessionController) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err := c.Service.RevokeOtherSessions(r.Context(), principal.UserID, principal.SessionID); err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, "Other sessions successfully revoked")
+}
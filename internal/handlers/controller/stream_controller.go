@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+)
+
+type StreamController struct {
+	Service   *services.StreamService
+	Heartbeat time.Duration
+}
+
+// Stream Real-time updates
+// @summary Real-time updates
+// @description Server-Sent Events stream of post.created, post.updated and post.deleted events, plus notification.created for the authenticated user. Reconnecting with the Last-Event-ID header replays the events missed in between, a stream.reset event is sent first instead when some of them can't be replayed anymore and the client has to refetch
+// @tags Stream
+// @id stream
+// @produce text/event-stream
+// @param Last-Event-ID header string false "ID of the last event received"
+// @success 200 {string} string "Event stream"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /stream [get]
+// @security Bearer
+func (c *StreamController) Stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		api.InternalErrorHandler(w, errors.New("streaming is not supported by the response writer"))
+		return
+	}
+
+	subscription, missed := c.Service.Subscribe(uint(viewerID(r)), r.Header.Get("Last-Event-ID"))
+	defer c.Service.Unsubscribe(subscription)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// Stops nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range missed {
+		writeStreamEvent(w, event)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(c.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-subscription.Events:
+			if !ok {
+				// Too far behind, the client reconnects and resumes from the buffer
+				return
+			}
+
+			writeStreamEvent(w, event)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event services.StreamEvent) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
@@ -4,11 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 
 	"github.com/gorilla/mux"
 	"github.com/simple-crud-go/api"
-	"github.com/simple-crud-go/internal/middleware"
 	"github.com/simple-crud-go/internal/services"
 	"gorm.io/gorm"
 )
@@ -30,6 +28,7 @@ type UserController struct {
 // @success 200 {object} api.NoDataResponse "Success"
 // @failure 404 {object} api.ErrorResponse "Not Found"
 // @failure 409 {object} api.ErrorResponse "Conflict"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
 // @failure 500 {object} api.ErrorResponse "Internal Server Error"
 // @router /user/{id} [put]
 // @security Bearer
@@ -38,8 +37,6 @@ func (c *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		username = r.FormValue("username")
 		name     = r.FormValue("name")
 		password = r.FormValue("password")
-		ctx      = r.Context()
-		authIdS  = ctx.Value(middleware.UserIdKey).(string)
 	)
 
 	if err := r.ParseForm(); err != nil {
@@ -47,15 +44,15 @@ func (c *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	authId, err := strconv.Atoi(authIdS)
-	if err != nil {
-		api.InternalErrorHandler(w, err)
+	principal, ok := authenticated(w, r)
+	if !ok {
 		return
 	}
 
-	if err := c.Service.UpdateUser(authId, username, name, password); err != nil {
+	if err := c.Service.UpdateUser(r.Context(), principal.UserID, username, name, password, clientInfo(r)); err != nil {
+		var validationErr *services.ValidationError
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			api.RequestErrorHandler(w, fmt.Errorf("User with id %d doesn't exist", authId), 404)
+			api.RequestErrorHandler(w, fmt.Errorf("User with id %d doesn't exist", principal.UserID), 404)
 			return
 		} else if errors.Is(err, services.ErrUserExist) {
 			api.RequestErrorHandler(w, err, http.StatusConflict)
@@ -63,13 +60,16 @@ func (c *UserController) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		} else if errors.Is(err, services.ErrMismatchID) {
 			api.RequestErrorHandler(w, err, http.StatusUnauthorized)
 			return
+		} else if errors.As(err, &validationErr) {
+			api.ValidationErrorHandler(w, validationErr.Fields)
+			return
 		} else {
 			api.InternalErrorHandler(w, err)
 			return
 		}
 	}
 
-	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("User with ID=%v successfully updated", authId))
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("User with ID=%v successfully updated", principal.UserID))
 }
 
 func (c *UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -84,10 +84,14 @@ func (c *UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := c.Service.CreateUser(username, name, password)
+	var validationErr *services.ValidationError
+	err := c.Service.CreateUser(r.Context(), username, name, password)
 	if err != nil && errors.Is(err, services.ErrUserExist) {
 		api.RequestErrorHandler(w, err, http.StatusConflict)
 		return
+	} else if errors.As(err, &validationErr) {
+		api.ValidationErrorHandler(w, validationErr.Fields)
+		return
 	} else if err != nil {
 		api.InternalErrorHandler(w, err)
 		return
@@ -106,7 +110,7 @@ func (c *UserController) CreateUser(w http.ResponseWriter, r *http.Request) {
 // @failure 500 {object} api.ErrorResponse "Internal Server Error"
 // @router /user [get]
 func (c *UserController) Users(w http.ResponseWriter, r *http.Request) {
-	user, err := c.Service.GetAllUser()
+	user, err := c.Service.GetAllUser(r.Context())
 	if err != nil {
 		api.InternalErrorHandler(w, err)
 		return
@@ -134,7 +138,7 @@ func (c *UserController) UserByUsername(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	user, err := c.Service.GetUserByUsername(username)
+	user, err := c.Service.GetUserByUsername(r.Context(), username)
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -161,22 +165,16 @@ func (c *UserController) UserByUsername(w http.ResponseWriter, r *http.Request)
 // @router /user [delete]
 // @security Bearer
 func (c *UserController) DeleteUserById(w http.ResponseWriter, r *http.Request) {
-	var (
-		ctx     = r.Context()
-		authIdS = ctx.Value(middleware.UserIdKey).(string)
-	)
-
-	authId, err := strconv.Atoi(authIdS)
-	if err != nil {
-		api.InternalErrorHandler(w, err)
+	principal, ok := authenticated(w, r)
+	if !ok {
 		return
 	}
 
-	err = c.Service.DeleteUserById(authId)
+	err := c.Service.DeleteUserById(r.Context(), principal.UserID, clientInfo(r))
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			api.RequestErrorHandler(w, fmt.Errorf("user with id = %d not found", authId), 404)
+			api.RequestErrorHandler(w, fmt.Errorf("user with id = %d not found", principal.UserID), 404)
 			return
 		}
 
@@ -0,0 +1,282 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
+)
+
+type WebhookController struct {
+	Service *services.WebhookService
+}
+
+// CreateWebhook Register a webhook
+// @summary Register a webhook
+// @description Register an endpoint the events involving the authenticated user are POSTed to. Payloads are signed with HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>" using the secret, which is only shown in this response
+// @tags Webhook
+// @id create-webhook
+// @accept mpfd
+// @produce json
+// @param url formData string true "Endpoint URL"
+// @param events formData string true "Space or comma separated events (post.created, post.updated, post.deleted, post.reacted, user.registered, user.followed, user.updated, user.deleted)"
+// @success 201 {object} api.GenericSuccessResponse[api.WebhookCreatedResponse] "Webhook registered"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/webhooks [post]
+// @security Bearer
+func (c *WebhookController) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	c.createWebhook(w, r, false)
+}
+
+// CreateGlobalWebhook Register a global webhook
+// @summary Register a global webhook
+// @description Register an endpoint the events of every user are POSTed to, it is managed like the other webhooks of the admin
+// @tags Admin
+// @id admin-create-webhook
+// @accept mpfd
+// @produce json
+// @param url formData string true "Endpoint URL"
+// @param events formData string true "Space or comma separated events"
+// @success 201 {object} api.GenericSuccessResponse[api.WebhookCreatedResponse] "Webhook registered"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 403 {object} api.ErrorResponse "Forbidden"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /admin/webhooks [post]
+// @security Bearer
+func (c *WebhookController) CreateGlobalWebhook(w http.ResponseWriter, r *http.Request) {
+	c.createWebhook(w, r, true)
+}
+
+func (c *WebhookController) createWebhook(w http.ResponseWriter, r *http.Request, global bool) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := c.Service.CreateWebhook(r.Context(), principal.UserID, r.FormValue("url"), webhookEvents(r.FormValue("events")), global)
+	if err != nil {
+		webhookErrorHandler(w, "Webhook", 0, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusCreated, data)
+}
+
+// Webhooks List the webhooks
+// @summary List the webhooks
+// @description List the webhooks of the authenticated user, including the disabled ones
+// @tags Webhook
+// @id get-webhooks
+// @produce json
+// @success 200 {object} api.GenericSuccessResponse[[]models.Webhook] "Success"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/webhooks [get]
+// @security Bearer
+func (c *WebhookController) Webhooks(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	webhooks, err := c.Service.GetWebhooks(r.Context(), principal.UserID)
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, webhooks)
+}
+
+// UpdateWebhook Update a webhook
+// @summary Update a webhook
+// @description Change the URL or the events of a webhook, or enable/disable it. Enabling a webhook resumes its pending deliveries
+// @tags Webhook
+// @id update-webhook
+// @accept mpfd
+// @produce json
+// @param id path int true "Webhook ID"
+// @param url formData string false "Endpoint URL"
+// @param events formData string false "Space or comma separated events"
+// @param enabled formData bool false "Enable or disable the webhook"
+// @success 200 {object} api.NoDataResponse "Webhook updated"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/webhooks/{id} [put]
+// @security Bearer
+func (c *WebhookController) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	var enabled *bool
+	if value := r.FormValue("enabled"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			api.RequestErrorHandler(w, errors.New("enabled must be true or false"), http.StatusBadRequest)
+			return
+		}
+
+		enabled = &parsed
+	}
+
+	if err = c.Service.UpdateWebhook(r.Context(), principal.UserID, id, r.FormValue("url"), webhookEvents(r.FormValue("events")), enabled); err != nil {
+		webhookErrorHandler(w, "Webhook", id, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Webhook with id %v successfully updated", id))
+}
+
+// DeleteWebhook Delete a webhook
+// @summary Delete a webhook
+// @description Delete a webhook along with its delivery log
+// @tags Webhook
+// @id delete-webhook
+// @produce json
+// @param id path int true "Webhook ID"
+// @success 200 {object} api.NoDataResponse "Webhook deleted"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/webhooks/{id} [delete]
+// @security Bearer
+func (c *WebhookController) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	if err = c.Service.DeleteWebhook(r.Context(), principal.UserID, id); err != nil {
+		webhookErrorHandler(w, "Webhook", id, err)
+		return
+	}
+
+	api.NoDataResponseHandler(w, http.StatusOK, fmt.Sprintf("Webhook with id %v successfully deleted", id))
+}
+
+// Deliveries List the deliveries of a webhook
+// @summary List the deliveries of a webhook
+// @description The delivery log of a webhook, newest first, with the response status of the last attempt. Pass the id of the last delivery as before_id to get the next page
+// @tags Webhook
+// @id get-webhook-deliveries
+// @produce json
+// @param id path int true "Webhook ID"
+// @param before_id query int false "Deliveries older than this delivery"
+// @param limit query int false "Page size, 20 by default and 100 at most"
+// @success 200 {object} api.GenericSuccessResponse[[]models.WebhookDelivery] "Success"
+// @failure 400 {object} api.ErrorResponse "Bad Request"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/webhooks/{id}/deliveries [get]
+// @security Bearer
+func (c *WebhookController) Deliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	beforeId, limit, ok := pagination(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := c.Service.GetDeliveries(r.Context(), principal.UserID, id, beforeId, limit)
+	if err != nil {
+		webhookErrorHandler(w, "Webhook", id, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusOK, deliveries)
+}
+
+// Redeliver Redeliver a webhook delivery
+// @summary Redeliver a webhook delivery
+// @description Queue the payload of a past delivery again, it is sent as a new delivery
+// @tags Webhook
+// @id redeliver-webhook-delivery
+// @produce json
+// @param id path int true "Webhook ID"
+// @param delivery_id path int true "Delivery ID"
+// @success 202 {object} api.GenericSuccessResponse[models.WebhookDelivery] "Delivery queued"
+// @failure 401 {object} api.ErrorResponse "Unauthorized"
+// @failure 404 {object} api.ErrorResponse "Not Found"
+// @failure 422 {object} api.ValidationErrorResponse "Unprocessable Entity"
+// @failure 500 {object} api.ErrorResponse "Internal Server Error"
+// @router /me/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+// @security Bearer
+func (c *WebhookController) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	deliveryId, err := strconv.Atoi(mux.Vars(r)["delivery_id"])
+	if err != nil {
+		api.InternalErrorHandler(w, err)
+		return
+	}
+
+	principal, ok := authenticated(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := c.Service.Redeliver(r.Context(), principal.UserID, id, deliveryId)
+	if err != nil {
+		webhookErrorHandler(w, "Webhook or delivery", id, err)
+		return
+	}
+
+	api.GenericResponseHandler(w, http.StatusAccepted, delivery)
+}
+
+func webhookErrorHandler(w http.ResponseWriter, resource string, id int, err error) {
+	var validationErr *services.ValidationError
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		api.RequestErrorHandler(w, fmt.Errorf("%s with id = %d doesn't exist", resource, id), http.StatusNotFound)
+	} else if errors.As(err, &validationErr) {
+		api.ValidationErrorHandler(w, validationErr.Fields)
+	} else {
+		api.InternalErrorHandler(w, err)
+	}
+}
+
+// webhookEvents splits the space or comma separated events.
+func webhookEvents(raw string) []string {
+	return strings.FieldsFunc(raw, func(r rune) bool { return r == ' ' || r == ',' })
+}
@@ -1,40 +1,112 @@
 package helper
 
 import (
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/simple-crud-go/internal/configs"
 )
 
+// defaultTokenTTL lifetime of the tokens issued when logging in.
+const defaultTokenTTL = time.Hour * (7 * 24)
+
 //go:generate mockgen -destination=./mocks/jwt.go -source=./jwt.go
 type JWTHelper interface {
 	CreateToken(id int) (string, error)
+	CreateTokenWithOptions(id int, options TokenOptions) (string, error)
 	CheckToken(token string) error
-	ExtractAudienceToken(token string) (string, error)
+	ExtractSubjectToken(token string) (string, error)
+	ParseClaims(token string) (*TokenClaims, error)
+}
+
+// TokenOptions customise the token created by CreateTokenWithOptions, zero values keep the defaults.
+type TokenOptions struct {
+	Scopes    []string
+	ClientID  string
+	TokenID   string
+	SessionID string
+	TTL       time.Duration
+}
+
+// TokenClaims the claims of a valid token, UserID is read from the `sub` claim.
+type TokenClaims struct {
+	UserID    string
+	Scopes    []string
+	ClientID  string
+	TokenID   string
+	SessionID string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// Legacy tokens were issued before `sub`, `iss` and `aud` were used, the user id was stored in `aud`.
+	Legacy bool
 }
 
 type jwtHelper struct {
 	Manager JWTManager
+
+	issuer             string
+	audience           string
+	acceptLegacyTokens bool
 }
 
 func NewJWTHelper(jwtManager JWTManager) JWTHelper {
 	return jwtHelper{
-		Manager: jwtManager,
+		Manager:            jwtManager,
+		issuer:             configs.GetJWTIssuer(),
+		audience:           configs.GetJWTAudience(),
+		acceptLegacyTokens: configs.GetJWTAcceptLegacyTokens(),
 	}
 }
 
 func NewDefaultJWTHelper() JWTHelper {
-	return jwtHelper{
-		Manager: NewDefaultJWTManager(),
-	}
+	return NewJWTHelper(NewDefaultJWTManager())
 }
 
 func (j jwtHelper) CreateToken(id int) (string, error) {
-	idString := strconv.Itoa(id)
+	return j.CreateTokenWithOptions(id, TokenOptions{})
+}
+
+func (j jwtHelper) CreateTokenWithOptions(id int, options TokenOptions) (string, error) {
+	ttl := options.TTL
+	if ttl == 0 {
+		ttl = defaultTokenTTL
+	}
+
+	tokenId := options.TokenID
+	if tokenId == "" {
+		var err error
+		if tokenId, err = RandomToken(18); err != nil {
+			return "", err
+		}
+	}
 
-	signedToken, err := j.Manager.SignToken(idString)
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": strconv.Itoa(id),
+		"iss": j.issuer,
+		"aud": j.audience,
+		"jti": tokenId,
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+
+	if len(options.Scopes) > 0 {
+		claims["scope"] = strings.Join(options.Scopes, " ")
+	}
+
+	if options.ClientID != "" {
+		claims["client_id"] = options.ClientID
+	}
+
+	if options.SessionID != "" {
+		claims["sid"] = options.SessionID
+	}
+
+	signedToken, err := j.Manager.SignToken(claims)
 	if err != nil {
 		return "", err
 	}
@@ -43,41 +115,90 @@ func (j jwtHelper) CreateToken(id int) (string, error) {
 }
 
 func (j jwtHelper) CheckToken(token string) error {
-	t, err := j.Manager.ParseToken(token)
+	_, err := j.ParseClaims(token)
+	return err
+}
 
+// ExtractSubjectToken returns the id of the user the token was issued to.
+func (j jwtHelper) ExtractSubjectToken(token string) (string, error) {
+	claims, err := j.ParseClaims(token)
 	if err != nil {
-		return err
-	}
-
-	if !t.Valid {
-		return jwt.ErrInvalidKey
+		return "", err
 	}
 
-	return nil
+	return claims.UserID, nil
 }
 
-func (j jwtHelper) ExtractAudienceToken(token string) (string, error) {
+func (j jwtHelper) ParseClaims(token string) (*TokenClaims, error) {
 	t, err := j.Manager.ParseToken(token)
-
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if !t.Valid {
-		return "", jwt.ErrInvalidKey
+		return nil, jwt.ErrInvalidKey
 	}
 
-	claims, err := t.Claims.GetAudience()
+	mapClaims, ok := t.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
 
-	if err != nil {
-		return "", err
+	var claims TokenClaims
+
+	if sub, _ := mapClaims.GetSubject(); sub != "" {
+		if iss, _ := mapClaims.GetIssuer(); iss != j.issuer {
+			return nil, jwt.ErrTokenInvalidIssuer
+		}
+
+		if aud, _ := mapClaims.GetAudience(); !slices.Contains(aud, j.audience) {
+			return nil, jwt.ErrTokenInvalidAudience
+		}
+
+		claims.UserID = sub
+	} else {
+		if !j.acceptLegacyTokens {
+			return nil, jwt.ErrTokenInvalidSubject
+		}
+
+		aud, err := mapClaims.GetAudience()
+		if err != nil || len(aud) == 0 {
+			return nil, jwt.ErrTokenInvalidAudience
+		}
+
+		claims.UserID = aud[0]
+		claims.Legacy = true
+	}
+
+	if scope, ok := mapClaims["scope"].(string); ok {
+		claims.Scopes = ParseScopes(scope)
+	}
+
+	if clientID, ok := mapClaims["client_id"].(string); ok {
+		claims.ClientID = clientID
+	}
+
+	if tokenID, ok := mapClaims["jti"].(string); ok {
+		claims.TokenID = tokenID
+	}
+
+	if sessionID, ok := mapClaims["sid"].(string); ok {
+		claims.SessionID = sessionID
+	}
+
+	if iat, err := mapClaims.GetIssuedAt(); err == nil && iat != nil {
+		claims.IssuedAt = iat.Time
+	}
+
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		claims.ExpiresAt = exp.Time
 	}
 
-	return claims[0], nil
+	return &claims, nil
 }
 
 type JWTManager interface {
-	SignToken(data string) (string, error)
+	SignToken(claims jwt.MapClaims) (string, error)
 	ParseToken(token string) (*jwt.Token, error)
 }
 
@@ -87,18 +208,20 @@ func NewDefaultJWTManager() DefaultJWTManager {
 	return DefaultJWTManager{}
 }
 
-func (m DefaultJWTManager) SignToken(data string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &jwt.MapClaims{
-		"aud": data,
-		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(time.Hour * (7 * 24)).Unix(),
-	})
+func (m DefaultJWTManager) SignToken(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	return token.SignedString([]byte(configs.GetJWTSecret()))
 }
 
+// ParseToken only accepts HS256 tokens, so a token can't pick a weaker algorithm (e.g. `none`) to get verified.
+// The expiry and not-before claims are checked with some leeway for the clock skew between servers.
 func (m DefaultJWTManager) ParseToken(token string) (*jwt.Token, error) {
 	return jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
 		return []byte(configs.GetJWTSecret()), nil
-	})
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithLeeway(configs.GetJWTLeeway()),
+		jwt.WithExpirationRequired(),
+	)
 }
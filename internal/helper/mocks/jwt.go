@@ -13,6 +13,7 @@ import (
 	reflect "reflect"
 
 	jwt "github.com/golang-jwt/jwt/v5"
+	helper "github.com/simple-crud-go/internal/helper"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -68,19 +69,49 @@ func (mr *MockJWTHelperMockRecorder) CreateToken(id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockJWTHelper)(nil).CreateToken), id)
 }
 
-// ExtractAudienceToken mocks base method.
-func (m *MockJWTHelper) ExtractAudienceToken(token string) (string, error) {
+// CreateTokenWithOptions mocks base method.
+func (m *MockJWTHelper) CreateTokenWithOptions(id int, options helper.TokenOptions) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ExtractAudienceToken", token)
+	ret := m.ctrl.Call(m, "CreateTokenWithOptions", id, options)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ExtractAudienceToken indicates an expected call of ExtractAudienceToken.
-func (mr *MockJWTHelperMockRecorder) ExtractAudienceToken(token any) *gomock.Call {
+// CreateTokenWithOptions indicates an expected call of CreateTokenWithOptions.
+func (mr *MockJWTHelperMockRecorder) CreateTokenWithOptions(id, options any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractAudienceToken", reflect.TypeOf((*MockJWTHelper)(nil).ExtractAudienceToken), token)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTokenWithOptions", reflect.TypeOf((*MockJWTHelper)(nil).CreateTokenWithOptions), id, options)
+}
+
+// ExtractSubjectToken mocks base method.
+func (m *MockJWTHelper) ExtractSubjectToken(token string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtractSubjectToken", token)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExtractSubjectToken indicates an expected call of ExtractSubjectToken.
+func (mr *MockJWTHelperMockRecorder) ExtractSubjectToken(token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractSubjectToken", reflect.TypeOf((*MockJWTHelper)(nil).ExtractSubjectToken), token)
+}
+
+// ParseClaims mocks base method.
+func (m *MockJWTHelper) ParseClaims(token string) (*helper.TokenClaims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseClaims", token)
+	ret0, _ := ret[0].(*helper.TokenClaims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseClaims indicates an expected call of ParseClaims.
+func (mr *MockJWTHelperMockRecorder) ParseClaims(token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseClaims", reflect.TypeOf((*MockJWTHelper)(nil).ParseClaims), token)
 }
 
 // MockJWTManager is a mock of JWTManager interface.
@@ -122,16 +153,16 @@ func (mr *MockJWTManagerMockRecorder) ParseToken(token any) *gomock.Call {
 }
 
 // SignToken mocks base method.
-func (m *MockJWTManager) SignToken(data string) (string, error) {
+func (m *MockJWTManager) SignToken(claims jwt.MapClaims) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SignToken", data)
+	ret := m.ctrl.Call(m, "SignToken", claims)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // SignToken indicates an expected call of SignToken.
-func (mr *MockJWTManagerMockRecorder) SignToken(data any) *gomock.Call {
+func (mr *MockJWTManagerMockRecorder) SignToken(claims any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignToken", reflect.TypeOf((*MockJWTManager)(nil).SignToken), data)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SignToken", reflect.TypeOf((*MockJWTManager)(nil).SignToken), claims)
 }
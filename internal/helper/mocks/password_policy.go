@@ -0,0 +1,92 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./password_policy.go
+//
+// Generated by this command:
+//
+//	mockgen -destination=./mocks/password_policy.go -source=./password_policy.go
+//
+
+// Package mock_helper is a generated GoMock package.
+package mock_helper
+
+import (
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPasswordPolicy is a mock of PasswordPolicy interface.
+type MockPasswordPolicy struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordPolicyMockRecorder
+}
+
+// MockPasswordPolicyMockRecorder is the mock recorder for MockPasswordPolicy.
+type MockPasswordPolicyMockRecorder struct {
+	mock *MockPasswordPolicy
+}
+
+// NewMockPasswordPolicy creates a new mock instance.
+func NewMockPasswordPolicy(ctrl *gomock.Controller) *MockPasswordPolicy {
+	mock := &MockPasswordPolicy{ctrl: ctrl}
+	mock.recorder = &MockPasswordPolicyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordPolicy) EXPECT() *MockPasswordPolicyMockRecorder {
+	return m.recorder
+}
+
+// Validate mocks base method.
+func (m *MockPasswordPolicy) Validate(password, username, name string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Validate", password, username, name)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Validate indicates an expected call of Validate.
+func (mr *MockPasswordPolicyMockRecorder) Validate(password, username, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockPasswordPolicy)(nil).Validate), password, username, name)
+}
+
+// MockBreachedPasswordChecker is a mock of BreachedPasswordChecker interface.
+type MockBreachedPasswordChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockBreachedPasswordCheckerMockRecorder
+}
+
+// MockBreachedPasswordCheckerMockRecorder is the mock recorder for MockBreachedPasswordChecker.
+type MockBreachedPasswordCheckerMockRecorder struct {
+	mock *MockBreachedPasswordChecker
+}
+
+// NewMockBreachedPasswordChecker creates a new mock instance.
+func NewMockBreachedPasswordChecker(ctrl *gomock.Controller) *MockBreachedPasswordChecker {
+	mock := &MockBreachedPasswordChecker{ctrl: ctrl}
+	mock.recorder = &MockBreachedPasswordCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBreachedPasswordChecker) EXPECT() *MockBreachedPasswordCheckerMockRecorder {
+	return m.recorder
+}
+
+// IsBreached mocks base method.
+func (m *MockBreachedPasswordChecker) IsBreached(password string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsBreached", password)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsBreached indicates an expected call of IsBreached.
+func (mr *MockBreachedPasswordCheckerMockRecorder) IsBreached(password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsBreached", reflect.TypeOf((*MockBreachedPasswordChecker)(nil).IsBreached), password)
+}
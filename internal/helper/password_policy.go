@@ -0,0 +1,208 @@
+package helper
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/simple-crud-go/internal/configs"
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed data/breached_passwords.txt
+var bundledBreachedPasswords []byte
+
+// hashPrefixLength the number of hex characters of the SHA-1 hash used as the lookup bucket,
+// the same split the k-anonymity range API of Have I Been Pwned uses.
+const hashPrefixLength = 5
+
+//go:generate mockgen -destination=./mocks/password_policy.go -source=./password_policy.go
+type PasswordPolicy interface {
+	// Validate returns every rule the password violates, an empty slice means the password is acceptable.
+	Validate(password string, username string, name string) ([]string, error)
+}
+
+type BreachedPasswordChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+type DefaultPasswordPolicy struct {
+	MinLength            int
+	RequireUpper         bool
+	RequireLower         bool
+	RequireDigit         bool
+	RequireSymbol        bool
+	DisallowPersonalInfo bool
+	Breached             BreachedPasswordChecker
+}
+
+func NewDefaultPasswordPolicy() DefaultPasswordPolicy {
+	return DefaultPasswordPolicy{
+		MinLength:            configs.GetPasswordMinLength(),
+		RequireUpper:         configs.GetPasswordRequireUpper(),
+		RequireLower:         configs.GetPasswordRequireLower(),
+		RequireDigit:         configs.GetPasswordRequireDigit(),
+		RequireSymbol:        configs.GetPasswordRequireSymbol(),
+		DisallowPersonalInfo: configs.GetPasswordDisallowPersonalInfo(),
+		Breached:             NewDefaultBreachedPasswordChecker(),
+	}
+}
+
+func (p DefaultPasswordPolicy) Validate(password string, username string, name string) ([]string, error) {
+	violations := []string{}
+
+	if len([]rune(password)) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c) || unicode.IsSpace(c):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, "must contain an uppercase letter")
+	}
+
+	if p.RequireLower && !hasLower {
+		violations = append(violations, "must contain a lowercase letter")
+	}
+
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, "must contain a digit")
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, "must contain a symbol")
+	}
+
+	if p.DisallowPersonalInfo && containsPersonalInfo(password, username, name) {
+		violations = append(violations, "must not contain your username or name")
+	}
+
+	if p.Breached != nil {
+		breached, err := p.Breached.IsBreached(password)
+		if err != nil {
+			return nil, err
+		}
+
+		if breached {
+			violations = append(violations, "is too common or has appeared in a data breach")
+		}
+	}
+
+	return violations, nil
+}
+
+// containsPersonalInfo reports whether the password contains the username or any word of the name,
+// parts shorter than 3 characters are ignored because they match too many passwords by accident.
+func containsPersonalInfo(password string, username string, name string) bool {
+	lowered := strings.ToLower(password)
+	parts := append([]string{username}, strings.Fields(name)...)
+
+	for _, part := range parts {
+		part = strings.ToLower(part)
+		if len(part) >= 3 && strings.Contains(lowered, part) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HashPrefixBreachedChecker looks passwords up by SHA-1 hash, bucketed by the first 5 hex characters
+// of the hash so a range file (or a remote range API) only ever has to be queried by prefix.
+type HashPrefixBreachedChecker struct {
+	ranges map[string]map[string]struct{}
+}
+
+// NewHashPrefixBreachedChecker reads one uppercase or lowercase SHA-1 hex hash per line, optionally
+// followed by ":<count>". Empty lines and lines starting with "#" are skipped.
+func NewHashPrefixBreachedChecker(r io.Reader) (*HashPrefixBreachedChecker, error) {
+	checker := &HashPrefixBreachedChecker{ranges: map[string]map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("invalid SHA-1 hash in breached password list: %q", hash)
+		}
+
+		prefix, suffix := hash[:hashPrefixLength], hash[hashPrefixLength:]
+		if _, ok := checker.ranges[prefix]; !ok {
+			checker.ranges[prefix] = map[string]struct{}{}
+		}
+		checker.ranges[prefix][suffix] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return checker, nil
+}
+
+// NewDefaultBreachedPasswordChecker loads the list configured with PASSWORD_BREACHED_FILE,
+// falling back to the bundled list when it is not set or cannot be read.
+func NewDefaultBreachedPasswordChecker() *HashPrefixBreachedChecker {
+	if path := configs.GetPasswordBreachedFile(); path != "" {
+		checker, err := loadBreachedPasswordFile(path)
+		if err == nil {
+			return checker
+		}
+
+		logrus.Error(fmt.Sprintf("failed to load breached password file '%v', using the bundled list instead, error: %v", path, err))
+	}
+
+	checker, err := NewHashPrefixBreachedChecker(bytes.NewReader(bundledBreachedPasswords))
+	if err != nil {
+		panic(err.Error())
+	}
+
+	return checker
+}
+
+func loadBreachedPasswordFile(path string) (*HashPrefixBreachedChecker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return NewHashPrefixBreachedChecker(f)
+}
+
+func (c *HashPrefixBreachedChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes, ok := c.ranges[hash[:hashPrefixLength]]
+	if !ok {
+		return false, nil
+	}
+
+	_, ok = suffixes[hash[hashPrefixLength:]]
+	return ok, nil
+}
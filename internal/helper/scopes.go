@@ -0,0 +1,89 @@
+package helper
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ScopePostsRead  = "posts:read"
+	ScopePostsWrite = "posts:write"
+	// ScopeUsersRead reading the private data of the account, like its notifications.
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+	// ScopeCredentials managing the credentials of the account (API keys, tokens, sessions, OAuth apps and consents,
+	// linked accounts) and its webhooks, which receive everything the account does. It only comes with a login, it
+	// can't be delegated to API keys, tokens or OAuth clients.
+	ScopeCredentials = "credentials"
+	// ScopeAdmin only comes with the admin role, it can't be delegated to API keys, tokens or OAuth clients.
+	ScopeAdmin = "admin"
+)
+
+// KnownScopes every scope a token, an API key or an OAuth client may be granted.
+var KnownScopes = []string{ScopePostsRead, ScopePostsWrite, ScopeUsersRead, ScopeUsersWrite}
+
+// DefaultScopes the scopes of a regular user, also given to tokens and API keys issued without scopes.
+var DefaultScopes = KnownScopes
+
+// ParseScopes splits a space or comma separated scope list, dropping empty and duplicated entries.
+func ParseScopes(raw string) []string {
+	scopes := []string{}
+	seen := map[string]bool{}
+
+	for _, scope := range strings.FieldsFunc(raw, func(r rune) bool { return r == ' ' || r == ',' }) {
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes
+}
+
+// ValidateScopes returns an error naming the first scope that isn't one of KnownScopes.
+func ValidateScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if scope == ScopeCredentials || scope == ScopeAdmin {
+			return fmt.Errorf("scope '%v' can't be delegated", scope)
+		}
+
+		if !containsScope(KnownScopes, scope) {
+			return fmt.Errorf("unknown scope '%v'", scope)
+		}
+	}
+
+	return nil
+}
+
+// DelegableScopes the scopes of `granted` that can be handed to an API key or a token, in the order of KnownScopes.
+func DelegableScopes(granted []string) []string {
+	scopes := []string{}
+	for _, scope := range KnownScopes {
+		if containsScope(granted, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	return scopes
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsScopes reports whether every scope of `required` is part of `granted`.
+func ContainsScopes(granted []string, required []string) bool {
+	for _, scope := range required {
+		if !containsScope(granted, scope) {
+			return false
+		}
+	}
+
+	return true
+}
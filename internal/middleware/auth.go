@@ -4,51 +4,222 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/simple-crud-go/api"
 	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/services"
+	"gorm.io/gorm"
 )
 
-type CtxKey uint
+// APIKeyHeader alternative to `Authorization: ApiKey <key>` for clients that can't set the Authorization header.
+const APIKeyHeader = "X-API-Key"
 
-var UserIdKey CtxKey = 0
+var (
+	errMissingAuthentication = errors.New("Missing authentication")
+	errInvalidToken          = errors.New("Invalid token")
+)
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		jwtHelper := helper.NewDefaultJWTHelper()
+// UserLoader loads the user a token or an API key was issued to.
+type UserLoader interface {
+	GetAuthUser(ctx context.Context, id int) (*models.User, error)
+}
 
-		token := r.Header.Get("Authorization")
-		if token == "" {
-			api.RequestErrorHandler(w, errors.New("Missing authentication"), http.StatusUnauthorized)
-			return
-		}
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, plainKey string) (*models.APIKey, error)
+}
+
+// TokenRevocationChecker knows about the tokens issued to OAuth clients, which can be revoked before they expire.
+type TokenRevocationChecker interface {
+	IsTokenRevoked(ctx context.Context, tokenId string) (bool, error)
+}
 
-		if !strings.Contains(token, "Bearer") {
-			api.RequestErrorHandler(w, errors.New("Missing authentication"), http.StatusUnauthorized)
+// SessionChecker knows about the login sessions, the tokens issued at login stop working once their session is revoked.
+type SessionChecker interface {
+	IsSessionActive(ctx context.Context, sessionId string) (bool, error)
+}
+
+type Auth struct {
+	JWTHelper helper.JWTHelper
+	Users     UserLoader
+	APIKeys   APIKeyAuthenticator
+	Tokens    TokenRevocationChecker
+	Sessions  SessionChecker
+}
+
+func NewAuth(jwtHelper helper.JWTHelper, users UserLoader, apiKeys APIKeyAuthenticator, tokens TokenRevocationChecker, sessions SessionChecker) *Auth {
+	return &Auth{
+		JWTHelper: jwtHelper,
+		Users:     users,
+		APIKeys:   apiKeys,
+		Tokens:    tokens,
+		Sessions:  sessions,
+	}
+}
+
+// AuthMiddleware accepts either a JWT (`Authorization: Bearer <token>`) or a personal API key
+// (`Authorization: ApiKey <key>` or the `X-API-Key` header) and stores the Principal in the request context.
+func (a *Auth) AuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.authenticate(r)
+		if err != nil {
+			authErrorHandler(w, err)
 			return
 		}
 
-		token = token[len("bearer:"):]
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
 
-		if err := jwtHelper.CheckToken(token); err != nil {
-			if !errors.Is(err, jwt.ErrInvalidKey) {
-				api.InternalErrorHandler(w, err)
+// OptionalAuthMiddleware lets anonymous requests through, so public endpoints can personalise their response
+// when the caller is authenticated. Invalid credentials are still rejected.
+func (a *Auth) OptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.authenticate(r)
+		if err != nil {
+			if errors.Is(err, errMissingAuthentication) {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			api.RequestErrorHandler(w, errors.New("Invalid token"), http.StatusUnauthorized)
+			authErrorHandler(w, err)
 			return
 		}
 
-		aud, err := jwtHelper.ExtractAudienceToken(token)
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func authErrorHandler(w http.ResponseWriter, err error) {
+	if errors.Is(err, errMissingAuthentication) || errors.Is(err, errInvalidToken) || errors.Is(err, services.ErrInvalidAPIKey) {
+		api.RequestErrorHandler(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	api.InternalErrorHandler(w, err)
+}
+
+func (a *Auth) authenticate(r *http.Request) (*Principal, error) {
+	scheme, credentials, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	credentials = strings.TrimSpace(credentials)
+
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		scheme, credentials = "ApiKey", key
+	}
+
+	if credentials == "" {
+		return nil, errMissingAuthentication
+	}
+
+	var (
+		principal *Principal
+		err       error
+	)
+
+	switch strings.ToLower(scheme) {
+	case "bearer":
+		principal, err = a.authenticateToken(r.Context(), credentials)
+	case "apikey":
+		principal, err = a.authenticateAPIKey(r.Context(), credentials)
+	default:
+		return nil, errMissingAuthentication
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens issued before scopes existed and API keys created without scopes act as a regular user
+	if len(principal.Scopes) == 0 {
+		principal.Scopes = helper.DefaultScopes
+	}
+
+	if a.Users == nil {
+		return nil, errInvalidToken
+	}
+
+	user, err := a.Users.GetAuthUser(r.Context(), principal.UserID)
+	if err != nil {
+		// The user was deleted after the credentials were issued
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errInvalidToken
+		}
+
+		return nil, err
+	}
+
+	principal.Username = user.Username
+	principal.Roles = []string{user.Role}
+
+	return principal, nil
+}
+
+func (a *Auth) authenticateToken(ctx context.Context, token string) (*Principal, error) {
+	claims, err := a.JWTHelper.ParseClaims(token)
+	if err != nil {
+		// Every parsing error (malformed, expired, bad signature...) means the token can't be trusted
+		return nil, errInvalidToken
+	}
+
+	userId, err := strconv.Atoi(claims.UserID)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	if claims.ClientID != "" {
+		if claims.TokenID == "" || a.Tokens == nil {
+			return nil, errInvalidToken
+		}
+
+		revoked, err := a.Tokens.IsTokenRevoked(ctx, claims.TokenID)
 		if err != nil {
-			api.InternalErrorHandler(w, err)
+			return nil, err
 		}
 
-		r = r.WithContext(context.WithValue(r.Context(), UserIdKey, aud))
+		if revoked {
+			return nil, errInvalidToken
+		}
+	}
 
-		next.ServeHTTP(w, r)
-	})
+	// Tokens issued before sessions existed don't have a session id, they stay valid until they expire
+	if claims.SessionID != "" {
+		if a.Sessions == nil {
+			return nil, errInvalidToken
+		}
+
+		active, err := a.Sessions.IsSessionActive(ctx, claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+
+		if !active {
+			return nil, errInvalidToken
+		}
+	}
+
+	return &Principal{
+		UserID:    userId,
+		Scopes:    claims.Scopes,
+		TokenID:   claims.TokenID,
+		SessionID: claims.SessionID,
+	}, nil
+}
+
+func (a *Auth) authenticateAPIKey(ctx context.Context, plainKey string) (*Principal, error) {
+	if a.APIKeys == nil {
+		return nil, services.ErrInvalidAPIKey
+	}
+
+	key, err := a.APIKeys.Authenticate(ctx, plainKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{
+		UserID:  int(key.UserID),
+		Scopes:  key.Scopes,
+		TokenID: key.Prefix,
+	}, nil
 }
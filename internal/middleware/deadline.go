@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// RequestDeadline cancels the context of the request, and with it its database queries, once `timeout` passed.
+// `routes` overrides the timeout of some routes, keyed by method and path template ("GET /api/post/{id}"),
+// 0 meaning no deadline. It has to be used on the router so the matched route is known.
+func RequestDeadline(timeout time.Duration, routes map[string]time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := timeout
+			if route := mux.CurrentRoute(r); route != nil {
+				if template, err := route.GetPathTemplate(); err == nil {
+					if routeTimeout, ok := routes[r.Method+" "+template]; ok {
+						deadline = routeTimeout
+					}
+				}
+			}
+
+			if deadline <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), deadline)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
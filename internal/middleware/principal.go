@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/simple-crud-go/internal/helper"
+)
+
+type CtxKey uint
+
+var PrincipalKey CtxKey = 0
+
+// Principal the authenticated caller of a request.
+type Principal struct {
+	UserID   int
+	Username string
+	Roles    []string
+	Scopes   []string
+	// TokenID the `jti` of the token, or the prefix of the API key, the request was authenticated with.
+	TokenID string
+	// SessionID is empty unless the request was authenticated with a token issued at login.
+	SessionID string
+}
+
+func (p *Principal) HasScopes(scopes ...string) bool {
+	return helper.ContainsScopes(p.Scopes, scopes)
+}
+
+// PrincipalFrom returns the caller stored by AuthMiddleware or OptionalAuthMiddleware, ok is false for anonymous requests.
+func PrincipalFrom(ctx context.Context) (principal *Principal, ok bool) {
+	principal, ok = ctx.Value(PrincipalKey).(*Principal)
+	return principal, ok && principal != nil
+}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, PrincipalKey, principal)
+}
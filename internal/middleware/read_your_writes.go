@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/simple-crud-go/internal/database"
+)
+
+// ReadYourWrites keeps the lookups of a request on the primary once it wrote something, a replica may not have
+// caught up with the write yet.
+func ReadYourWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(database.TrackWrites(r.Context())))
+	})
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/simple-crud-go/api"
+)
+
+// RequireScopes rejects the request unless the caller was granted every scope of `scopes`,
+// it has to be wrapped by AuthMiddleware which stores the caller.
+func RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := PrincipalFrom(r.Context())
+
+			if !ok || !principal.HasScopes(scopes...) {
+				api.RequestErrorHandler(w, fmt.Errorf("Insufficient scope, required: %v", strings.Join(scopes, " ")), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
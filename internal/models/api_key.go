@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+type APIKey struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"index" json:"-"`
+	User       *User      `json:"-"`
+	Label      string     `json:"label"`
+	Prefix     string     `gorm:"size:16;uniqueIndex" json:"prefix"`
+	KeyHash    string     `gorm:"size:64" json:"-"`
+	Scopes     []string   `gorm:"serializer:json" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	AuditLogin           = "auth.login"
+	AuditLoginFailed     = "auth.login_failed"
+	AuditRegister        = "auth.register"
+	AuditPasswordChange  = "user.password_change"
+	AuditUsernameChange  = "user.username_change"
+	AuditUserDelete      = "user.delete"
+	AuditPostCreate      = "post.create"
+	AuditPostUpdate      = "post.update"
+	AuditPostDelete      = "post.delete"
+	AuditAdminRoleChange = "admin.role_change"
+	AuditAdminPostDelete = "admin.post_delete"
+)
+
+const (
+	AuditTargetUser = "user"
+	AuditTargetPost = "post"
+)
+
+// AuditEvent an entry of the audit log, entries are only ever inserted.
+// Before and After summarise the target, secrets such as passwords are never recorded.
+type AuditEvent struct {
+	ID         uint              `gorm:"primarykey" json:"id"`
+	Action     string            `gorm:"size:64;index" json:"action"`
+	ActorID    *uint             `gorm:"index" json:"actor_id"`
+	TargetType string            `gorm:"size:32;index:idx_audit_events_target" json:"target_type"`
+	TargetID   string            `gorm:"size:64;index:idx_audit_events_target" json:"target_id"`
+	IP         string            `gorm:"size:45" json:"ip"`
+	UserAgent  string            `json:"user_agent"`
+	Before     map[string]string `gorm:"serializer:json" json:"before,omitempty"`
+	After      map[string]string `gorm:"serializer:json" json:"after,omitempty"`
+	CreatedAt  time.Time         `gorm:"index" json:"created_at"`
+}
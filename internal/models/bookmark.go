@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// Bookmark a post saved by a user to read later, optionally filed in a named collection.
+type Bookmark struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_bookmarks_user_post,priority:1;index:idx_bookmarks_user_collection,priority:1" json:"-"`
+	PostID     uint      `gorm:"not null;uniqueIndex:idx_bookmarks_user_post,priority:2;index" json:"-"`
+	Post       *Post     `json:"post,omitempty"`
+	Collection string    `gorm:"size:64;not null;default:'';index:idx_bookmarks_user_collection,priority:2" json:"collection"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Follow `FollowerID` follows `FolloweeID`. The unique index starts with the follower so the home feed
+// finds the followed authors with an index range scan, the followee index serves the follower lists.
+type Follow struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	FollowerID uint      `gorm:"not null;uniqueIndex:idx_follows_follower_followee,priority:1" json:"-"`
+	Follower   *User     `gorm:"constraint:OnDelete:CASCADE" json:"follower,omitempty"`
+	FolloweeID uint      `gorm:"not null;uniqueIndex:idx_follows_follower_followee,priority:2;index" json:"-"`
+	Followee   *User     `gorm:"constraint:OnDelete:CASCADE" json:"followee,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// Identity links an account of an external OpenID Connect provider to a user.
+type Identity struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    uint      `gorm:"index" json:"-"`
+	User      *User     `json:"-"`
+	Provider  string    `gorm:"size:64;uniqueIndex:idx_identities_provider_subject" json:"provider"`
+	Subject   string    `gorm:"size:255;uniqueIndex:idx_identities_provider_subject" json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
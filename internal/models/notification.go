@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	NotificationFollow   = "follow"
+	NotificationReaction = "reaction"
+)
+
+// NotificationTypes every type of notification, each one can be turned off in the preferences.
+var NotificationTypes = []string{NotificationFollow, NotificationReaction}
+
+// Notification tells `UserID` that `Actor` did something involving them, e.g. reacted to one of their posts.
+type Notification struct {
+	ID        uint       `gorm:"primarykey;index:idx_notifications_user_id_id,priority:2" json:"id"`
+	UserID    uint       `gorm:"not null;index:idx_notifications_user_id_id,priority:1;index:idx_notifications_user_read,priority:1" json:"-"`
+	Type      string     `gorm:"size:32;not null" json:"type"`
+	ActorID   *uint      `json:"actor_id"`
+	Actor     *User      `json:"actor,omitempty"`
+	PostID    *uint      `json:"post_id,omitempty"`
+	ReadAt    *time.Time `gorm:"index:idx_notifications_user_read,priority:2" json:"read_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// NotificationPreference every type is enabled until the user stores a preference for it.
+type NotificationPreference struct {
+	UserID  uint   `gorm:"primarykey" json:"-"`
+	Type    string `gorm:"primarykey;size:32" json:"type"`
+	Enabled bool   `json:"enabled"`
+}
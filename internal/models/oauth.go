@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+)
+
+// OAuthClient a third-party application allowed to request tokens on behalf of our users.
+type OAuthClient struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	ClientID     string    `gorm:"size:64;uniqueIndex" json:"client_id"`
+	SecretHash   string    `gorm:"size:64" json:"-"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `gorm:"serializer:json" json:"redirect_uris"`
+	Scopes       []string  `gorm:"serializer:json" json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	UserID       uint      `gorm:"index" json:"-"`
+	User         *User     `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// OAuthConsent the scopes a user agreed to grant to a client.
+type OAuthConsent struct {
+	ID            uint         `gorm:"primarykey" json:"id"`
+	UserID        uint         `gorm:"uniqueIndex:idx_oauth_consents_user_client" json:"-"`
+	OAuthClientID uint         `gorm:"column:oauth_client_id;uniqueIndex:idx_oauth_consents_user_client" json:"-"`
+	OAuthClient   *OAuthClient `json:"client,omitempty"`
+	Scopes        []string     `gorm:"serializer:json" json:"scopes"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+type OAuthAuthorizationCode struct {
+	ID            uint   `gorm:"primarykey"`
+	CodeHash      string `gorm:"size:64;uniqueIndex"`
+	OAuthClientID uint   `gorm:"column:oauth_client_id"`
+	UserID        uint
+	RedirectURI   string
+	Scopes        []string `gorm:"serializer:json"`
+	CodeChallenge string
+	ExpiresAt     time.Time
+	UsedAt        *time.Time
+	CreatedAt     time.Time
+}
+
+// OAuthToken an access token issued to a client, the token itself is a JWT identified by its `jti` (TokenID).
+type OAuthToken struct {
+	ID            uint     `gorm:"primarykey"`
+	TokenID       string   `gorm:"size:64;uniqueIndex"`
+	OAuthClientID uint     `gorm:"index"`
+	UserID        uint     `gorm:"index"`
+	Scopes        []string `gorm:"serializer:json"`
+	ExpiresAt     time.Time
+	RevokedAt     *time.Time
+	CreatedAt     time.Time
+}
+
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+func (OAuthToken) TableName() string {
+	return "oauth_tokens"
+}
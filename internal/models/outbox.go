@@ -0,0 +1,46 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// OutboxEvent a domain event stored in the same transaction as the change it is about,
+// it stays pending until it is dispatched to the subscribers.
+type OutboxEvent struct {
+	ID      uint   `gorm:"primarykey;index:idx_outbox_events_pending,priority:2"`
+	Type    string `gorm:"size:64;not null"`
+	ActorID uint
+	UserID  uint
+	PostID  uint
+	Kind    string `gorm:"size:32"`
+	// Data the JSON encoded event, decoded into the type of its event when dispatched.
+	Data string `gorm:"type:text"`
+	// Attempts dispatches that failed, the event is given up after too many of them.
+	Attempts  int
+	LastError string `gorm:"size:512"`
+	// NextAttemptAt when the event is dispatched again after a failed dispatch.
+	NextAttemptAt *time.Time
+	DispatchedAt  *time.Time `gorm:"index:idx_outbox_events_pending,priority:1"`
+	// FailedAt when the event was given up after too many failed dispatches, it isn't dispatched anymore.
+	FailedAt  *time.Time
+	CreatedAt time.Time
+}
+
+// Aggregate the post the event is about, or else its user. The events of an aggregate are dispatched in the order they
+// were recorded, one failing holds back the following ones but not the events of the other aggregates.
+func (e OutboxEvent) Aggregate() string {
+	if e.PostID != 0 {
+		return fmt.Sprintf("post:%d", e.PostID)
+	}
+
+	return fmt.Sprintf("user:%d", e.UserID)
+}
+
+// OutboxReceipt a subscriber received the event, a dispatch that failed for other subscribers doesn't publish it to
+// this one again. The receipts of an event are removed once it is dispatched.
+type OutboxReceipt struct {
+	EventID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	Subscriber string `gorm:"primaryKey;size:64"`
+	CreatedAt  time.Time
+}
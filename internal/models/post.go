@@ -7,12 +7,18 @@ import (
 )
 
 type Post struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
+	ID        uint           `gorm:"primarykey;index:idx_posts_user_id_id,priority:2" json:"id"`
 	Title     string         `json:"title"`
 	Body      string         `json:"body"`
-	UserID    uint           `json:"-"`
+	UserID    uint           `gorm:"index:idx_posts_user_id_id,priority:1" json:"-"`
 	User      *User          `json:"author,omitempty"`
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	// Editable whether the authenticated caller is the author, always false for anonymous requests.
+	Editable bool `gorm:"-" json:"editable"`
+	// Reactions number of reactions of each kind.
+	Reactions map[string]int64 `gorm:"-" json:"reactions"`
+	// ViewerReaction kinds the authenticated caller reacted with, left out when there are none or the request is anonymous.
+	ViewerReaction []string `gorm:"-" json:"viewer_reaction,omitempty"`
 }
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ReactionLike  = "like"
+	ReactionLove  = "love"
+	ReactionLaugh = "laugh"
+	ReactionWow   = "wow"
+	ReactionSad   = "sad"
+)
+
+// ReactionKinds every reaction a post can get.
+var ReactionKinds = []string{ReactionLike, ReactionLove, ReactionLaugh, ReactionWow, ReactionSad}
+
+// Reaction a user can react to a post once per kind, the unique index starts with the post
+// so counting the reactions of a page of posts is an index range scan.
+type Reaction struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	PostID    uint      `gorm:"not null;uniqueIndex:idx_reactions_post_user_kind,priority:1" json:"post_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_reactions_post_user_kind,priority:2;index" json:"user_id"`
+	Kind      string    `gorm:"size:16;not null;uniqueIndex:idx_reactions_post_user_kind,priority:3" json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Session a login of the user, the tokens issued at login carry the session id and stop working once it is revoked.
+type Session struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	UserID     uint       `gorm:"index" json:"-"`
+	User       *User      `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `gorm:"size:45" json:"ip"`
+	Scopes     []string   `gorm:"serializer:json" json:"scopes"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Current    bool       `gorm:"-" json:"current"`
+}
@@ -6,13 +6,22 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type User struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Name      string         `json:"name"`
-	Username  string         `json:"username"`
-	Password  string         `json:"-"`
-	Posts     *[]Post        `json:"posts,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+	ID       uint    `gorm:"primarykey" json:"id"`
+	Name     string  `json:"name"`
+	Username string  `json:"username"`
+	Password string  `json:"-"`
+	Role     string  `gorm:"size:20;default:user" json:"role"`
+	Posts    *[]Post `json:"posts,omitempty"`
+	// FollowersCount and FollowingCount are only filled on the user profile.
+	FollowersCount *int64         `gorm:"-" json:"followers_count,omitempty"`
+	FollowingCount *int64         `gorm:"-" json:"following_count,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 }
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// Webhook an endpoint the events of the user are sent to, `Events` filters which ones.
+type Webhook struct {
+	ID     uint   `gorm:"primarykey" json:"id"`
+	UserID uint   `gorm:"index" json:"-"`
+	URL    string `gorm:"size:2048;not null" json:"url"`
+	// Secret signs the payloads, it is kept in plain text since the signature is computed from it.
+	Secret string   `gorm:"size:64" json:"-"`
+	Events []string `gorm:"serializer:json" json:"events"`
+	// Global the webhook receives the events of every user, only admins can register one.
+	Global bool `json:"global"`
+	// ConsecutiveFailures failed attempts since the last successful one, the webhook is disabled once it gets too high.
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// WebhookDelivery an event queued for a webhook, along with the outcome of the last attempt to send it.
+type WebhookDelivery struct {
+	ID        uint     `gorm:"primarykey;index:idx_webhook_deliveries_webhook_id_id,priority:2" json:"id"`
+	WebhookID uint     `gorm:"not null;index:idx_webhook_deliveries_webhook_id_id,priority:1" json:"webhook_id"`
+	Webhook   *Webhook `json:"-"`
+	Event     string   `gorm:"size:64;not null" json:"event"`
+	Payload   string   `gorm:"type:text" json:"payload"`
+	Status    string   `gorm:"size:16;not null;index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts  int      `json:"attempts"`
+	// NextAttemptAt when the delivery is retried, only set while it is pending.
+	NextAttemptAt  *time.Time `gorm:"index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	ResponseStatus *int       `json:"response_status"`
+	Error          string     `gorm:"size:512" json:"error,omitempty"`
+	// RedeliveryOf the delivery that was manually redelivered.
+	RedeliveryOf *uint     `json:"redelivery_of,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepo interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	Update(ctx context.Context, key *models.APIKey) error
+	GetById(ctx context.Context, id uint) (*models.APIKey, error)
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	GetAllByUserId(ctx context.Context, userId uint) ([]models.APIKey, error)
+	TouchLastUsed(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *gormAPIKeyRepository {
+	return &gormAPIKeyRepository{
+		db: db,
+	}
+}
+
+type gormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *gormAPIKeyRepository) Update(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Save(key).Error
+}
+
+func (r *gormAPIKeyRepository) GetById(ctx context.Context, id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.WithContext(ctx).First(&key, id).Error
+	return &key, err
+}
+
+func (r *gormAPIKeyRepository) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.WithContext(ctx).Where("prefix = ?", prefix).First(&key).Error
+	return &key, err
+}
+
+func (r *gormAPIKeyRepository) GetAllByUserId(ctx context.Context, userId uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("id").Find(&keys).Error
+	return keys, err
+}
+
+// TouchLastUsed only updates `last_used_at` so `updated_at` keeps tracking changes made by the owner.
+func (r *gormAPIKeyRepository) TouchLastUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
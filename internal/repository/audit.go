@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+)
+
+// AuditFilter narrows an audit log search, zero values don't filter.
+type AuditFilter struct {
+	Action     string
+	ActorID    uint
+	TargetType string
+	TargetID   string
+	Since      *time.Time
+	Until      *time.Time
+	// BeforeID returns the events older than this one, to page through the results.
+	BeforeID uint
+	Limit    int
+}
+
+// AuditRepo the audit log is append-only, there is no way to update or delete an event.
+type AuditRepo interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	Find(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error)
+}
+
+func NewAuditRepository(db *gorm.DB) *gormAuditRepository {
+	return &gormAuditRepository{
+		db: db,
+	}
+}
+
+type gormAuditRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormAuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// Find returns the matching events, newest first.
+func (r *gormAuditRepository) Find(ctx context.Context, filter AuditFilter) ([]models.AuditEvent, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditEvent{})
+
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+
+	var events []models.AuditEvent
+	err := query.Order("id DESC").Limit(filter.Limit).Find(&events).Error
+	return events, err
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BookmarkRepo interface {
+	// Save bookmarking a post again moves it to the collection of `bookmark`.
+	Save(ctx context.Context, bookmark *models.Bookmark) error
+	Delete(ctx context.Context, userId uint, postId uint) error
+	// GetByUserId newest first, `collection` is ignored when nil. Paginated with `beforeId`, the id of the
+	// last bookmark of the previous page, 0 for the first page.
+	GetByUserId(ctx context.Context, userId uint, collection *string, beforeId uint, limit int) ([]models.Bookmark, error)
+	DeleteByPostId(ctx context.Context, postId uint) error
+}
+
+func NewBookmarkRepository(db *gorm.DB) *gormBookmarkRepository {
+	return &gormBookmarkRepository{
+		db: db,
+	}
+}
+
+type gormBookmarkRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormBookmarkRepository) Save(ctx context.Context, bookmark *models.Bookmark) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "post_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"collection"}),
+	}).Create(bookmark).Error
+}
+
+func (r *gormBookmarkRepository) Delete(ctx context.Context, userId uint, postId uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND post_id = ?", userId, postId).Delete(&models.Bookmark{}).Error
+}
+
+func (r *gormBookmarkRepository) GetByUserId(ctx context.Context, userId uint, collection *string, beforeId uint, limit int) ([]models.Bookmark, error) {
+	query := r.db.WithContext(ctx).InnerJoins("Post").Preload("Post.User").Where("bookmarks.user_id = ?", userId)
+
+	if collection != nil {
+		query = query.Where("bookmarks.collection = ?", *collection)
+	}
+
+	if beforeId != 0 {
+		query = query.Where("bookmarks.id < ?", beforeId)
+	}
+
+	var bookmarks []models.Bookmark
+	err := query.Order("bookmarks.id DESC").Limit(limit).Find(&bookmarks).Error
+	return bookmarks, err
+}
+
+func (r *gormBookmarkRepository) DeleteByPostId(ctx context.Context, postId uint) error {
+	return r.db.WithContext(ctx).Where("post_id = ?", postId).Delete(&models.Bookmark{}).Error
+}
@@ -0,0 +1,466 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/simple-crud-go/internal/cache"
+	"github.com/simple-crud-go/internal/database"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+)
+
+// RepositoryCache shared by the cached repositories and the caching transactor. The entities are cached for `ttl`, the
+// lookups that found nothing for `negativeTTL`.
+type RepositoryCache struct {
+	cache       cache.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	// group runs a single load per key, the concurrent misses of the key wait for it
+	group singleflight.Group
+
+	mu sync.Mutex
+	// generations of the keys being loaded, bumped when they are invalidated so a load that read the entity before
+	// doesn't cache it. Only the keys with a load in flight are kept.
+	generations map[string]*generation
+	// invalidations counts every invalidation, for the entries cached along with the one being loaded
+	invalidations atomic.Uint64
+}
+
+type generation struct {
+	value uint64
+	loads int
+}
+
+func NewRepositoryCache(cache cache.Cache, ttl time.Duration, negativeTTL time.Duration) *RepositoryCache {
+	return &RepositoryCache{
+		cache:       cache,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		generations: map[string]*generation{},
+	}
+}
+
+func userKey(id uint) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+func authUserKey(id uint) string {
+	return fmt.Sprintf("user:auth:%d", id)
+}
+
+func usernameKey(username string) string {
+	return "user:username:" + strings.ToLower(username)
+}
+
+func postKey(id uint) string {
+	return fmt.Sprintf("post:%d", id)
+}
+
+// get decodes the value cached under `key` into `dest`, loading it with `load` when it isn't cached. An empty value
+// is a lookup that found nothing.
+func (c *RepositoryCache) get(ctx context.Context, key string, dest any, load func(ctx context.Context) (any, error)) error {
+	data, ok, err := c.cache.Get(ctx, key)
+	if err != nil {
+		// The cache being down only makes the lookups slower
+		logrus.WithField("key", key).Warn(err)
+	}
+
+	if !ok {
+		if data, err = c.load(ctx, key, load); err != nil {
+			return err
+		}
+	}
+
+	if len(data) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(dest)
+}
+
+func (c *RepositoryCache) load(ctx context.Context, key string, load func(ctx context.Context) (any, error)) ([]byte, error) {
+	fill := func() (any, error) {
+		generation := c.startLoad(key)
+		defer c.endLoad(key)
+
+		// A lagging replica could still return what was just invalidated, it would be cached for the whole TTL
+		value, err := load(database.ReadFromPrimary(ctx))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.setLoaded(ctx, key, generation, []byte{}, c.negativeTTL)
+			return []byte{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := encode(value)
+		if err != nil {
+			return nil, err
+		}
+
+		c.setLoaded(ctx, key, generation, data, c.ttl)
+		return data, nil
+	}
+
+	// Every caller gets the encoded value and decodes its own copy, they may change it
+	data, err, _ := c.group.Do(key, fill)
+	if err != nil && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		// The request the load ran for went away, this one is still waiting for the value
+		data, err = fill()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return data.([]byte), nil
+}
+
+func (c *RepositoryCache) startLoad(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, ok := c.generations[key]
+	if !ok {
+		g = &generation{}
+		c.generations[key] = g
+	}
+
+	g.loads++
+	return g.value
+}
+
+func (c *RepositoryCache) endLoad(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if g := c.generations[key]; g != nil {
+		if g.loads--; g.loads == 0 {
+			delete(c.generations, key)
+		}
+	}
+}
+
+func (c *RepositoryCache) loadedAt(key string, value uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	g, ok := c.generations[key]
+	return ok && g.value == value
+}
+
+// setLoaded caches what a load of the key read at `generation`, unless the key was invalidated since.
+func (c *RepositoryCache) setLoaded(ctx context.Context, key string, generation uint64, data []byte, ttl time.Duration) {
+	if !c.loadedAt(key, generation) {
+		return
+	}
+
+	c.set(ctx, key, data, ttl)
+
+	// The invalidation may have deleted the key right before it was set
+	if !c.loadedAt(key, generation) {
+		c.invalidate(ctx, key)
+	}
+}
+
+// put caches `value` under `key` without waiting for a lookup to miss it, unless anything was invalidated since
+// `invalidations`, what was read may be stale already.
+func (c *RepositoryCache) put(ctx context.Context, key string, value any, invalidations uint64) {
+	if c.invalidations.Load() != invalidations {
+		return
+	}
+
+	data, err := encode(value)
+	if err != nil {
+		logrus.WithField("key", key).Error(err)
+		return
+	}
+
+	c.set(ctx, key, data, c.ttl)
+
+	if c.invalidations.Load() != invalidations {
+		c.invalidate(ctx, key)
+	}
+}
+
+func (c *RepositoryCache) set(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	if err := c.cache.Set(ctx, key, data, ttl); err != nil {
+		logrus.WithField("key", key).Warn(err)
+	}
+}
+
+func (c *RepositoryCache) invalidate(ctx context.Context, keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	// Bumped before the entries are deleted, a load caching what it read afterwards deletes it again
+	c.invalidations.Add(1)
+	c.mu.Lock()
+	for _, key := range keys {
+		if g, ok := c.generations[key]; ok {
+			g.value++
+		}
+	}
+	c.mu.Unlock()
+
+	// The write already happened, the entries have to go even when the client went away
+	if err := c.cache.Delete(context.WithoutCancel(ctx), keys...); err != nil {
+		logrus.WithField("keys", keys).Error(err)
+	}
+}
+
+func encode(value any) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(value); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// invalidator drops the cached entries right away, or once the transaction commits when `pending` is set.
+type invalidator struct {
+	cache   *RepositoryCache
+	pending *[]string
+}
+
+func (i invalidator) invalidate(ctx context.Context, keys ...string) {
+	if i.pending != nil {
+		*i.pending = append(*i.pending, keys...)
+		return
+	}
+
+	i.cache.invalidate(ctx, keys...)
+}
+
+// cachedUserRepository reads the users by id and username from the cache first. The lookup by username only caches
+// the id of the user, so a change of the user only has to invalidate the user under its id.
+type cachedUserRepository struct {
+	UserRepo
+	invalidator
+}
+
+// NewCachedUserRepository caches the lookups by id and username of `repo`, the listing isn't cached.
+func NewCachedUserRepository(repo UserRepo, cache *RepositoryCache) *cachedUserRepository {
+	return &cachedUserRepository{
+		UserRepo:    repo,
+		invalidator: invalidator{cache: cache},
+	}
+}
+
+func (r *cachedUserRepository) GetById(ctx context.Context, id uint) (*models.User, error) {
+	// A transaction reads what it wrote itself
+	if r.pending != nil {
+		return r.UserRepo.GetById(ctx, id)
+	}
+
+	var user models.User
+	err := r.cache.get(ctx, userKey(id), &user, func(ctx context.Context) (any, error) {
+		return r.UserRepo.GetById(ctx, id)
+	})
+	if err != nil {
+		return &models.User{}, err
+	}
+
+	// The user is looked up with its posts, gob leaves the empty ones out
+	if user.Posts == nil {
+		user.Posts = &[]models.Post{}
+	}
+
+	return &user, nil
+}
+
+func (r *cachedUserRepository) GetAuthUser(ctx context.Context, id uint) (*models.User, error) {
+	if r.pending != nil {
+		return r.UserRepo.GetAuthUser(ctx, id)
+	}
+
+	var user models.User
+	err := r.cache.get(ctx, authUserKey(id), &user, func(ctx context.Context) (any, error) {
+		return r.UserRepo.GetAuthUser(ctx, id)
+	})
+	if err != nil {
+		return &models.User{}, err
+	}
+
+	return &user, nil
+}
+
+func (r *cachedUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	if r.pending != nil {
+		return r.UserRepo.GetByUsername(ctx, username)
+	}
+
+	var id uint
+	err := r.cache.get(ctx, usernameKey(username), &id, func(ctx context.Context) (any, error) {
+		invalidations := r.cache.invalidations.Load()
+		user, err := r.UserRepo.GetByUsername(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+
+		r.cache.put(ctx, userKey(user.ID), user, invalidations)
+		return user.ID, nil
+	})
+	if err != nil {
+		return &models.User{}, err
+	}
+
+	user, err := r.GetById(ctx, id)
+	if err != nil || !strings.EqualFold(user.Username, username) {
+		// The user was renamed or deleted since
+		r.cache.invalidate(ctx, usernameKey(username))
+		return r.UserRepo.GetByUsername(ctx, username)
+	}
+
+	return user, nil
+}
+
+func (r *cachedUserRepository) Create(ctx context.Context, user *models.User) error {
+	if err := r.UserRepo.Create(ctx, user); err != nil {
+		return err
+	}
+
+	// A lookup may have found nothing before
+	r.invalidate(ctx, userKey(user.ID), authUserKey(user.ID), usernameKey(user.Username))
+	return nil
+}
+
+func (r *cachedUserRepository) Update(ctx context.Context, user models.User, columns ...string) error {
+	if err := r.UserRepo.Update(ctx, user, columns...); err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, userKey(user.ID), authUserKey(user.ID), usernameKey(user.Username))
+	return nil
+}
+
+func (r *cachedUserRepository) DeleteById(ctx context.Context, id uint) error {
+	if err := r.UserRepo.DeleteById(ctx, id); err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, userKey(id), authUserKey(id))
+	return nil
+}
+
+// cachedPostRepository reads the posts by id from the cache first. The author is cached with its posts, so the
+// changes of a post invalidate its author as well.
+type cachedPostRepository struct {
+	PostRepo
+	invalidator
+}
+
+// NewCachedPostRepository caches the lookups by id of `repo`, the listings and the feed aren't cached.
+func NewCachedPostRepository(repo PostRepo, cache *RepositoryCache) *cachedPostRepository {
+	return &cachedPostRepository{
+		PostRepo:    repo,
+		invalidator: invalidator{cache: cache},
+	}
+}
+
+func (r *cachedPostRepository) GetById(ctx context.Context, id int) (*models.Post, error) {
+	if r.pending != nil {
+		return r.PostRepo.GetById(ctx, id)
+	}
+
+	var post models.Post
+	err := r.cache.get(ctx, postKey(uint(id)), &post, func(ctx context.Context) (any, error) {
+		return r.PostRepo.GetById(ctx, id)
+	})
+	if err != nil {
+		return &models.Post{}, err
+	}
+
+	return &post, nil
+}
+
+func (r *cachedPostRepository) Create(ctx context.Context, post *models.Post) error {
+	if err := r.PostRepo.Create(ctx, post); err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, postKey(post.ID), userKey(post.UserID))
+	return nil
+}
+
+func (r *cachedPostRepository) Update(ctx context.Context, post *models.Post, columns ...string) error {
+	if err := r.PostRepo.Update(ctx, post, columns...); err != nil {
+		return err
+	}
+
+	r.invalidate(ctx, postKey(post.ID), userKey(post.UserID))
+	return nil
+}
+
+func (r *cachedPostRepository) Delete(ctx context.Context, id uint) error {
+	// The author has to be known before the post is gone
+	post, err := r.PostRepo.GetById(ctx, int(id))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if err = r.PostRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	keys := []string{postKey(id)}
+	if post != nil && post.UserID != 0 {
+		keys = append(keys, userKey(post.UserID))
+	}
+
+	r.invalidate(ctx, keys...)
+	return nil
+}
+
+// cachingTransactor invalidates the cached users and posts written in a transaction once it is committed, the lookups
+// done in the transaction skip the cache.
+type cachingTransactor struct {
+	Transactor
+	cache *RepositoryCache
+	// pending the keys of the outermost transaction when nested, only it invalidates them
+	pending *[]string
+}
+
+func NewCachingTransactor(transactor Transactor, cache *RepositoryCache) *cachingTransactor {
+	return &cachingTransactor{
+		Transactor: transactor,
+		cache:      cache,
+	}
+}
+
+func (t *cachingTransactor) RunInTx(ctx context.Context, fn func(repos Repositories) error) error {
+	pending := t.pending
+	if pending == nil {
+		pending = &[]string{}
+	}
+
+	err := t.Transactor.RunInTx(ctx, func(repos Repositories) error {
+		repos.Users = &cachedUserRepository{UserRepo: repos.Users, invalidator: invalidator{cache: t.cache, pending: pending}}
+		repos.Posts = &cachedPostRepository{PostRepo: repos.Posts, invalidator: invalidator{cache: t.cache, pending: pending}}
+		repos.Tx = &cachingTransactor{Transactor: repos.Tx, cache: t.cache, pending: pending}
+
+		return fn(repos)
+	})
+
+	// Nothing changed when it was rolled back, dropping the entries anyway is harmless
+	if t.pending == nil {
+		t.cache.invalidate(ctx, *pending...)
+	}
+
+	return err
+}
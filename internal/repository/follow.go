@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// FollowRepo the lists are paginated with `beforeId`, the id of the last follow of the previous page, 0 for the first page.
+type FollowRepo interface {
+	// Create following someone already followed is a no-op, `created` reports whether the follow is new.
+	Create(ctx context.Context, follow *models.Follow) (created bool, err error)
+	Delete(ctx context.Context, followerId uint, followeeId uint) error
+	GetFollowers(ctx context.Context, userId uint, beforeId uint, limit int) ([]models.Follow, error)
+	GetFollowing(ctx context.Context, userId uint, beforeId uint, limit int) ([]models.Follow, error)
+	CountFollowers(ctx context.Context, userId uint) (int64, error)
+	CountFollowing(ctx context.Context, userId uint) (int64, error)
+}
+
+func NewFollowRepository(db *gorm.DB) *gormFollowRepository {
+	return &gormFollowRepository{
+		db: db,
+	}
+}
+
+type gormFollowRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormFollowRepository) Create(ctx context.Context, follow *models.Follow) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(follow)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormFollowRepository) Delete(ctx context.Context, followerId uint, followeeId uint) error {
+	return r.db.WithContext(ctx).Where("follower_id = ? AND followee_id = ?", followerId, followeeId).Delete(&models.Follow{}).Error
+}
+
+// GetFollowers newest first, deleted users are left out.
+func (r *gormFollowRepository) GetFollowers(ctx context.Context, userId uint, beforeId uint, limit int) ([]models.Follow, error) {
+	var follows []models.Follow
+	err := r.page(r.db.WithContext(ctx).InnerJoins("Follower").Where("follows.followee_id = ?", userId), beforeId, limit).Find(&follows).Error
+	return follows, err
+}
+
+// GetFollowing newest first, deleted users are left out.
+func (r *gormFollowRepository) GetFollowing(ctx context.Context, userId uint, beforeId uint, limit int) ([]models.Follow, error) {
+	var follows []models.Follow
+	err := r.page(r.db.WithContext(ctx).InnerJoins("Followee").Where("follows.follower_id = ?", userId), beforeId, limit).Find(&follows).Error
+	return follows, err
+}
+
+func (r *gormFollowRepository) CountFollowers(ctx context.Context, userId uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Follow{}).InnerJoins("Follower").Where("follows.followee_id = ?", userId).Count(&count).Error
+	return count, err
+}
+
+func (r *gormFollowRepository) CountFollowing(ctx context.Context, userId uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Follow{}).InnerJoins("Followee").Where("follows.follower_id = ?", userId).Count(&count).Error
+	return count, err
+}
+
+func (r *gormFollowRepository) page(query *gorm.DB, beforeId uint, limit int) *gorm.DB {
+	if beforeId != 0 {
+		query = query.Where("follows.id < ?", beforeId)
+	}
+
+	return query.Order("follows.id DESC").Limit(limit)
+}
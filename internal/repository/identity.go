@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+)
+
+type IdentityRepo interface {
+	Create(ctx context.Context, identity *models.Identity) error
+	GetByProviderSubject(ctx context.Context, provider string, subject string) (*models.Identity, error)
+	GetAllByUserId(ctx context.Context, userId uint) ([]models.Identity, error)
+}
+
+func NewIdentityRepository(db *gorm.DB) *gormIdentityRepository {
+	return &gormIdentityRepository{
+		db: db,
+	}
+}
+
+type gormIdentityRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormIdentityRepository) Create(ctx context.Context, identity *models.Identity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *gormIdentityRepository) GetByProviderSubject(ctx context.Context, provider string, subject string) (*models.Identity, error) {
+	var identity models.Identity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	return &identity, err
+}
+
+func (r *gormIdentityRepository) GetAllByUserId(ctx context.Context, userId uint) ([]models.Identity, error) {
+	var identities []models.Identity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("id").Find(&identities).Error
+	return identities, err
+}
@@ -0,0 +1,98 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/bookmark.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/bookmark.go -destination=./internal/repository/mocks/bookmark.go
+//
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/simple-crud-go/internal/models"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockBookmarkRepo is a mock of BookmarkRepo interface.
+type MockBookmarkRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockBookmarkRepoMockRecorder
+}
+
+// MockBookmarkRepoMockRecorder is the mock recorder for MockBookmarkRepo.
+type MockBookmarkRepoMockRecorder struct {
+	mock *MockBookmarkRepo
+}
+
+// NewMockBookmarkRepo creates a new mock instance.
+func NewMockBookmarkRepo(ctrl *gomock.Controller) *MockBookmarkRepo {
+	mock := &MockBookmarkRepo{ctrl: ctrl}
+	mock.recorder = &MockBookmarkRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBookmarkRepo) EXPECT() *MockBookmarkRepoMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockBookmarkRepo) Delete(ctx context.Context, userId, postId uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, userId, postId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockBookmarkRepoMockRecorder) Delete(ctx, userId, postId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockBookmarkRepo)(nil).Delete), ctx, userId, postId)
+}
+
+// DeleteByPostId mocks base method.
+func (m *MockBookmarkRepo) DeleteByPostId(ctx context.Context, postId uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteByPostId", ctx, postId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteByPostId indicates an expected call of DeleteByPostId.
+func (mr *MockBookmarkRepoMockRecorder) DeleteByPostId(ctx, postId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteByPostId", reflect.TypeOf((*MockBookmarkRepo)(nil).DeleteByPostId), ctx, postId)
+}
+
+// GetByUserId mocks base method.
+func (m *MockBookmarkRepo) GetByUserId(ctx context.Context, userId uint, collection *string, beforeId uint, limit int) ([]models.Bookmark, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserId", ctx, userId, collection, beforeId, limit)
+	ret0, _ := ret[0].([]models.Bookmark)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserId indicates an expected call of GetByUserId.
+func (mr *MockBookmarkRepoMockRecorder) GetByUserId(ctx, userId, collection, beforeId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserId", reflect.TypeOf((*MockBookmarkRepo)(nil).GetByUserId), ctx, userId, collection, beforeId, limit)
+}
+
+// Save mocks base method.
+func (m *MockBookmarkRepo) Save(ctx context.Context, bookmark *models.Bookmark) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", ctx, bookmark)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockBookmarkRepoMockRecorder) Save(ctx, bookmark any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockBookmarkRepo)(nil).Save), ctx, bookmark)
+}
@@ -0,0 +1,130 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/follow.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/follow.go -destination=./internal/repository/mocks/follow.go
+//
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/simple-crud-go/internal/models"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockFollowRepo is a mock of FollowRepo interface.
+type MockFollowRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockFollowRepoMockRecorder
+}
+
+// MockFollowRepoMockRecorder is the mock recorder for MockFollowRepo.
+type MockFollowRepoMockRecorder struct {
+	mock *MockFollowRepo
+}
+
+// NewMockFollowRepo creates a new mock instance.
+func NewMockFollowRepo(ctrl *gomock.Controller) *MockFollowRepo {
+	mock := &MockFollowRepo{ctrl: ctrl}
+	mock.recorder = &MockFollowRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFollowRepo) EXPECT() *MockFollowRepoMockRecorder {
+	return m.recorder
+}
+
+// CountFollowers mocks base method.
+func (m *MockFollowRepo) CountFollowers(ctx context.Context, userId uint) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountFollowers", ctx, userId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountFollowers indicates an expected call of CountFollowers.
+func (mr *MockFollowRepoMockRecorder) CountFollowers(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountFollowers", reflect.TypeOf((*MockFollowRepo)(nil).CountFollowers), ctx, userId)
+}
+
+// CountFollowing mocks base method.
+func (m *MockFollowRepo) CountFollowing(ctx context.Context, userId uint) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountFollowing", ctx, userId)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountFollowing indicates an expected call of CountFollowing.
+func (mr *MockFollowRepoMockRecorder) CountFollowing(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountFollowing", reflect.TypeOf((*MockFollowRepo)(nil).CountFollowing), ctx, userId)
+}
+
+// Create mocks base method.
+func (m *MockFollowRepo) Create(ctx context.Context, follow *models.Follow) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, follow)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockFollowRepoMockRecorder) Create(ctx, follow any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockFollowRepo)(nil).Create), ctx, follow)
+}
+
+// Delete mocks base method.
+func (m *MockFollowRepo) Delete(ctx context.Context, followerId, followeeId uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, followerId, followeeId)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockFollowRepoMockRecorder) Delete(ctx, followerId, followeeId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockFollowRepo)(nil).Delete), ctx, followerId, followeeId)
+}
+
+// GetFollowers mocks base method.
+func (m *MockFollowRepo) GetFollowers(ctx context.Context, userId, beforeId uint, limit int) ([]models.Follow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowers", ctx, userId, beforeId, limit)
+	ret0, _ := ret[0].([]models.Follow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowers indicates an expected call of GetFollowers.
+func (mr *MockFollowRepoMockRecorder) GetFollowers(ctx, userId, beforeId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowers", reflect.TypeOf((*MockFollowRepo)(nil).GetFollowers), ctx, userId, beforeId, limit)
+}
+
+// GetFollowing mocks base method.
+func (m *MockFollowRepo) GetFollowing(ctx context.Context, userId, beforeId uint, limit int) ([]models.Follow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowing", ctx, userId, beforeId, limit)
+	ret0, _ := ret[0].([]models.Follow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowing indicates an expected call of GetFollowing.
+func (mr *MockFollowRepoMockRecorder) GetFollowing(ctx, userId, beforeId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowing", reflect.TypeOf((*MockFollowRepo)(nil).GetFollowing), ctx, userId, beforeId, limit)
+}
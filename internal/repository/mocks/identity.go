@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/identity.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/identity.go -destination=./internal/repository/mocks/identity.go
+//
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/simple-crud-go/internal/models"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIdentityRepo is a mock of IdentityRepo interface.
+type MockIdentityRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdentityRepoMockRecorder
+}
+
+// MockIdentityRepoMockRecorder is the mock recorder for MockIdentityRepo.
+type MockIdentityRepoMockRecorder struct {
+	mock *MockIdentityRepo
+}
+
+// NewMockIdentityRepo creates a new mock instance.
+func NewMockIdentityRepo(ctrl *gomock.Controller) *MockIdentityRepo {
+	mock := &MockIdentityRepo{ctrl: ctrl}
+	mock.recorder = &MockIdentityRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdentityRepo) EXPECT() *MockIdentityRepoMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockIdentityRepo) Create(ctx context.Context, identity *models.Identity) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, identity)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockIdentityRepoMockRecorder) Create(ctx, identity any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockIdentityRepo)(nil).Create), ctx, identity)
+}
+
+// GetAllByUserId mocks base method.
+func (m *MockIdentityRepo) GetAllByUserId(ctx context.Context, userId uint) ([]models.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllByUserId", ctx, userId)
+	ret0, _ := ret[0].([]models.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllByUserId indicates an expected call of GetAllByUserId.
+func (mr *MockIdentityRepoMockRecorder) GetAllByUserId(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllByUserId", reflect.TypeOf((*MockIdentityRepo)(nil).GetAllByUserId), ctx, userId)
+}
+
+// GetByProviderSubject mocks base method.
+func (m *MockIdentityRepo) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByProviderSubject", ctx, provider, subject)
+	ret0, _ := ret[0].(*models.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByProviderSubject indicates an expected call of GetByProviderSubject.
+func (mr *MockIdentityRepoMockRecorder) GetByProviderSubject(ctx, provider, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByProviderSubject", reflect.TypeOf((*MockIdentityRepo)(nil).GetByProviderSubject), ctx, provider, subject)
+}
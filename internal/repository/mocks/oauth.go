@@ -0,0 +1,259 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/oauth.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/oauth.go -destination=./internal/repository/mocks/oauth.go
+//
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	models "github.com/simple-crud-go/internal/models"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOAuthRepo is a mock of OAuthRepo interface.
+type MockOAuthRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockOAuthRepoMockRecorder
+}
+
+// MockOAuthRepoMockRecorder is the mock recorder for MockOAuthRepo.
+type MockOAuthRepoMockRecorder struct {
+	mock *MockOAuthRepo
+}
+
+// NewMockOAuthRepo creates a new mock instance.
+func NewMockOAuthRepo(ctrl *gomock.Controller) *MockOAuthRepo {
+	mock := &MockOAuthRepo{ctrl: ctrl}
+	mock.recorder = &MockOAuthRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOAuthRepo) EXPECT() *MockOAuthRepoMockRecorder {
+	return m.recorder
+}
+
+// CreateClient mocks base method.
+func (m *MockOAuthRepo) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateClient", ctx, client)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateClient indicates an expected call of CreateClient.
+func (mr *MockOAuthRepoMockRecorder) CreateClient(ctx, client any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateClient", reflect.TypeOf((*MockOAuthRepo)(nil).CreateClient), ctx, client)
+}
+
+// CreateCode mocks base method.
+func (m *MockOAuthRepo) CreateCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCode", ctx, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateCode indicates an expected call of CreateCode.
+func (mr *MockOAuthRepoMockRecorder) CreateCode(ctx, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCode", reflect.TypeOf((*MockOAuthRepo)(nil).CreateCode), ctx, code)
+}
+
+// CreateToken mocks base method.
+func (m *MockOAuthRepo) CreateToken(ctx context.Context, token *models.OAuthToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateToken", ctx, token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateToken indicates an expected call of CreateToken.
+func (mr *MockOAuthRepoMockRecorder) CreateToken(ctx, token any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateToken", reflect.TypeOf((*MockOAuthRepo)(nil).CreateToken), ctx, token)
+}
+
+// DeleteClient mocks base method.
+func (m *MockOAuthRepo) DeleteClient(ctx context.Context, id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteClient", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteClient indicates an expected call of DeleteClient.
+func (mr *MockOAuthRepoMockRecorder) DeleteClient(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteClient", reflect.TypeOf((*MockOAuthRepo)(nil).DeleteClient), ctx, id)
+}
+
+// DeleteConsent mocks base method.
+func (m *MockOAuthRepo) DeleteConsent(ctx context.Context, id uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteConsent", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteConsent indicates an expected call of DeleteConsent.
+func (mr *MockOAuthRepoMockRecorder) DeleteConsent(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteConsent", reflect.TypeOf((*MockOAuthRepo)(nil).DeleteConsent), ctx, id)
+}
+
+// GetClientByClientId mocks base method.
+func (m *MockOAuthRepo) GetClientByClientId(ctx context.Context, clientId string) (*models.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClientByClientId", ctx, clientId)
+	ret0, _ := ret[0].(*models.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClientByClientId indicates an expected call of GetClientByClientId.
+func (mr *MockOAuthRepoMockRecorder) GetClientByClientId(ctx, clientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClientByClientId", reflect.TypeOf((*MockOAuthRepo)(nil).GetClientByClientId), ctx, clientId)
+}
+
+// GetClientsByUserId mocks base method.
+func (m *MockOAuthRepo) GetClientsByUserId(ctx context.Context, userId uint) ([]models.OAuthClient, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClientsByUserId", ctx, userId)
+	ret0, _ := ret[0].([]models.OAuthClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClientsByUserId indicates an expected call of GetClientsByUserId.
+func (mr *MockOAuthRepoMockRecorder) GetClientsByUserId(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClientsByUserId", reflect.TypeOf((*MockOAuthRepo)(nil).GetClientsByUserId), ctx, userId)
+}
+
+// GetCodeByHash mocks base method.
+func (m *MockOAuthRepo) GetCodeByHash(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCodeByHash", ctx, codeHash)
+	ret0, _ := ret[0].(*models.OAuthAuthorizationCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCodeByHash indicates an expected call of GetCodeByHash.
+func (mr *MockOAuthRepoMockRecorder) GetCodeByHash(ctx, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCodeByHash", reflect.TypeOf((*MockOAuthRepo)(nil).GetCodeByHash), ctx, codeHash)
+}
+
+// GetConsent mocks base method.
+func (m *MockOAuthRepo) GetConsent(ctx context.Context, userId, oauthClientId uint) (*models.OAuthConsent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsent", ctx, userId, oauthClientId)
+	ret0, _ := ret[0].(*models.OAuthConsent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsent indicates an expected call of GetConsent.
+func (mr *MockOAuthRepoMockRecorder) GetConsent(ctx, userId, oauthClientId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsent", reflect.TypeOf((*MockOAuthRepo)(nil).GetConsent), ctx, userId, oauthClientId)
+}
+
+// GetConsentsByUserId mocks base method.
+func (m *MockOAuthRepo) GetConsentsByUserId(ctx context.Context, userId uint) ([]models.OAuthConsent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetConsentsByUserId", ctx, userId)
+	ret0, _ := ret[0].([]models.OAuthConsent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetConsentsByUserId indicates an expected call of GetConsentsByUserId.
+func (mr *MockOAuthRepoMockRecorder) GetConsentsByUserId(ctx, userId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetConsentsByUserId", reflect.TypeOf((*MockOAuthRepo)(nil).GetConsentsByUserId), ctx, userId)
+}
+
+// GetTokenByTokenId mocks base method.
+func (m *MockOAuthRepo) GetTokenByTokenId(ctx context.Context, tokenId string) (*models.OAuthToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTokenByTokenId", ctx, tokenId)
+	ret0, _ := ret[0].(*models.OAuthToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTokenByTokenId indicates an expected call of GetTokenByTokenId.
+func (mr *MockOAuthRepoMockRecorder) GetTokenByTokenId(ctx, tokenId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTokenByTokenId", reflect.TypeOf((*MockOAuthRepo)(nil).GetTokenByTokenId), ctx, tokenId)
+}
+
+// MarkCodeUsed mocks base method.
+func (m *MockOAuthRepo) MarkCodeUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkCodeUsed", ctx, id, usedAt)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkCodeUsed indicates an expected call of MarkCodeUsed.
+func (mr *MockOAuthRepoMockRecorder) MarkCodeUsed(ctx, id, usedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkCodeUsed", reflect.TypeOf((*MockOAuthRepo)(nil).MarkCodeUsed), ctx, id, usedAt)
+}
+
+// RevokeToken mocks base method.
+func (m *MockOAuthRepo) RevokeToken(ctx context.Context, tokenId string, revokedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeToken", ctx, tokenId, revokedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeToken indicates an expected call of RevokeToken.
+func (mr *MockOAuthRepoMockRecorder) RevokeToken(ctx, tokenId, revokedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeToken", reflect.TypeOf((*MockOAuthRepo)(nil).RevokeToken), ctx, tokenId, revokedAt)
+}
+
+// RevokeTokensByUserClient mocks base method.
+func (m *MockOAuthRepo) RevokeTokensByUserClient(ctx context.Context, userId, oauthClientId uint, revokedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeTokensByUserClient", ctx, userId, oauthClientId, revokedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeTokensByUserClient indicates an expected call of RevokeTokensByUserClient.
+func (mr *MockOAuthRepoMockRecorder) RevokeTokensByUserClient(ctx, userId, oauthClientId, revokedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeTokensByUserClient", reflect.TypeOf((*MockOAuthRepo)(nil).RevokeTokensByUserClient), ctx, userId, oauthClientId, revokedAt)
+}
+
+// SaveConsent mocks base method.
+func (m *MockOAuthRepo) SaveConsent(ctx context.Context, consent *models.OAuthConsent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveConsent", ctx, consent)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveConsent indicates an expected call of SaveConsent.
+func (mr *MockOAuthRepoMockRecorder) SaveConsent(ctx, consent any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveConsent", reflect.TypeOf((*MockOAuthRepo)(nil).SaveConsent), ctx, consent)
+}
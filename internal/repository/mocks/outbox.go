@@ -0,0 +1,170 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/outbox.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/outbox.go -destination=./internal/repository/mocks/outbox.go
+//
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	models "github.com/simple-crud-go/internal/models"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOutboxRepo is a mock of OutboxRepo interface.
+type MockOutboxRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockOutboxRepoMockRecorder
+}
+
+// MockOutboxRepoMockRecorder is the mock recorder for MockOutboxRepo.
+type MockOutboxRepoMockRecorder struct {
+	mock *MockOutboxRepo
+}
+
+// NewMockOutboxRepo creates a new mock instance.
+func NewMockOutboxRepo(ctrl *gomock.Controller) *MockOutboxRepo {
+	mock := &MockOutboxRepo{ctrl: ctrl}
+	mock.recorder = &MockOutboxRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOutboxRepo) EXPECT() *MockOutboxRepoMockRecorder {
+	return m.recorder
+}
+
+// Add mocks base method.
+func (m *MockOutboxRepo) Add(ctx context.Context, event *models.OutboxEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Add", ctx, event)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Add indicates an expected call of Add.
+func (mr *MockOutboxRepoMockRecorder) Add(ctx, event any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Add", reflect.TypeOf((*MockOutboxRepo)(nil).Add), ctx, event)
+}
+
+// AddReceipt mocks base method.
+func (m *MockOutboxRepo) AddReceipt(ctx context.Context, id uint, subscriber string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReceipt", ctx, id, subscriber)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddReceipt indicates an expected call of AddReceipt.
+func (mr *MockOutboxRepoMockRecorder) AddReceipt(ctx, id, subscriber any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReceipt", reflect.TypeOf((*MockOutboxRepo)(nil).AddReceipt), ctx, id, subscriber)
+}
+
+// ClaimPending mocks base method.
+func (m *MockOutboxRepo) ClaimPending(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimPending", ctx, now, leaseUntil, limit)
+	ret0, _ := ret[0].([]models.OutboxEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimPending indicates an expected call of ClaimPending.
+func (mr *MockOutboxRepoMockRecorder) ClaimPending(ctx, now, leaseUntil, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimPending", reflect.TypeOf((*MockOutboxRepo)(nil).ClaimPending), ctx, now, leaseUntil, limit)
+}
+
+// DeleteDispatchedBefore mocks base method.
+func (m *MockOutboxRepo) DeleteDispatchedBefore(ctx context.Context, before time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteDispatchedBefore", ctx, before)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteDispatchedBefore indicates an expected call of DeleteDispatchedBefore.
+func (mr *MockOutboxRepoMockRecorder) DeleteDispatchedBefore(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteDispatchedBefore", reflect.TypeOf((*MockOutboxRepo)(nil).DeleteDispatchedBefore), ctx, before)
+}
+
+// GetReceipts mocks base method.
+func (m *MockOutboxRepo) GetReceipts(ctx context.Context, id uint) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReceipts", ctx, id)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReceipts indicates an expected call of GetReceipts.
+func (mr *MockOutboxRepoMockRecorder) GetReceipts(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReceipts", reflect.TypeOf((*MockOutboxRepo)(nil).GetReceipts), ctx, id)
+}
+
+// MarkDead mocks base method.
+func (m *MockOutboxRepo) MarkDead(ctx context.Context, id uint, lastError string, failedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkDead", ctx, id, lastError, failedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkDead indicates an expected call of MarkDead.
+func (mr *MockOutboxRepoMockRecorder) MarkDead(ctx, id, lastError, failedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDead", reflect.TypeOf((*MockOutboxRepo)(nil).MarkDead), ctx, id, lastError, failedAt)
+}
+
+// MarkDispatched mocks base method.
+func (m *MockOutboxRepo) MarkDispatched(ctx context.Context, id uint, dispatchedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkDispatched", ctx, id, dispatchedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkDispatched indicates an expected call of MarkDispatched.
+func (mr *MockOutboxRepoMockRecorder) MarkDispatched(ctx, id, dispatchedAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkDispatched", reflect.TypeOf((*MockOutboxRepo)(nil).MarkDispatched), ctx, id, dispatchedAt)
+}
+
+// MarkFailed mocks base method.
+func (m *MockOutboxRepo) MarkFailed(ctx context.Context, id uint, lastError string, nextAttemptAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkFailed", ctx, id, lastError, nextAttemptAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkFailed indicates an expected call of MarkFailed.
+func (mr *MockOutboxRepoMockRecorder) MarkFailed(ctx, id, lastError, nextAttemptAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkFailed", reflect.TypeOf((*MockOutboxRepo)(nil).MarkFailed), ctx, id, lastError, nextAttemptAt)
+}
+
+// Release mocks base method.
+func (m *MockOutboxRepo) Release(ctx context.Context, ids []uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, ids)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockOutboxRepoMockRecorder) Release(ctx, ids any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockOutboxRepo)(nil).Release), ctx, ids)
+}
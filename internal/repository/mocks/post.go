@@ -10,6 +10,7 @@
 package mock_repository
 
 import (
+	context "context"
 	reflect "reflect"
 
 	models "github.com/simple-crud-go/internal/models"
@@ -40,73 +41,108 @@ func (m *MockPostRepo) EXPECT() *MockPostRepoMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockPostRepo) Create(post *models.Post) error {
+func (m *MockPostRepo) Create(ctx context.Context, post *models.Post) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", post)
+	ret := m.ctrl.Call(m, "Create", ctx, post)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockPostRepoMockRecorder) Create(post any) *gomock.Call {
+func (mr *MockPostRepoMockRecorder) Create(ctx, post any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPostRepo)(nil).Create), post)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockPostRepo)(nil).Create), ctx, post)
 }
 
 // Delete mocks base method.
-func (m *MockPostRepo) Delete(id uint) error {
+func (m *MockPostRepo) Delete(ctx context.Context, id uint) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", id)
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Delete indicates an expected call of Delete.
-func (mr *MockPostRepoMockRecorder) Delete(id any) *gomock.Call {
+func (mr *MockPostRepoMockRecorder) Delete(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPostRepo)(nil).Delete), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockPostRepo)(nil).Delete), ctx, id)
 }
 
 // GetAll mocks base method.
-func (m *MockPostRepo) GetAll() ([]models.Post, error) {
+func (m *MockPostRepo) GetAll(ctx context.Context) ([]models.Post, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAll")
+	ret := m.ctrl.Call(m, "GetAll", ctx)
 	ret0, _ := ret[0].([]models.Post)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetAll indicates an expected call of GetAll.
-func (mr *MockPostRepoMockRecorder) GetAll() *gomock.Call {
+func (mr *MockPostRepoMockRecorder) GetAll(ctx any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockPostRepo)(nil).GetAll))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAll", reflect.TypeOf((*MockPostRepo)(nil).GetAll), ctx)
 }
 
 // GetById mocks base method.
-func (m *MockPostRepo) GetById(id int) (*models.Post, error) {
+func (m *MockPostRepo) GetById(ctx context.Context, id int) (*models.Post, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetById", id)
+	ret := m.ctrl.Call(m, "GetById", ctx, id)
 	ret0, _ := ret[0].(*models.Post)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetById indicates an expected call of GetById.
-func (mr *MockPostRepoMockRecorder) GetById(id any) *gomock.Call {
+func (mr *MockPostRepoMockRecorder) GetById(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockPostRepo)(nil).GetById), id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetById", reflect.TypeOf((*MockPostRepo)(nil).GetById), ctx, id)
+}
+
+// GetFeed mocks base method.
+func (m *MockPostRepo) GetFeed(ctx context.Context, followerId, beforeId uint, limit int) ([]models.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeed", ctx, followerId, beforeId, limit)
+	ret0, _ := ret[0].([]models.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFeed indicates an expected call of GetFeed.
+func (mr *MockPostRepoMockRecorder) GetFeed(ctx, followerId, beforeId, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeed", reflect.TypeOf((*MockPostRepo)(nil).GetFeed), ctx, followerId, beforeId, limit)
+}
+
+// GetMostReacted mocks base method.
+func (m *MockPostRepo) GetMostReacted(ctx context.Context) ([]models.Post, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMostReacted", ctx)
+	ret0, _ := ret[0].([]models.Post)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMostReacted indicates an expected call of GetMostReacted.
+func (mr *MockPostRepoMockRecorder) GetMostReacted(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMostReacted", reflect.TypeOf((*MockPostRepo)(nil).GetMostReacted), ctx)
 }
 
 // Update mocks base method.
-func (m *MockPostRepo) Update(post *models.Post) error {
+func (m *MockPostRepo) Update(ctx context.Context, post *models.Post, columns ...string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Update", post)
+	varargs := []any{ctx, post}
+	for _, a := range columns {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Update", varargs...)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Update indicates an expected call of Update.
-func (mr *MockPostRepoMockRecorder) Update(post any) *gomock.Call {
+func (mr *MockPostRepoMockRecorder) Update(ctx, post any, columns ...any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockPostRepo)(nil).Update), post)
+	varargs := append([]any{ctx, post}, columns...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockPostRepo)(nil).Update), varargs...)
 }
@@ -0,0 +1,101 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: ./internal/repository/reaction.go
+//
+// Generated by this command:
+//
+//	mockgen -source=./internal/repository/reaction.go -destination=./internal/repository/mocks/reaction.go
+//
+
+// Package mock_repository is a generated GoMock package.
+package mock_repository
+
+import (
+	context "context"
+	reflect "reflect"
+
+	models "github.com/simple-crud-go/internal/models"
+	repository "github.com/simple-crud-go/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockReactionRepo is a mock of ReactionRepo interface.
+type MockReactionRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockReactionRepoMockRecorder
+}
+
+// MockReactionRepoMockRecorder is the mock recorder for MockReactionRepo.
+type MockReactionRepoMockRecorder struct {
+	mock *MockReactionRepo
+}
+
+// NewMockReactionRepo creates a new mock instance.
+func NewMockReactionRepo(ctrl *gomock.Controller) *MockReactionRepo {
+	mock := &MockReactionRepo{ctrl: ctrl}
+	mock.recorder = &MockReactionRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockReactionRepo) EXPECT() *MockReactionRepoMockRecorder {
+	return m.recorder
+}
+
+// CountByPostIds mocks base method.
+func (m *MockReactionRepo) CountByPostIds(ctx context.Context, postIds []uint) ([]repository.ReactionCount, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountByPostIds", ctx, postIds)
+	ret0, _ := ret[0].([]repository.ReactionCount)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountByPostIds indicates an expected call of CountByPostIds.
+func (mr *MockReactionRepoMockRecorder) CountByPostIds(ctx, postIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountByPostIds", reflect.TypeOf((*MockReactionRepo)(nil).CountByPostIds), ctx, postIds)
+}
+
+// Create mocks base method.
+func (m *MockReactionRepo) Create(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, reaction)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockReactionRepoMockRecorder) Create(ctx, reaction any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockReactionRepo)(nil).Create), ctx, reaction)
+}
+
+// Delete mocks base method.
+func (m *MockReactionRepo) Delete(ctx context.Context, postId, userId uint, kind string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, postId, userId, kind)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockReactionRepoMockRecorder) Delete(ctx, postId, userId, kind any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockReactionRepo)(nil).Delete), ctx, postId, userId, kind)
+}
+
+// GetByUserAndPostIds mocks base method.
+func (m *MockReactionRepo) GetByUserAndPostIds(ctx context.Context, userId uint, postIds []uint) ([]models.Reaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUserAndPostIds", ctx, userId, postIds)
+	ret0, _ := ret[0].([]models.Reaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUserAndPostIds indicates an expected call of GetByUserAndPostIds.
+func (mr *MockReactionRepoMockRecorder) GetByUserAndPostIds(ctx, userId, postIds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUserAndPostIds", reflect.TypeOf((*MockReactionRepo)(nil).GetByUserAndPostIds), ctx, userId, postIds)
+}
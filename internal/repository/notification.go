@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepo interface {
+	Create(ctx context.Context, notification *models.Notification) error
+	GetById(ctx context.Context, id uint) (*models.Notification, error)
+	// GetByUserId newest first, paginated with `beforeId`, the id of the last notification of the previous page, 0 for the first page.
+	GetByUserId(ctx context.Context, userId uint, unreadOnly bool, beforeId uint, limit int) ([]models.Notification, error)
+	CountUnread(ctx context.Context, userId uint) (int64, error)
+	MarkRead(ctx context.Context, id uint, readAt time.Time) error
+	MarkAllRead(ctx context.Context, userId uint, readAt time.Time) error
+	GetPreferences(ctx context.Context, userId uint) ([]models.NotificationPreference, error)
+	SavePreference(ctx context.Context, preference *models.NotificationPreference) error
+}
+
+func NewNotificationRepository(db *gorm.DB) *gormNotificationRepository {
+	return &gormNotificationRepository{
+		db: db,
+	}
+}
+
+type gormNotificationRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormNotificationRepository) Create(ctx context.Context, notification *models.Notification) error {
+	return r.db.WithContext(ctx).Create(notification).Error
+}
+
+func (r *gormNotificationRepository) GetById(ctx context.Context, id uint) (*models.Notification, error) {
+	var notification models.Notification
+	err := r.db.WithContext(ctx).First(&notification, id).Error
+	return &notification, err
+}
+
+func (r *gormNotificationRepository) GetByUserId(ctx context.Context, userId uint, unreadOnly bool, beforeId uint, limit int) ([]models.Notification, error) {
+	query := r.db.WithContext(ctx).Preload("Actor").Where("user_id = ?", userId)
+
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if beforeId != 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+
+	var notifications []models.Notification
+	err := query.Order("id DESC").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *gormNotificationRepository) CountUnread(ctx context.Context, userId uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userId).Count(&count).Error
+	return count, err
+}
+
+func (r *gormNotificationRepository) MarkRead(ctx context.Context, id uint, readAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Notification{}).Where("id = ? AND read_at IS NULL", id).UpdateColumn("read_at", readAt).Error
+}
+
+func (r *gormNotificationRepository) MarkAllRead(ctx context.Context, userId uint, readAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userId).UpdateColumn("read_at", readAt).Error
+}
+
+func (r *gormNotificationRepository) GetPreferences(ctx context.Context, userId uint) ([]models.NotificationPreference, error) {
+	var preferences []models.NotificationPreference
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Find(&preferences).Error
+	return preferences, err
+}
+
+func (r *gormNotificationRepository) SavePreference(ctx context.Context, preference *models.NotificationPreference) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+	}).Create(preference).Error
+}
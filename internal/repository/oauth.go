@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+)
+
+type OAuthRepo interface {
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+	GetClientByClientId(ctx context.Context, clientId string) (*models.OAuthClient, error)
+	GetClientsByUserId(ctx context.Context, userId uint) ([]models.OAuthClient, error)
+	DeleteClient(ctx context.Context, id uint) error
+
+	GetConsent(ctx context.Context, userId uint, oauthClientId uint) (*models.OAuthConsent, error)
+	GetConsentsByUserId(ctx context.Context, userId uint) ([]models.OAuthConsent, error)
+	SaveConsent(ctx context.Context, consent *models.OAuthConsent) error
+	DeleteConsent(ctx context.Context, id uint) error
+
+	CreateCode(ctx context.Context, code *models.OAuthAuthorizationCode) error
+	GetCodeByHash(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error)
+	// MarkCodeUsed returns false when the code was already used, so a code can only ever be redeemed once.
+	MarkCodeUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error)
+
+	CreateToken(ctx context.Context, token *models.OAuthToken) error
+	GetTokenByTokenId(ctx context.Context, tokenId string) (*models.OAuthToken, error)
+	RevokeToken(ctx context.Context, tokenId string, revokedAt time.Time) error
+	RevokeTokensByUserClient(ctx context.Context, userId uint, oauthClientId uint, revokedAt time.Time) error
+}
+
+func NewOAuthRepository(db *gorm.DB) *gormOAuthRepository {
+	return &gormOAuthRepository{
+		db: db,
+	}
+}
+
+type gormOAuthRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormOAuthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+func (r *gormOAuthRepository) GetClientByClientId(ctx context.Context, clientId string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := r.db.WithContext(ctx).Where("client_id = ?", clientId).First(&client).Error
+	return &client, err
+}
+
+func (r *gormOAuthRepository) GetClientsByUserId(ctx context.Context, userId uint) ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("id").Find(&clients).Error
+	return clients, err
+}
+
+func (r *gormOAuthRepository) DeleteClient(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("oauth_client_id = ?", id).Delete(&models.OAuthConsent{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.OAuthToken{}).Where("oauth_client_id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.OAuthClient{}, id).Error
+	})
+}
+
+func (r *gormOAuthRepository) GetConsent(ctx context.Context, userId uint, oauthClientId uint) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	err := r.db.WithContext(ctx).Where("user_id = ? AND oauth_client_id = ?", userId, oauthClientId).First(&consent).Error
+	return &consent, err
+}
+
+func (r *gormOAuthRepository) GetConsentsByUserId(ctx context.Context, userId uint) ([]models.OAuthConsent, error) {
+	var consents []models.OAuthConsent
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Preload("OAuthClient").Order("id").Find(&consents).Error
+	return consents, err
+}
+
+func (r *gormOAuthRepository) SaveConsent(ctx context.Context, consent *models.OAuthConsent) error {
+	return r.db.WithContext(ctx).Save(consent).Error
+}
+
+func (r *gormOAuthRepository) DeleteConsent(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.OAuthConsent{}, id).Error
+}
+
+func (r *gormOAuthRepository) CreateCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	return r.db.WithContext(ctx).Create(code).Error
+}
+
+func (r *gormOAuthRepository) GetCodeByHash(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error) {
+	var code models.OAuthAuthorizationCode
+	err := r.db.WithContext(ctx).Where("code_hash = ?", codeHash).First(&code).Error
+	return &code, err
+}
+
+func (r *gormOAuthRepository) MarkCodeUsed(ctx context.Context, id uint, usedAt time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.OAuthAuthorizationCode{}).Where("id = ? AND used_at IS NULL", id).Update("used_at", usedAt)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *gormOAuthRepository) CreateToken(ctx context.Context, token *models.OAuthToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *gormOAuthRepository) GetTokenByTokenId(ctx context.Context, tokenId string) (*models.OAuthToken, error) {
+	var token models.OAuthToken
+	err := r.db.WithContext(ctx).Where("token_id = ?", tokenId).First(&token).Error
+	return &token, err
+}
+
+func (r *gormOAuthRepository) RevokeToken(ctx context.Context, tokenId string, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OAuthToken{}).Where("token_id = ? AND revoked_at IS NULL", tokenId).Update("revoked_at", revokedAt).Error
+}
+
+func (r *gormOAuthRepository) RevokeTokensByUserClient(ctx context.Context, userId uint, oauthClientId uint, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OAuthToken{}).Where("user_id = ? AND oauth_client_id = ? AND revoked_at IS NULL", userId, oauthClientId).Update("revoked_at", revokedAt).Error
+}
@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxRepo interface {
+	Add(ctx context.Context, event *models.OutboxEvent) error
+	// ClaimPending the oldest events neither dispatched nor given up yet, leaving out the events of an aggregate from its
+	// first one that isn't due at `now` on. They aren't due again before `leaseUntil`, so the other dispatchers leave
+	// them alone while they are being dispatched and hold back the following events of their aggregates.
+	ClaimPending(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error)
+	// Release makes the claimed events due again right away.
+	Release(ctx context.Context, ids []uint) error
+	// MarkDispatched the event was published to every subscriber, its receipts are removed.
+	MarkDispatched(ctx context.Context, id uint, dispatchedAt time.Time) error
+	// GetReceipts the subscribers that received the event.
+	GetReceipts(ctx context.Context, id uint) ([]string, error)
+	// AddReceipt records that the subscriber received the event, recording it again does nothing.
+	AddReceipt(ctx context.Context, id uint, subscriber string) error
+	// MarkFailed counts a failed dispatch of the event, it is dispatched again at `nextAttemptAt`.
+	MarkFailed(ctx context.Context, id uint, lastError string, nextAttemptAt time.Time) error
+	// MarkDead counts the last failed dispatch of the event and gives it up, it stays in the outbox.
+	MarkDead(ctx context.Context, id uint, lastError string, failedAt time.Time) error
+	// DeleteDispatchedBefore removes the events dispatched before `before`.
+	DeleteDispatchedBefore(ctx context.Context, before time.Time) error
+}
+
+func NewOutboxRepository(db *gorm.DB) *gormOutboxRepository {
+	return &gormOutboxRepository{
+		db: db,
+	}
+}
+
+type gormOutboxRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormOutboxRepository) Add(ctx context.Context, event *models.OutboxEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *gormOutboxRepository) ClaimPending(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	events := make([]models.OutboxEvent, 0, limit)
+	// The aggregates whose events wait for an earlier one
+	held := map[string]bool{}
+	var lastId uint
+
+	// Read by pages, so held events filling a page don't keep the following ones from being claimed
+	for len(events) < limit {
+		var pending []models.OutboxEvent
+		err := r.db.WithContext(ctx).Where("dispatched_at IS NULL AND failed_at IS NULL AND id > ?", lastId).
+			Order("id").Limit(limit).Find(&pending).Error
+		if err != nil {
+			return events, err
+		}
+
+		if len(pending) == 0 {
+			break
+		}
+
+		for _, event := range pending {
+			lastId = event.ID
+
+			aggregate := event.Aggregate()
+			if held[aggregate] {
+				continue
+			}
+
+			// Only the dispatcher whose update still finds the event due gets it, the following events of its
+			// aggregate wait for it
+			result := r.db.WithContext(ctx).Model(&models.OutboxEvent{}).
+				Where("id = ? AND dispatched_at IS NULL AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", event.ID, now).
+				UpdateColumn("next_attempt_at", leaseUntil)
+			if result.Error != nil {
+				return events, result.Error
+			}
+
+			if result.RowsAffected == 0 {
+				held[aggregate] = true
+				continue
+			}
+
+			event.NextAttemptAt = &leaseUntil
+			events = append(events, event)
+
+			if len(events) == limit {
+				break
+			}
+		}
+
+		if len(pending) < limit {
+			break
+		}
+	}
+
+	return events, nil
+}
+
+func (r *gormOutboxRepository) Release(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id IN ? AND dispatched_at IS NULL", ids).UpdateColumn("next_attempt_at", nil).Error
+}
+
+func (r *gormOutboxRepository) MarkDispatched(ctx context.Context, id uint, dispatchedAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OutboxEvent{}).Where("id = ?", id).UpdateColumn("dispatched_at", dispatchedAt).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("event_id = ?", id).Delete(&models.OutboxReceipt{}).Error
+	})
+}
+
+func (r *gormOutboxRepository) GetReceipts(ctx context.Context, id uint) ([]string, error) {
+	var subscribers []string
+	err := r.db.WithContext(ctx).Model(&models.OutboxReceipt{}).Where("event_id = ?", id).Pluck("subscriber", &subscribers).Error
+	return subscribers, err
+}
+
+func (r *gormOutboxRepository) AddReceipt(ctx context.Context, id uint, subscriber string) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.OutboxReceipt{EventID: id, Subscriber: subscriber}).Error
+}
+
+func (r *gormOutboxRepository) MarkFailed(ctx context.Context, id uint, lastError string, nextAttemptAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      lastError,
+		"next_attempt_at": nextAttemptAt,
+	}).Error
+}
+
+func (r *gormOutboxRepository) MarkDead(ctx context.Context, id uint, lastError string, failedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxEvent{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      lastError,
+		"next_attempt_at": nil,
+		"failed_at":       failedAt,
+	}).Error
+}
+
+func (r *gormOutboxRepository) DeleteDispatchedBefore(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).Where("dispatched_at < ?", before).Delete(&models.OutboxEvent{}).Error
+}
@@ -1,52 +1,99 @@
 package repository
 
 import (
+	"context"
+
+	"github.com/simple-crud-go/internal/database"
 	"github.com/simple-crud-go/internal/models"
 	"gorm.io/gorm"
 )
 
 type PostRepo interface {
-	Create(post *models.Post) error
-	Update(post *models.Post) error
-	GetById(id int) (*models.Post, error)
-	GetAll() ([]models.Post, error)
-	Delete(id uint) error
+	Create(ctx context.Context, post *models.Post) error
+	// Update writes the given columns of the post, the others keep what they are in the database.
+	Update(ctx context.Context, post *models.Post, columns ...string) error
+	GetById(ctx context.Context, id int) (*models.Post, error)
+	GetAll(ctx context.Context) ([]models.Post, error)
+	// GetMostReacted every post, the ones with the most reactions first.
+	GetMostReacted(ctx context.Context) ([]models.Post, error)
+	// GetFeed posts of the authors followed by `followerId`, newest first, older than `beforeId` unless it is 0.
+	GetFeed(ctx context.Context, followerId uint, beforeId uint, limit int) ([]models.Post, error)
+	Delete(ctx context.Context, id uint) error
 }
 
-func NewPostRepository(db *gorm.DB) *gormPostRepository {
+// NewPostRepository the lookups by id and the listing read from `replicas` when given.
+func NewPostRepository(db *gorm.DB, replicas *database.Replicas) *gormPostRepository {
 	return &gormPostRepository{
-		db: db,
+		db:       db,
+		replicas: replicas,
 	}
 }
 
 type gormPostRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	replicas *database.Replicas
 }
 
-func (r *gormPostRepository) GetById(id int) (*models.Post, error) {
+func (r *gormPostRepository) GetById(ctx context.Context, id int) (*models.Post, error) {
 	var post models.Post
-	// err := r.db.Model(&models.Post{}).Preload("User", func(db *gorm.DB) *gorm.DB {
+	// err := r.db.WithContext(ctx).Model(&models.Post{}).Preload("User", func(db *gorm.DB) *gorm.DB {
 	// 	return db.Omit("Posts")
 	// }).First(&post, id).Error
-	err := r.db.Model(&models.Post{}).Preload("User").First(&post, id).Error
+	err := r.replicas.Read(ctx, r.db, func(db *gorm.DB) error {
+		post = models.Post{}
+		return db.Model(&models.Post{}).Preload("User").First(&post, id).Error
+	})
 	return &post, err
 }
 
-func (r *gormPostRepository) GetAll() ([]models.Post, error) {
+func (r *gormPostRepository) GetAll(ctx context.Context) ([]models.Post, error) {
 	var posts []models.Post
-	err := r.db.Model(&models.Post{}).Preload("User").Find(&posts).Error
+	err := r.replicas.Read(ctx, r.db, func(db *gorm.DB) error {
+		posts = nil
+		return db.Model(&models.Post{}).Preload("User").Find(&posts).Error
+	})
 
 	return posts, err
 }
 
-func (r *gormPostRepository) Create(post *models.Post) error {
-	return r.db.Create(&post).Error
+func (r *gormPostRepository) GetMostReacted(ctx context.Context) ([]models.Post, error) {
+	var posts []models.Post
+	err := r.db.WithContext(ctx).Model(&models.Post{}).
+		Preload("User").
+		Order("(SELECT COUNT(*) FROM reactions WHERE reactions.post_id = posts.id) DESC, posts.id DESC").
+		Find(&posts).Error
+
+	return posts, err
 }
 
-func (r *gormPostRepository) Update(post *models.Post) error {
-	return r.db.Save(&post).Error
+// GetFeed joins from the follows of the user, both sides are covered by an index: (follower_id, followee_id)
+// on follows and (user_id, id) on posts. Paginating on the post id instead of an offset keeps deep pages as
+// cheap as the first one.
+func (r *gormPostRepository) GetFeed(ctx context.Context, followerId uint, beforeId uint, limit int) ([]models.Post, error) {
+	query := r.db.WithContext(ctx).Model(&models.Post{}).
+		Joins("JOIN follows ON follows.followee_id = posts.user_id AND follows.follower_id = ?", followerId)
+
+	if beforeId != 0 {
+		query = query.Where("posts.id < ?", beforeId)
+	}
+
+	var posts []models.Post
+	err := query.Preload("User").Order("posts.id DESC").Limit(limit).Find(&posts).Error
+	return posts, err
+}
+
+func (r *gormPostRepository) Create(ctx context.Context, post *models.Post) error {
+	return r.db.WithContext(ctx).Create(&post).Error
+}
+
+func (r *gormPostRepository) Update(ctx context.Context, post *models.Post, columns ...string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Model(post).Select(columns).Updates(post).Error
 }
 
-func (r *gormPostRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Post{}, id).Error
+func (r *gormPostRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.Post{}, id).Error
 }
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReactionCount number of reactions of a kind on a post.
+type ReactionCount struct {
+	PostID uint
+	Kind   string
+	Count  int64
+}
+
+type ReactionRepo interface {
+	// Create reacting twice with the same kind is a no-op, `created` reports whether the reaction is new.
+	Create(ctx context.Context, reaction *models.Reaction) (created bool, err error)
+	Delete(ctx context.Context, postId uint, userId uint, kind string) error
+	CountByPostIds(ctx context.Context, postIds []uint) ([]ReactionCount, error)
+	GetByUserAndPostIds(ctx context.Context, userId uint, postIds []uint) ([]models.Reaction, error)
+}
+
+func NewReactionRepository(db *gorm.DB) *gormReactionRepository {
+	return &gormReactionRepository{
+		db: db,
+	}
+}
+
+type gormReactionRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormReactionRepository) Create(ctx context.Context, reaction *models.Reaction) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(reaction)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *gormReactionRepository) Delete(ctx context.Context, postId uint, userId uint, kind string) error {
+	return r.db.WithContext(ctx).Where("post_id = ? AND user_id = ? AND kind = ?", postId, userId, kind).Delete(&models.Reaction{}).Error
+}
+
+func (r *gormReactionRepository) CountByPostIds(ctx context.Context, postIds []uint) ([]ReactionCount, error) {
+	var counts []ReactionCount
+	err := r.db.WithContext(ctx).Model(&models.Reaction{}).
+		Select("post_id, kind, COUNT(*) AS count").
+		Where("post_id IN ?", postIds).
+		Group("post_id, kind").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *gormReactionRepository) GetByUserAndPostIds(ctx context.Context, userId uint, postIds []uint) ([]models.Reaction, error) {
+	var reactions []models.Reaction
+	err := r.db.WithContext(ctx).Where("user_id = ? AND post_id IN ?", userId, postIds).Find(&reactions).Error
+	return reactions, err
+}
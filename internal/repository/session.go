@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+)
+
+type SessionRepo interface {
+	Create(ctx context.Context, session *models.Session) error
+	GetById(ctx context.Context, id uint) (*models.Session, error)
+	GetActiveByUserId(ctx context.Context, userId uint, now time.Time) ([]models.Session, error)
+	Revoke(ctx context.Context, id uint, revokedAt time.Time) error
+	RevokeAllByUserId(ctx context.Context, userId uint, exceptId uint, revokedAt time.Time) error
+	TouchLastSeen(ctx context.Context, id uint, seenAt time.Time) error
+}
+
+func NewSessionRepository(db *gorm.DB) *gormSessionRepository {
+	return &gormSessionRepository{
+		db: db,
+	}
+}
+
+type gormSessionRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormSessionRepository) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *gormSessionRepository) GetById(ctx context.Context, id uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).First(&session, id).Error
+	return &session, err
+}
+
+func (r *gormSessionRepository) GetActiveByUserId(ctx context.Context, userId uint, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, now).Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *gormSessionRepository) Revoke(ctx context.Context, id uint, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ? AND revoked_at IS NULL", id).UpdateColumn("revoked_at", revokedAt).Error
+}
+
+// RevokeAllByUserId revokes every session of the user but `exceptId`, pass 0 to revoke them all.
+func (r *gormSessionRepository) RevokeAllByUserId(ctx context.Context, userId uint, exceptId uint, revokedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userId, exceptId).
+		UpdateColumn("revoked_at", revokedAt).Error
+}
+
+func (r *gormSessionRepository) TouchLastSeen(ctx context.Context, id uint, seenAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).Where("id = ?", id).UpdateColumn("last_seen_at", seenAt).Error
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Repositories bound to a single transaction.
+type Repositories struct {
+	Users         UserRepo
+	Identities    IdentityRepo
+	Posts         PostRepo
+	Follows       FollowRepo
+	Reactions     ReactionRepo
+	Bookmarks     BookmarkRepo
+	Notifications NotificationRepo
+	Outbox        OutboxRepo
+	// Tx runs a nested transaction in a savepoint, rolling it back only undoes what was done inside of it.
+	Tx Transactor
+}
+
+// Transactor runs `fn` in a database transaction, everything done through `repos` is rolled back when it returns an
+// error or panics. A panic is passed on once the transaction is rolled back.
+type Transactor interface {
+	RunInTx(ctx context.Context, fn func(repos Repositories) error) error
+}
+
+// NewTransactor `onCommit`, when set, is called after every committed transaction.
+func NewTransactor(db *gorm.DB, onCommit func()) *gormTransactor {
+	return &gormTransactor{
+		db:       db,
+		onCommit: onCommit,
+	}
+}
+
+type gormTransactor struct {
+	db       *gorm.DB
+	onCommit func()
+}
+
+func (t *gormTransactor) RunInTx(ctx context.Context, fn func(repos Repositories) error) error {
+	// Called on a transaction gorm creates a savepoint instead of a new transaction, and rolls back to it on errors
+	// and panics.
+	err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Everything reads from the transaction, a replica wouldn't see what it wrote
+		return fn(Repositories{
+			Users:         NewUserRepository(tx, nil),
+			Identities:    NewIdentityRepository(tx),
+			Posts:         NewPostRepository(tx, nil),
+			Follows:       NewFollowRepository(tx),
+			Reactions:     NewReactionRepository(tx),
+			Bookmarks:     NewBookmarkRepository(tx),
+			Notifications: NewNotificationRepository(tx),
+			Outbox:        NewOutboxRepository(tx),
+			// Nested transactions aren't committed on their own, only the outermost one calls `onCommit`.
+			Tx: &gormTransactor{db: tx},
+		})
+	})
+
+	if err == nil && t.onCommit != nil {
+		t.onCommit()
+	}
+
+	return err
+}
@@ -1,57 +1,87 @@
 package repository
 
 import (
+	"context"
+
+	"github.com/simple-crud-go/internal/database"
 	"github.com/simple-crud-go/internal/models"
 	"gorm.io/gorm"
 )
 
 type UserRepo interface {
-	Update(user models.User) error
-	Create(user models.User) error
-	GetById(id uint) (*models.User, error)
-	GetByUsername(username string) (*models.User, error)
-	GetAll() ([]models.User, error)
-	DeleteById(id uint) error
+	// Update writes the given columns of the user, the others keep what they are in the database.
+	Update(ctx context.Context, user models.User, columns ...string) error
+	Create(ctx context.Context, user *models.User) error
+	GetById(ctx context.Context, id uint) (*models.User, error)
+	GetAuthUser(ctx context.Context, id uint) (*models.User, error)
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetAll(ctx context.Context) ([]models.User, error)
+	DeleteById(ctx context.Context, id uint) error
 }
 
-func NewUserRepository(db *gorm.DB) *gormUserRepository {
+// NewUserRepository the lookups by id and username and the listing read from `replicas` when given.
+func NewUserRepository(db *gorm.DB, replicas *database.Replicas) *gormUserRepository {
 	return &gormUserRepository{
-		db: db,
+		db:       db,
+		replicas: replicas,
 	}
 }
 
 type gormUserRepository struct {
-	db *gorm.DB
+	db       *gorm.DB
+	replicas *database.Replicas
 }
 
-func (r *gormUserRepository) Update(user models.User) error {
-	return r.db.Save(&user).Error
+func (r *gormUserRepository) Update(ctx context.Context, user models.User, columns ...string) error {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Model(&user).Select(columns).Updates(&user).Error
 }
 
-func (r *gormUserRepository) GetAll() ([]models.User, error) {
+func (r *gormUserRepository) GetAll(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	err := r.db.Omit("posts").Find(&users).Error
+	err := r.replicas.Read(ctx, r.db, func(db *gorm.DB) error {
+		users = nil
+		return db.Omit("posts").Find(&users).Error
+	})
 
 	return users, err
 }
 
-func (r *gormUserRepository) Create(user models.User) error {
-	return r.db.Create(&user).Error
+func (r *gormUserRepository) Create(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *gormUserRepository) GetByUsername(username string) (*models.User, error) {
+func (r *gormUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user *models.User
-	err := r.db.Where("username = ?", username).Preload("Posts").First(&user).Error
+	err := r.replicas.Read(ctx, r.db, func(db *gorm.DB) error {
+		user = nil
+		return db.Where("LOWER(username) = LOWER(?)", username).Preload("Posts").First(&user).Error
+	})
 	return user, err
 }
 
-func (r *gormUserRepository) GetById(id uint) (*models.User, error) {
+func (r *gormUserRepository) GetById(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	err := r.replicas.Read(ctx, r.db, func(db *gorm.DB) error {
+		user = models.User{}
+		return db.Preload("Posts").First(&user, id).Error
+	})
+	return &user, err
+}
+
+func (r *gormUserRepository) GetAuthUser(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	err := r.db.Preload("Posts").First(&user, id).Error
+	err := r.replicas.Read(ctx, r.db, func(db *gorm.DB) error {
+		user = models.User{}
+		return db.Select("id", "username", "role").First(&user, id).Error
+	})
 	return &user, err
 }
 
-func (r *gormUserRepository) DeleteById(id uint) error {
-	err := r.db.Delete(&models.User{}, id).Error
+func (r *gormUserRepository) DeleteById(ctx context.Context, id uint) error {
+	err := r.db.WithContext(ctx).Delete(&models.User{}, id).Error
 	return err
 }
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"gorm.io/gorm"
+)
+
+type WebhookRepo interface {
+	Create(ctx context.Context, webhook *models.Webhook) error
+	Update(ctx context.Context, webhook *models.Webhook) error
+	// Delete removes the webhook along with its deliveries.
+	Delete(ctx context.Context, id uint) error
+	GetById(ctx context.Context, id uint) (*models.Webhook, error)
+	GetByUserId(ctx context.Context, userId uint) ([]models.Webhook, error)
+	// GetEnabled every webhook that isn't disabled.
+	GetEnabled(ctx context.Context) ([]models.Webhook, error)
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// CreateDeliveries queues the deliveries in a single statement, either all of them or none.
+	CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDeliveryById(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	// GetDeliveries newest first, paginated with `beforeId`, the id of the last delivery of the previous page, 0 for the first page.
+	GetDeliveries(ctx context.Context, webhookId uint, beforeId uint, limit int) ([]models.WebhookDelivery, error)
+	// ClaimDueDeliveries the pending deliveries of enabled webhooks due at `now`, oldest first, with their webhook. They
+	// aren't due again before `leaseUntil`, so the other dispatchers leave them alone while they are being sent.
+	ClaimDueDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	// ReleaseDeliveries makes the claimed deliveries due again at `nextAttemptAt`.
+	ReleaseDeliveries(ctx context.Context, ids []uint, nextAttemptAt time.Time) error
+	// ResetFailures clears the consecutive failures of the webhook, leaving the rest of it untouched.
+	ResetFailures(ctx context.Context, id uint) error
+	// RecordFailure counts a failed delivery of the webhook and disables it at `disabledAt` once it failed `disableAfter`
+	// times in a row, 0 never disables it. It returns the webhook as updated.
+	RecordFailure(ctx context.Context, id uint, disableAfter int, disabledAt time.Time) (*models.Webhook, error)
+}
+
+func NewWebhookRepository(db *gorm.DB) *gormWebhookRepository {
+	return &gormWebhookRepository{
+		db: db,
+	}
+}
+
+type gormWebhookRepository struct {
+	db *gorm.DB
+}
+
+func (r *gormWebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *gormWebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+func (r *gormWebhookRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.Webhook{}, id).Error
+	})
+}
+
+func (r *gormWebhookRepository) GetById(ctx context.Context, id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.WithContext(ctx).First(&webhook, id).Error
+	return &webhook, err
+}
+
+func (r *gormWebhookRepository) GetByUserId(ctx context.Context, userId uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Order("id").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *gormWebhookRepository) GetEnabled(ctx context.Context) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.WithContext(ctx).Where("disabled_at IS NULL").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *gormWebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *gormWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *gormWebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Omit("Webhook").Save(delivery).Error
+}
+
+func (r *gormWebhookRepository) GetDeliveryById(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.WithContext(ctx).First(&delivery, id).Error
+	return &delivery, err
+}
+
+func (r *gormWebhookRepository) GetDeliveries(ctx context.Context, webhookId uint, beforeId uint, limit int) ([]models.WebhookDelivery, error) {
+	query := r.db.WithContext(ctx).Where("webhook_id = ?", webhookId)
+
+	if beforeId != 0 {
+		query = query.Where("id < ?", beforeId)
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("id DESC").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *gormWebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var due []models.WebhookDelivery
+	err := r.db.WithContext(ctx).InnerJoins("Webhook").
+		Where("disabled_at IS NULL AND webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("webhook_deliveries.next_attempt_at").
+		Limit(limit).
+		Find(&due).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the dispatcher whose update still finds the delivery due gets it
+	deliveries := make([]models.WebhookDelivery, 0, len(due))
+	for _, delivery := range due {
+		result := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, models.WebhookDeliveryPending, now).
+			UpdateColumn("next_attempt_at", leaseUntil)
+		if result.Error != nil {
+			return deliveries, result.Error
+		}
+
+		if result.RowsAffected == 1 {
+			delivery.NextAttemptAt = &leaseUntil
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (r *gormWebhookRepository) ReleaseDeliveries(ctx context.Context, ids []uint, nextAttemptAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("id IN ? AND status = ?", ids, models.WebhookDeliveryPending).
+		UpdateColumn("next_attempt_at", nextAttemptAt).Error
+}
+
+func (r *gormWebhookRepository) ResetFailures(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Model(&models.Webhook{}).Where("id = ?", id).UpdateColumn("consecutive_failures", 0).Error
+}
+
+func (r *gormWebhookRepository) RecordFailure(ctx context.Context, id uint, disableAfter int, disabledAt time.Time) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Incremented in the database, the concurrent deliveries and edits of the webhook don't overwrite each other
+		err := tx.Model(&models.Webhook{}).Where("id = ?", id).UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+		if err != nil {
+			return err
+		}
+
+		if disableAfter > 0 {
+			err = tx.Model(&models.Webhook{}).Where("id = ? AND disabled_at IS NULL AND consecutive_failures >= ?", id, disableAfter).
+				UpdateColumn("disabled_at", disabledAt).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.First(&webhook, id).Error
+	})
+
+	return &webhook, err
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// AdminService moderation actions, the routes using it require the admin scope.
+type AdminService struct {
+	UserRepository    repository.UserRepo
+	PostRepository    repository.PostRepo
+	SessionRepository repository.SessionRepo
+	AuditRepository   repository.AuditRepo
+	Transactor        repository.Transactor
+}
+
+func NewAdminService(userRepo repository.UserRepo, postRepo repository.PostRepo, sessionRepo repository.SessionRepo, auditRepo repository.AuditRepo, transactor repository.Transactor) *AdminService {
+	return &AdminService{
+		UserRepository:    userRepo,
+		PostRepository:    postRepo,
+		SessionRepository: sessionRepo,
+		AuditRepository:   auditRepo,
+		Transactor:        transactor,
+	}
+}
+
+// SetRole changes the role of the user. The scopes of a session are fixed at login,
+// so the sessions of the user are revoked to make the change effective right away.
+func (s *AdminService) SetRole(ctx context.Context, adminId int, username string, role string, client ClientInfo) error {
+	if role != models.RoleUser && role != models.RoleAdmin {
+		return &ValidationError{Fields: map[string][]string{"role": {"must be either '" + models.RoleUser + "' or '" + models.RoleAdmin + "'"}}}
+	}
+
+	var (
+		user         *models.User
+		previousRole string
+	)
+
+	// Read from the transaction rather than the cache or a replica, and only the role is written, not to undo a
+	// concurrent change of the user.
+	err := s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		user, err = repos.Users.GetByUsername(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		previousRole = user.Role
+		if user.Role == role {
+			return nil
+		}
+
+		user.Role = role
+		return repos.Users.Update(ctx, *user, "role")
+	})
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return err
+	}
+
+	if previousRole == role {
+		return nil
+	}
+
+	actorId := uint(adminId)
+	recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+		Action:     models.AuditAdminRoleChange,
+		ActorID:    &actorId,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditID(user.ID),
+		Before:     map[string]string{"role": previousRole},
+		After:      map[string]string{"role": role},
+	})
+
+	if err = s.SessionRepository.RevokeAllByUserId(ctx, user.ID, 0, time.Now()); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// DeletePost deletes any post, regardless of its author.
+func (s *AdminService) DeletePost(ctx context.Context, adminId int, postId int, client ClientInfo) error {
+	post, err := s.PostRepository.GetById(ctx, postId)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return err
+	}
+
+	if err = deletePost(ctx, s.Transactor, post, uint(adminId)); err != nil {
+		return err
+	}
+
+	actorId := uint(adminId)
+	before := postSummary(post)
+	before["author_id"] = auditID(post.UserID)
+
+	recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+		Action:     models.AuditAdminPostDelete,
+		ActorID:    &actorId,
+		TargetType: models.AuditTargetPost,
+		TargetID:   auditID(post.ID),
+		Before:     before,
+	})
+
+	return nil
+}
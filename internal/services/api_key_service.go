@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix marks our API keys so they are easy to recognise (e.g. by secret scanners).
+// A key looks like `sck_<8 hex chars lookup prefix>_<secret>`.
+const apiKeyPrefix = "sck_"
+
+var ErrInvalidAPIKey = errors.New("Invalid API key")
+
+type APIKeyService struct {
+	APIKeyRepository repository.APIKeyRepo
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepo) *APIKeyService {
+	return &APIKeyService{
+		APIKeyRepository: apiKeyRepo,
+	}
+}
+
+// CreateKey `scopes` have to be part of the `grantedScopes` of the caller, the key gets every scope of the caller that
+// can be delegated when none are given.
+func (s *APIKeyService) CreateKey(ctx context.Context, userId int, grantedScopes []string, label string, scopes []string, expiresAt *time.Time) (*api.APIKeyCreatedResponse, error) {
+	// A key stored without scopes would act as a regular user, whatever the caller was allowed to do
+	if len(scopes) == 0 {
+		scopes = helper.DelegableScopes(grantedScopes)
+	}
+
+	if len(scopes) == 0 {
+		return nil, &ValidationError{Fields: map[string][]string{"scopes": {"at least one scope is required"}}}
+	} else if err := helper.ValidateScopes(scopes); err != nil {
+		return nil, &ValidationError{Fields: map[string][]string{"scopes": {err.Error()}}}
+	} else if !helper.ContainsScopes(grantedScopes, scopes) {
+		return nil, &ValidationError{Fields: map[string][]string{"scopes": {"can't grant scopes the current token doesn't have"}}}
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, &ValidationError{Fields: map[string][]string{"expires_at": {"must be in the future"}}}
+	}
+
+	prefixBytes := make([]byte, 4)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return nil, err
+	}
+
+	secret, err := helper.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := hex.EncodeToString(prefixBytes)
+	plainKey := apiKeyPrefix + prefix + "_" + secret
+
+	key := models.APIKey{
+		UserID:    uint(userId),
+		Label:     label,
+		Prefix:    prefix,
+		KeyHash:   helper.HashToken(plainKey),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+
+	if err = s.APIKeyRepository.Create(ctx, &key); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &api.APIKeyCreatedResponse{Key: plainKey, APIKey: &key}, nil
+}
+
+func (s *APIKeyService) GetKeys(ctx context.Context, userId int) ([]models.APIKey, error) {
+	keys, err := s.APIKeyRepository.GetAllByUserId(ctx, uint(userId))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (s *APIKeyService) UpdateLabel(ctx context.Context, userId int, keyId int, label string) error {
+	key, err := s.ownedKey(ctx, userId, keyId)
+	if err != nil {
+		return err
+	}
+
+	key.Label = label
+
+	if err = s.APIKeyRepository.Update(ctx, key); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *APIKeyService) RevokeKey(ctx context.Context, userId int, keyId int) error {
+	key, err := s.ownedKey(ctx, userId, keyId)
+	if err != nil {
+		return err
+	}
+
+	if key.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+
+	if err = s.APIKeyRepository.Update(ctx, key); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Authenticate returns the key matching the plain API key, as long as it is neither revoked nor expired.
+func (s *APIKeyService) Authenticate(ctx context.Context, plainKey string) (*models.APIKey, error) {
+	rest, ok := strings.CutPrefix(plainKey, apiKeyPrefix)
+	if !ok || len(rest) < 10 || rest[8] != '_' {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.APIKeyRepository.GetByPrefix(ctx, rest[:8])
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if !helper.CompareTokenHash(key.KeyHash, plainKey) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(now)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err = s.APIKeyRepository.TouchLastUsed(ctx, key.ID, now); err != nil {
+		// Failing to record the usage shouldn't lock the client out
+		logrus.Error(err)
+	} else {
+		key.LastUsedAt = &now
+	}
+
+	return key, nil
+}
+
+// ownedKey returns the key only if it belongs to the user, other users keys are reported as not found.
+func (s *APIKeyService) ownedKey(ctx context.Context, userId int, keyId int) (*models.APIKey, error) {
+	key, err := s.APIKeyRepository.GetById(ctx, uint(keyId))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return nil, err
+	}
+
+	if key.UserID != uint(userId) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return key, nil
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+	// auditSummaryLength longest value kept in the before/after summaries, post bodies can be long.
+	auditSummaryLength = 200
+)
+
+type AuditService struct {
+	AuditRepository repository.AuditRepo
+}
+
+func NewAuditService(auditRepo repository.AuditRepo) *AuditService {
+	return &AuditService{
+		AuditRepository: auditRepo,
+	}
+}
+
+func (s *AuditService) Search(ctx context.Context, filter repository.AuditFilter) ([]models.AuditEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditLimit
+	} else if filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+
+	events, err := s.AuditRepository.Find(ctx, filter)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// recordAudit appends the event to the audit log. A failure is logged but doesn't fail the audited action.
+// The action is done by then, so the event is recorded even when the client went away.
+func recordAudit(ctx context.Context, auditRepo repository.AuditRepo, client ClientInfo, event models.AuditEvent) {
+	event.IP = client.IP
+	event.UserAgent = truncate(client.UserAgent, userAgentMaxLength)
+
+	if err := auditRepo.Create(context.WithoutCancel(ctx), &event); err != nil {
+		logrus.WithField("action", event.Action).Error(err)
+	}
+}
+
+func auditID(id uint) string {
+	return strconv.Itoa(int(id))
+}
+
+func postSummary(post *models.Post) map[string]string {
+	return map[string]string{
+		"title": truncate(post.Title, auditSummaryLength),
+		"body":  truncate(post.Body, auditSummaryLength),
+	}
+}
+
+func truncate(s string, length int) string {
+	if len(s) > length {
+		// Cutting in the middle of a multi-byte character would leave an invalid sequence
+		return strings.ToValidUTF8(s[:length], "")
+	}
+
+	return s
+}
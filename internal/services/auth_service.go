@@ -1,7 +1,10 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/simple-crud-go/api"
 	"github.com/simple-crud-go/internal/helper"
@@ -11,83 +14,118 @@ import (
 	"gorm.io/gorm"
 )
 
+// scopedTokenTTL default lifetime of the tokens minted with CreateScopedToken.
+const scopedTokenTTL = 24 * time.Hour
+
 type AuthService struct {
-	UserRepository repository.UserRepo
-	PasswordCrypto helper.PasswordCrypto
-	jwtHelper      helper.JWTHelper
+	UserRepository    repository.UserRepo
+	SessionRepository repository.SessionRepo
+	AuditRepository   repository.AuditRepo
+	PasswordCrypto    helper.PasswordCrypto
+	PasswordPolicy    helper.PasswordPolicy
+	Transactor        repository.Transactor
+	jwtHelper         helper.JWTHelper
 }
 
-func NewAuthService(userRepo repository.UserRepo, passwordCrypto helper.PasswordCrypto, jwtHelper helper.JWTHelper) *AuthService {
+func NewAuthService(userRepo repository.UserRepo, sessionRepo repository.SessionRepo, auditRepo repository.AuditRepo, passwordCrypto helper.PasswordCrypto, passwordPolicy helper.PasswordPolicy, jwtHelper helper.JWTHelper, transactor repository.Transactor) *AuthService {
 	return &AuthService{
-		UserRepository: userRepo,
-		PasswordCrypto: passwordCrypto,
-		jwtHelper:      jwtHelper,
+		UserRepository:    userRepo,
+		SessionRepository: sessionRepo,
+		AuditRepository:   auditRepo,
+		PasswordCrypto:    passwordCrypto,
+		PasswordPolicy:    passwordPolicy,
+		Transactor:        transactor,
+		jwtHelper:         jwtHelper,
 	}
 }
 
-func (s *AuthService) Login(username string, password string) (string, error) {
-	user, err := s.UserRepository.GetByUsername(username)
+func (s *AuthService) Login(ctx context.Context, username string, password string, client ClientInfo) (string, error) {
+	user, err := s.UserRepository.GetByUsername(ctx, username)
 	if err != nil {
 		logrus.Error(err)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.recordLoginFailure(ctx, username, 0, client)
+		}
 		return "", err
 	}
 
 	if user == nil || user.ID == 0 {
 		logrus.Error("user doesn't exist")
+		s.recordLoginFailure(ctx, username, 0, client)
 		return "", gorm.ErrRecordNotFound
 	}
 
 	if err = s.PasswordCrypto.ComparePassword(user.Password, password); err != nil {
 		logrus.Error(err)
+		s.recordLoginFailure(ctx, username, user.ID, client)
 		return "", err
 	}
 
-	// token, err := helper.CreateToken(username)
-	token, err := s.jwtHelper.CreateToken(int(user.ID))
+	token, err := startSession(ctx, s.SessionRepository, s.jwtHelper, user.ID, scopesForUser(user), sessionTTL, client)
 	if err != nil {
 		return "", err
 	}
 
+	recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+		Action:     models.AuditLogin,
+		ActorID:    &user.ID,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditID(user.ID),
+	})
+
 	return token, nil
 }
 
-func (s *AuthService) Register(name string, username string, password string) (*api.RegisterSuccessResponse, error) {
-	user, err := s.UserRepository.GetByUsername(username)
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		logrus.Error(err)
+func (s *AuthService) Register(ctx context.Context, name string, username string, password string, client ClientInfo) (*api.RegisterSuccessResponse, error) {
+	if err := validatePassword(s.PasswordPolicy, password, username, name); err != nil {
 		return nil, err
 	}
 
-	if user.ID != 0 {
-		logrus.Error("User already exists")
-		return nil, ErrUserExist
-	}
-
 	hashedPassword, err := s.PasswordCrypto.HashPassword(password)
 	if err != nil {
-		logrus.Error(nil)
+		logrus.Error(err)
 		return nil, err
 	}
 
-	newUser := models.User{
+	user := &models.User{
 		Name:     name,
 		Username: username,
 		Password: hashedPassword,
 	}
 
-	err = s.UserRepository.Create(newUser)
-	if err != nil {
-		logrus.Error(err)
-		return nil, err
-	}
+	// The username is checked in the same transaction the user is created in, so a failure midway leaves nothing behind.
+	err = s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		existing, err := repos.Users.GetByUsername(ctx, username)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		if existing != nil && existing.ID != 0 {
+			return ErrUserExist
+		}
 
-	user, err = s.UserRepository.GetByUsername(username)
+		if err := repos.Users.Create(ctx, user); err != nil {
+			return usernameTaken(err)
+		}
+
+		return recordEvent(ctx, repos.Outbox, UserRegistered{EventMeta: EventMeta{ActorID: user.ID, UserID: user.ID}, User: *user})
+	})
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
 	}
 
-	token, err := s.jwtHelper.CreateToken(int(user.ID))
+	user.Posts = &[]models.Post{}
+
+	recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+		Action:     models.AuditRegister,
+		ActorID:    &user.ID,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditID(user.ID),
+		After:      map[string]string{"username": user.Username, "name": user.Name},
+	})
+
+	token, err := startSession(ctx, s.SessionRepository, s.jwtHelper, user.ID, scopesForUser(user), sessionTTL, client)
 	if err != nil {
 		return nil, err
 	}
@@ -99,3 +137,44 @@ func (s *AuthService) Register(name string, username string, password string) (*
 
 	return &data, nil
 }
+
+// CreateScopedToken mints a token for an integration, restricted to `scopes` which have to be part of the
+// `grantedScopes` of the caller. The token gets its own session so it can be revoked like any other login.
+func (s *AuthService) CreateScopedToken(ctx context.Context, userId int, grantedScopes []string, scopes []string, expiresIn time.Duration, client ClientInfo) (string, error) {
+	fields := map[string][]string{}
+
+	if len(scopes) == 0 {
+		fields["scopes"] = []string{"at least one scope is required"}
+	} else if err := helper.ValidateScopes(scopes); err != nil {
+		fields["scopes"] = []string{err.Error()}
+	} else if !helper.ContainsScopes(grantedScopes, scopes) {
+		fields["scopes"] = []string{"can't grant scopes the current token doesn't have"}
+	}
+
+	if expiresIn == 0 {
+		expiresIn = scopedTokenTTL
+	} else if expiresIn < 0 || expiresIn > sessionTTL {
+		fields["expires_in"] = []string{fmt.Sprintf("must be between 1 and %d seconds", int(sessionTTL.Seconds()))}
+	}
+
+	if len(fields) > 0 {
+		return "", &ValidationError{Fields: fields}
+	}
+
+	return startSession(ctx, s.SessionRepository, s.jwtHelper, uint(userId), scopes, expiresIn, client)
+}
+
+// recordLoginFailure userId is 0 when the username doesn't exist, the attempted username is kept either way.
+func (s *AuthService) recordLoginFailure(ctx context.Context, username string, userId uint, client ClientInfo) {
+	event := models.AuditEvent{
+		Action:     models.AuditLoginFailed,
+		TargetType: models.AuditTargetUser,
+		After:      map[string]string{"username": truncate(username, auditSummaryLength)},
+	}
+
+	if userId != 0 {
+		event.TargetID = auditID(userId)
+	}
+
+	recordAudit(ctx, s.AuditRepository, client, event)
+}
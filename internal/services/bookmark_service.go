@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const collectionMaxLength = 64
+
+type BookmarkService struct {
+	BookmarkRepository repository.BookmarkRepo
+	PostRepository     repository.PostRepo
+}
+
+func NewBookmarkService(bookmarkRepo repository.BookmarkRepo, postRepo repository.PostRepo) *BookmarkService {
+	return &BookmarkService{
+		BookmarkRepository: bookmarkRepo,
+		PostRepository:     postRepo,
+	}
+}
+
+// Bookmark saves the post to read later, `collection` is optional. Bookmarking a post again moves it to `collection`.
+func (s *BookmarkService) Bookmark(ctx context.Context, userId int, postId int, collection string) error {
+	collection = strings.TrimSpace(collection)
+	if utf8.RuneCountInString(collection) > collectionMaxLength {
+		return &ValidationError{Fields: map[string][]string{
+			"collection": {fmt.Sprintf("must be at most %d characters", collectionMaxLength)},
+		}}
+	}
+
+	if _, err := s.PostRepository.GetById(ctx, postId); err != nil {
+		return err
+	}
+
+	if err := s.BookmarkRepository.Save(ctx, &models.Bookmark{UserID: uint(userId), PostID: uint(postId), Collection: collection}); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// RemoveBookmark removing a post that isn't bookmarked is not an error.
+func (s *BookmarkService) RemoveBookmark(ctx context.Context, userId int, postId int) error {
+	if err := s.BookmarkRepository.Delete(ctx, uint(userId), uint(postId)); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetBookmarks newest first, only the bookmarks of `collection` unless it is nil.
+func (s *BookmarkService) GetBookmarks(ctx context.Context, userId int, collection *string, beforeId uint, limit int) ([]models.Bookmark, error) {
+	bookmarks, err := s.BookmarkRepository.GetByUserId(ctx, uint(userId), collection, beforeId, pageLimit(limit))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return bookmarks, nil
+}
+
+// deletePost soft-deletes the post and removes its bookmarks, in a single transaction.
+func deletePost(ctx context.Context, transactor repository.Transactor, post *models.Post, actorId uint) error {
+	err := transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Posts.Delete(ctx, post.ID); err != nil {
+			return err
+		}
+
+		if err := repos.Bookmarks.DeleteByPostId(ctx, post.ID); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, repos.Outbox, PostDeleted{EventMeta: EventMeta{ActorID: actorId, UserID: post.UserID, PostID: post.ID}})
+	})
+	if err != nil {
+		logrus.WithField("post_id", post.ID).Error(err)
+		return err
+	}
+
+	return nil
+}
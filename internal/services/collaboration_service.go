@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	CollaborationSubscribe   = "subscribe"
+	CollaborationUnsubscribe = "unsubscribe"
+	CollaborationEdit        = "edit"
+	CollaborationTyping      = "typing"
+	CollaborationPresence    = "presence"
+	CollaborationError       = "error"
+)
+
+// collaborationClientBuffer messages queued for a client, a client falling further behind is disconnected.
+const collaborationClientBuffer = 32
+
+// CollaborationUser a user connected to a post.
+type CollaborationUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+}
+
+// CollaborationMessage the messages exchanged over the collaboration WebSocket, both ways.
+type CollaborationMessage struct {
+	Type   string `json:"type"`
+	PostID uint   `json:"post_id,omitempty"`
+	// User who sent an edit or typing message, set by the server.
+	User *CollaborationUser `json:"user,omitempty"`
+	// Users viewing the post, for presence messages.
+	Users  []CollaborationUser `json:"users,omitempty"`
+	Title  *string             `json:"title,omitempty"`
+	Body   *string             `json:"body,omitempty"`
+	Typing *bool               `json:"typing,omitempty"`
+	// Data the post, for post.updated and post.deleted messages.
+	Data    interface{} `json:"data,omitempty"`
+	Message string      `json:"message,omitempty"`
+}
+
+// CollaborationClient a WebSocket connection, Send is closed once the client is disconnected.
+type CollaborationClient struct {
+	User CollaborationUser
+	Send <-chan []byte
+	send chan []byte
+	// Slow whether the client got disconnected for not reading its messages fast enough.
+	Slow  bool
+	posts map[uint]bool
+}
+
+type collaborationRoom struct {
+	authorId uint
+	clients  map[*CollaborationClient]struct{}
+}
+
+// CollaborationHub fans the live edits, presence and typing indicators out to the clients viewing the same post.
+// Edits are drafts shared with the viewers, saving the post still goes through PUT /api/post/{id}.
+type CollaborationHub struct {
+	PostRepository repository.PostRepo
+
+	mu    sync.Mutex
+	rooms map[uint]*collaborationRoom
+}
+
+func NewCollaborationHub(postRepo repository.PostRepo) *CollaborationHub {
+	return &CollaborationHub{
+		PostRepository: postRepo,
+		rooms:          map[uint]*collaborationRoom{},
+	}
+}
+
+func (h *CollaborationHub) Connect(userId uint, username string) *CollaborationClient {
+	send := make(chan []byte, collaborationClientBuffer)
+
+	return &CollaborationClient{
+		User:  CollaborationUser{ID: userId, Username: username},
+		Send:  send,
+		send:  send,
+		posts: map[uint]bool{},
+	}
+}
+
+// Disconnect removes the client from every post it viewed, calling it again is a no-op.
+func (h *CollaborationHub) Disconnect(client *CollaborationClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.disconnect(client)
+}
+
+// Handle processes a message sent by the client, problems are reported to the client with an error message.
+func (h *CollaborationHub) Handle(ctx context.Context, client *CollaborationClient, raw []byte) {
+	var message CollaborationMessage
+	if err := json.Unmarshal(raw, &message); err != nil {
+		h.reply(client, CollaborationMessage{Type: CollaborationError, Message: "invalid message"})
+		return
+	}
+
+	switch message.Type {
+	case CollaborationSubscribe:
+		h.subscribe(ctx, client, message.PostID)
+	case CollaborationUnsubscribe:
+		h.mu.Lock()
+		h.leave(client, message.PostID)
+		h.mu.Unlock()
+	case CollaborationEdit:
+		h.edit(client, message)
+	case CollaborationTyping:
+		h.typing(client, message)
+	default:
+		h.reply(client, CollaborationMessage{Type: CollaborationError, PostID: message.PostID, Message: "unknown message type"})
+	}
+}
+
+// Publish pushes the saved changes of a post to its viewers.
+func (h *CollaborationHub) Publish(ctx context.Context, event DomainEvent) error {
+	switch event.(type) {
+	case PostUpdated, PostDeleted:
+	default:
+		return nil
+	}
+
+	postId := event.Meta().PostID
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.rooms[postId]
+	if !ok {
+		return nil
+	}
+
+	h.broadcast(room, nil, CollaborationMessage{Type: event.EventType(), PostID: postId, Data: event.Payload()})
+
+	if _, deleted := event.(PostDeleted); deleted {
+		for client := range room.clients {
+			delete(client.posts, postId)
+		}
+		delete(h.rooms, postId)
+	}
+
+	return nil
+}
+
+func (h *CollaborationHub) subscribe(ctx context.Context, client *CollaborationClient, postId uint) {
+	post, err := h.PostRepository.GetById(ctx, int(postId))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		h.reply(client, CollaborationMessage{Type: CollaborationError, PostID: postId, Message: "post not found"})
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if client.posts == nil {
+		// Disconnected while the post was loaded
+		return
+	}
+
+	room, ok := h.rooms[post.ID]
+	if !ok {
+		room = &collaborationRoom{authorId: post.UserID, clients: map[*CollaborationClient]struct{}{}}
+		h.rooms[post.ID] = room
+	}
+
+	room.clients[client] = struct{}{}
+	client.posts[post.ID] = true
+
+	h.broadcastPresence(post.ID, room)
+}
+
+func (h *CollaborationHub) edit(client *CollaborationClient, message CollaborationMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.joinedRoom(client, message.PostID)
+	if !ok {
+		return
+	}
+
+	if room.authorId != client.User.ID {
+		h.send(client, CollaborationMessage{Type: CollaborationError, PostID: message.PostID, Message: "only the author can edit the post"})
+		return
+	}
+
+	h.broadcast(room, client, CollaborationMessage{Type: CollaborationEdit, PostID: message.PostID, User: &client.User, Title: message.Title, Body: message.Body})
+}
+
+func (h *CollaborationHub) typing(client *CollaborationClient, message CollaborationMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room, ok := h.joinedRoom(client, message.PostID)
+	if !ok {
+		return
+	}
+
+	typing := message.Typing == nil || *message.Typing
+	h.broadcast(room, client, CollaborationMessage{Type: CollaborationTyping, PostID: message.PostID, User: &client.User, Typing: &typing})
+}
+
+// joinedRoom the room of the post, an error is sent when the client didn't subscribe to it.
+func (h *CollaborationHub) joinedRoom(client *CollaborationClient, postId uint) (*collaborationRoom, bool) {
+	room, ok := h.rooms[postId]
+	if !ok || !client.posts[postId] {
+		h.send(client, CollaborationMessage{Type: CollaborationError, PostID: postId, Message: "not subscribed to the post"})
+		return nil, false
+	}
+
+	return room, true
+}
+
+func (h *CollaborationHub) leave(client *CollaborationClient, postId uint) {
+	room, ok := h.rooms[postId]
+	if !ok || !client.posts[postId] {
+		return
+	}
+
+	delete(room.clients, client)
+	delete(client.posts, postId)
+
+	if len(room.clients) == 0 {
+		delete(h.rooms, postId)
+		return
+	}
+
+	h.broadcastPresence(postId, room)
+}
+
+func (h *CollaborationHub) disconnect(client *CollaborationClient) {
+	if client.posts == nil {
+		return
+	}
+
+	for postId := range client.posts {
+		h.leave(client, postId)
+	}
+
+	client.posts = nil
+	close(client.send)
+}
+
+// broadcastPresence sends the viewers of the post to every viewer, a user connected twice is listed once.
+func (h *CollaborationHub) broadcastPresence(postId uint, room *collaborationRoom) {
+	seen := map[uint]bool{}
+	users := []CollaborationUser{}
+
+	for client := range room.clients {
+		if !seen[client.User.ID] {
+			seen[client.User.ID] = true
+			users = append(users, client.User)
+		}
+	}
+
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	h.broadcast(room, nil, CollaborationMessage{Type: CollaborationPresence, PostID: postId, Users: users})
+}
+
+// broadcast sends the message to every client of the room but `except`.
+func (h *CollaborationHub) broadcast(room *collaborationRoom, except *CollaborationClient, message CollaborationMessage) {
+	for client := range room.clients {
+		if client != except {
+			h.send(client, message)
+		}
+	}
+}
+
+func (h *CollaborationHub) reply(client *CollaborationClient, message CollaborationMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.send(client, message)
+}
+
+// send never blocks, a client whose queue is full is disconnected instead of slowing everyone else down.
+func (h *CollaborationHub) send(client *CollaborationClient, message CollaborationMessage) {
+	if client.posts == nil {
+		return
+	}
+
+	data, err := json.Marshal(message)
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+
+	select {
+	case client.send <- data:
+	default:
+		client.Slow = true
+		h.disconnect(client)
+	}
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+)
+
+const (
+	EventUserRegistered      = "user.registered"
+	EventUserFollowed        = "user.followed"
+	EventUserUpdated         = "user.updated"
+	EventUserDeleted         = "user.deleted"
+	EventPostReacted         = "post.reacted"
+	EventPostCreated         = "post.created"
+	EventPostUpdated         = "post.updated"
+	EventPostDeleted         = "post.deleted"
+	EventNotificationCreated = "notification.created"
+)
+
+// DomainEvent something that happened in the services layer other parts of the app react to, there is one type per
+// event. The subscribers switch on the type of the event to get at its fields.
+type DomainEvent interface {
+	// EventType one of the Event constants.
+	EventType() string
+	// Meta the users and post the event involves.
+	Meta() EventMeta
+	// Payload sent as is to the stream clients and webhooks, nil when the event has none.
+	Payload() interface{}
+}
+
+// EventMeta the fields every event has.
+type EventMeta struct {
+	// ActorID the user who did the action.
+	ActorID uint `json:"actor_id"`
+	// UserID the user the action is about, the followed, updated or deleted user, the author of the post or the notified user.
+	UserID uint `json:"user_id"`
+	// PostID the post the action is about, 0 when it isn't about a post.
+	PostID uint `json:"post_id,omitempty"`
+}
+
+func (m EventMeta) Meta() EventMeta { return m }
+
+// UserRegistered a user signed up.
+type UserRegistered struct {
+	EventMeta
+	User models.User `json:"user"`
+}
+
+// UserFollowed the actor followed the user.
+type UserFollowed struct {
+	EventMeta
+}
+
+type UserUpdated struct {
+	EventMeta
+	User models.User `json:"user"`
+}
+
+type UserDeleted struct {
+	EventMeta
+}
+
+type PostCreated struct {
+	EventMeta
+	Post models.Post `json:"post"`
+}
+
+type PostUpdated struct {
+	EventMeta
+	Post models.Post `json:"post"`
+}
+
+// PostDeleted the actor is the author or the admin who deleted the post.
+type PostDeleted struct {
+	EventMeta
+}
+
+// PostReacted the actor reacted to the post with `Kind`.
+type PostReacted struct {
+	EventMeta
+	Kind string `json:"kind"`
+}
+
+// NotificationCreated the user got the notification.
+type NotificationCreated struct {
+	EventMeta
+	Notification models.Notification `json:"notification"`
+}
+
+func (UserRegistered) EventType() string      { return EventUserRegistered }
+func (UserFollowed) EventType() string        { return EventUserFollowed }
+func (UserUpdated) EventType() string         { return EventUserUpdated }
+func (UserDeleted) EventType() string         { return EventUserDeleted }
+func (PostCreated) EventType() string         { return EventPostCreated }
+func (PostUpdated) EventType() string         { return EventPostUpdated }
+func (PostDeleted) EventType() string         { return EventPostDeleted }
+func (PostReacted) EventType() string         { return EventPostReacted }
+func (NotificationCreated) EventType() string { return EventNotificationCreated }
+
+func (e UserRegistered) Payload() interface{}      { return e.User }
+func (UserFollowed) Payload() interface{}          { return nil }
+func (e UserUpdated) Payload() interface{}         { return e.User }
+func (e UserDeleted) Payload() interface{}         { return map[string]uint{"id": e.UserID} }
+func (e PostCreated) Payload() interface{}         { return e.Post }
+func (e PostUpdated) Payload() interface{}         { return e.Post }
+func (e PostDeleted) Payload() interface{}         { return map[string]uint{"id": e.PostID} }
+func (PostReacted) Payload() interface{}           { return nil }
+func (e NotificationCreated) Payload() interface{} { return e.Notification }
+
+// eventDecoders decode the data of the outbox events into the type of their event.
+var eventDecoders = map[string]func(data []byte) (DomainEvent, error){
+	EventUserRegistered:      decodeEvent[UserRegistered],
+	EventUserFollowed:        decodeEvent[UserFollowed],
+	EventUserUpdated:         decodeEvent[UserUpdated],
+	EventUserDeleted:         decodeEvent[UserDeleted],
+	EventPostCreated:         decodeEvent[PostCreated],
+	EventPostUpdated:         decodeEvent[PostUpdated],
+	EventPostDeleted:         decodeEvent[PostDeleted],
+	EventPostReacted:         decodeEvent[PostReacted],
+	EventNotificationCreated: decodeEvent[NotificationCreated],
+}
+
+func decodeEvent[T DomainEvent](data []byte) (DomainEvent, error) {
+	var event T
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, err
+	}
+
+	return event, nil
+}
+
+// EventPublisher receives the domain events. The events are published once the action that raised them is committed,
+// an error leaves the event in the outbox so it is published again later.
+type EventPublisher interface {
+	Publish(ctx context.Context, event DomainEvent) error
+}
+
+// EventBus routes the events to the subscribers of their type, in the order they subscribed.
+type EventBus struct {
+	mu            sync.RWMutex
+	subscriptions []eventSubscription
+}
+
+type eventSubscription struct {
+	// name tells the subscribers apart in the receipts of the outbox events, it must not change between releases.
+	name       string
+	subscriber EventPublisher
+	// types the subscriber receives, every type when empty.
+	types map[string]bool
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe the subscriber, known by `name`, receives the events of the given types, or every event when no type is given.
+func (b *EventBus) Subscribe(name string, subscriber EventPublisher, types ...string) {
+	subscription := eventSubscription{name: name, subscriber: subscriber, types: map[string]bool{}}
+	for _, eventType := range types {
+		subscription.types[eventType] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subscriptions = append(b.subscriptions, subscription)
+}
+
+// Publish a subscriber failing doesn't keep the following ones from receiving the event, the errors are returned together.
+func (b *EventBus) Publish(ctx context.Context, event DomainEvent) error {
+	return b.PublishExcept(ctx, event, nil, func(string) {})
+}
+
+// PublishExcept publishes the event like Publish, leaving out the subscribers named in `received`. `onReceived` is
+// called with the name of each subscriber the event was published to without an error.
+func (b *EventBus) PublishExcept(ctx context.Context, event DomainEvent, received []string, onReceived func(subscriber string)) error {
+	b.mu.RLock()
+	subscriptions := b.subscriptions
+	b.mu.RUnlock()
+
+	var errs []error
+	for _, subscription := range subscriptions {
+		if (len(subscription.types) > 0 && !subscription.types[event.EventType()]) || slices.Contains(received, subscription.name) {
+			continue
+		}
+
+		if err := subscription.subscriber.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		onReceived(subscription.name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// recordEvent stores the event in the outbox, along with the change it is about when `outbox` is bound to a transaction.
+// The ids are kept in their columns as well, so the outbox can be looked up by user or post.
+func recordEvent(ctx context.Context, outbox repository.OutboxRepo, event DomainEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	meta := event.Meta()
+	outboxEvent := models.OutboxEvent{
+		Type:    event.EventType(),
+		ActorID: meta.ActorID,
+		UserID:  meta.UserID,
+		PostID:  meta.PostID,
+		Data:    string(data),
+	}
+
+	if reacted, ok := event.(PostReacted); ok {
+		outboxEvent.Kind = reacted.Kind
+	}
+
+	return outbox.Add(ctx, &outboxEvent)
+}
+
+// outboxDomainEvent the event stored by recordEvent.
+func outboxDomainEvent(outboxEvent models.OutboxEvent) (DomainEvent, error) {
+	decode, ok := eventDecoders[outboxEvent.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown event type '%s'", outboxEvent.Type)
+	}
+
+	return decode([]byte(outboxEvent.Data))
+}
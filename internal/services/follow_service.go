@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type FollowService struct {
+	FollowRepository   repository.FollowRepo
+	UserRepository     repository.UserRepo
+	PostRepository     repository.PostRepo
+	ReactionRepository repository.ReactionRepo
+	Transactor         repository.Transactor
+}
+
+func NewFollowService(followRepo repository.FollowRepo, userRepo repository.UserRepo, postRepo repository.PostRepo, reactionRepo repository.ReactionRepo, transactor repository.Transactor) *FollowService {
+	return &FollowService{
+		FollowRepository:   followRepo,
+		UserRepository:     userRepo,
+		PostRepository:     postRepo,
+		ReactionRepository: reactionRepo,
+		Transactor:         transactor,
+	}
+}
+
+// Follow following the same user twice is not an error.
+func (s *FollowService) Follow(ctx context.Context, followerId int, username string) error {
+	followee, err := s.followee(ctx, followerId, username)
+	if err != nil {
+		return err
+	}
+
+	err = s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		created, err := repos.Follows.Create(ctx, &models.Follow{FollowerID: uint(followerId), FolloweeID: followee.ID})
+		if err != nil || !created {
+			return err
+		}
+
+		return recordEvent(ctx, repos.Outbox, UserFollowed{EventMeta: EventMeta{ActorID: uint(followerId), UserID: followee.ID}})
+	})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Unfollow unfollowing a user who isn't followed is not an error.
+func (s *FollowService) Unfollow(ctx context.Context, followerId int, username string) error {
+	followee, err := s.followee(ctx, followerId, username)
+	if err != nil {
+		return err
+	}
+
+	if err = s.FollowRepository.Delete(ctx, uint(followerId), followee.ID); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *FollowService) GetFollowers(ctx context.Context, username string, beforeId uint, limit int) ([]models.Follow, error) {
+	user, err := findUser(ctx, s.UserRepository, username)
+	if err != nil {
+		return nil, err
+	}
+
+	follows, err := s.FollowRepository.GetFollowers(ctx, user.ID, beforeId, pageLimit(limit))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return follows, nil
+}
+
+func (s *FollowService) GetFollowing(ctx context.Context, username string, beforeId uint, limit int) ([]models.Follow, error) {
+	user, err := findUser(ctx, s.UserRepository, username)
+	if err != nil {
+		return nil, err
+	}
+
+	follows, err := s.FollowRepository.GetFollowing(ctx, user.ID, beforeId, pageLimit(limit))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return follows, nil
+}
+
+// GetFeed posts of the users followed by `userId`, newest first. Pass the id of the last post as `beforeId` to get the next page.
+func (s *FollowService) GetFeed(ctx context.Context, userId int, beforeId uint, limit int) ([]models.Post, error) {
+	posts, err := s.PostRepository.GetFeed(ctx, uint(userId), beforeId, pageLimit(limit))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if err = attachReactions(ctx, s.ReactionRepository, posts, uint(userId)); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func (s *FollowService) followee(ctx context.Context, followerId int, username string) (*models.User, error) {
+	followee, err := findUser(ctx, s.UserRepository, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if followee.ID == uint(followerId) {
+		return nil, &ValidationError{Fields: map[string][]string{"username": {"can't follow yourself"}}}
+	}
+
+	return followee, nil
+}
+
+func findUser(ctx context.Context, userRepo repository.UserRepo, username string) (*models.User, error) {
+	user, err := userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithField("username", username).Error("User doesn't exist")
+		} else {
+			logrus.Error(err)
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// pageLimit the page size to use when `limit` is not given or too large.
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageLimit
+	} else if limit > maxPageLimit {
+		return maxPageLimit
+	}
+
+	return limit
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/database"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type HealthService struct {
+	db       *gorm.DB
+	replicas *database.Replicas
+}
+
+// NewHealthService `replicas` may be nil.
+func NewHealthService(db *gorm.DB, replicas *database.Replicas) *HealthService {
+	return &HealthService{
+		db:       db,
+		replicas: replicas,
+	}
+}
+
+// Check pings the primary database, the replicas are reported as of their last health check which doesn't wait on
+// the ones that are down.
+func (s *HealthService) Check(ctx context.Context) api.HealthResponse {
+	var (
+		response = api.HealthResponse{Status: api.HealthOK}
+		primary  = api.DatabaseHealth{Name: "primary", Healthy: true}
+	)
+
+	if err := database.Ping(ctx, s.db); err != nil {
+		logrus.Error(err)
+		primary.Healthy = false
+		response.Status = api.HealthUnavailable
+	}
+
+	if stats, err := database.PoolStats(s.db); err == nil {
+		primary.Pool = poolStats(stats)
+	}
+	response.Databases = append(response.Databases, primary)
+
+	for _, replica := range s.replicas.Status() {
+		if !replica.Healthy && response.Status == api.HealthOK {
+			response.Status = api.HealthDegraded
+		}
+
+		response.Databases = append(response.Databases, api.DatabaseHealth{Name: replica.Name, Healthy: replica.Healthy, Pool: poolStats(replica.Pool)})
+	}
+
+	return response
+}
+
+func poolStats(stats sql.DBStats) api.PoolStats {
+	return api.PoolStats{
+		MaxOpen:           stats.MaxOpenConnections,
+		Open:              stats.OpenConnections,
+		InUse:             stats.InUse,
+		Idle:              stats.Idle,
+		WaitCount:         stats.WaitCount,
+		WaitDurationMs:    stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:     stats.MaxIdleClosed,
+		MaxIdleTimeClosed: stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed: stats.MaxLifetimeClosed,
+	}
+}
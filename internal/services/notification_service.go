@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type NotificationService struct {
+	NotificationRepository repository.NotificationRepo
+	Transactor             repository.Transactor
+}
+
+func NewNotificationService(notificationRepo repository.NotificationRepo, transactor repository.Transactor) *NotificationService {
+	return &NotificationService{
+		NotificationRepository: notificationRepo,
+		Transactor:             transactor,
+	}
+}
+
+// Publish notifies the user the event is about, unless they did the action themselves or turned the type off.
+func (s *NotificationService) Publish(ctx context.Context, event DomainEvent) error {
+	var notificationType string
+	switch event.(type) {
+	case UserFollowed:
+		notificationType = models.NotificationFollow
+	case PostReacted:
+		notificationType = models.NotificationReaction
+	default:
+		return nil
+	}
+
+	meta := event.Meta()
+	if meta.UserID == 0 || meta.UserID == meta.ActorID {
+		return nil
+	}
+
+	enabled, err := s.isEnabled(ctx, meta.UserID, notificationType)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	notification := models.Notification{
+		UserID:  meta.UserID,
+		Type:    notificationType,
+		ActorID: &meta.ActorID,
+	}
+
+	if meta.PostID != 0 {
+		notification.PostID = &meta.PostID
+	}
+
+	return s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Notifications.Create(ctx, &notification); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, repos.Outbox, NotificationCreated{EventMeta: meta, Notification: notification})
+	})
+}
+
+// GetNotifications newest first, along with the number of unread notifications.
+func (s *NotificationService) GetNotifications(ctx context.Context, userId int, unreadOnly bool, beforeId uint, limit int) (*api.NotificationsResponse, error) {
+	notifications, err := s.NotificationRepository.GetByUserId(ctx, uint(userId), unreadOnly, beforeId, pageLimit(limit))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	unread, err := s.NotificationRepository.CountUnread(ctx, uint(userId))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &api.NotificationsResponse{Notifications: notifications, UnreadCount: unread}, nil
+}
+
+func (s *NotificationService) MarkRead(ctx context.Context, userId int, notificationId int) error {
+	notification, err := s.NotificationRepository.GetById(ctx, uint(notificationId))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return err
+	}
+
+	if notification.UserID != uint(userId) {
+		return gorm.ErrRecordNotFound
+	}
+
+	if err = s.NotificationRepository.MarkRead(ctx, notification.ID, time.Now()); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *NotificationService) MarkAllRead(ctx context.Context, userId int) error {
+	if err := s.NotificationRepository.MarkAllRead(ctx, uint(userId), time.Now()); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetPreferences whether each notification type is enabled.
+func (s *NotificationService) GetPreferences(ctx context.Context, userId int) (map[string]bool, error) {
+	stored, err := s.NotificationRepository.GetPreferences(ctx, uint(userId))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	preferences := make(map[string]bool, len(models.NotificationTypes))
+	for _, notificationType := range models.NotificationTypes {
+		preferences[notificationType] = true
+	}
+
+	for _, preference := range stored {
+		if _, ok := preferences[preference.Type]; ok {
+			preferences[preference.Type] = preference.Enabled
+		}
+	}
+
+	return preferences, nil
+}
+
+func (s *NotificationService) SetPreference(ctx context.Context, userId int, notificationType string, enabled bool) error {
+	if !slices.Contains(models.NotificationTypes, notificationType) {
+		return &ValidationError{Fields: map[string][]string{
+			"type": {fmt.Sprintf("must be one of %v", strings.Join(models.NotificationTypes, ", "))},
+		}}
+	}
+
+	preference := models.NotificationPreference{UserID: uint(userId), Type: notificationType, Enabled: enabled}
+	if err := s.NotificationRepository.SavePreference(ctx, &preference); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *NotificationService) isEnabled(ctx context.Context, userId uint, notificationType string) (bool, error) {
+	preferences, err := s.NotificationRepository.GetPreferences(ctx, userId)
+	if err != nil {
+		return false, err
+	}
+
+	for _, preference := range preferences {
+		if preference.Type == notificationType {
+			return preference.Enabled, nil
+		}
+	}
+
+	return true, nil
+}
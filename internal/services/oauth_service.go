@@ -0,0 +1,547 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	oauthCodeTTL        = 10 * time.Minute
+	oauthAccessTokenTTL = time.Hour
+
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// OAuthError an error reported to the client with one of the RFC 6749 error codes.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+var (
+	ErrOAuthInvalidClient = &OAuthError{"invalid_client", "Client authentication failed"}
+	ErrOAuthInvalidGrant  = &OAuthError{"invalid_grant", "The authorization code is invalid, expired or was already used"}
+)
+
+// AuthorizationRequest the parameters of an authorization code request (RFC 6749 section 4.1.1 and RFC 7636).
+type AuthorizationRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scopes              []string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	Scopes       []string
+}
+
+type OAuthService struct {
+	OAuthRepository repository.OAuthRepo
+	jwtHelper       helper.JWTHelper
+}
+
+func NewOAuthService(oauthRepo repository.OAuthRepo, jwtHelper helper.JWTHelper) *OAuthService {
+	return &OAuthService{
+		OAuthRepository: oauthRepo,
+		jwtHelper:       jwtHelper,
+	}
+}
+
+// RegisterClient `scopes` have to be part of the `grantedScopes` of the caller, the client credentials grant issues
+// tokens on their behalf.
+func (s *OAuthService) RegisterClient(ctx context.Context, userId int, grantedScopes []string, name string, redirectURIs []string, scopes []string, confidential bool) (*api.OAuthClientCreatedResponse, error) {
+	fields := map[string][]string{}
+
+	if name == "" {
+		fields["name"] = []string{"is required"}
+	}
+
+	if len(redirectURIs) == 0 {
+		fields["redirect_uris"] = []string{"at least one redirect URI is required"}
+	}
+
+	for _, uri := range redirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			fields["redirect_uris"] = append(fields["redirect_uris"], "'"+uri+"' must be an absolute URI without fragment")
+		}
+	}
+
+	if len(scopes) == 0 {
+		fields["scopes"] = []string{"at least one scope is required"}
+	} else if err := helper.ValidateScopes(scopes); err != nil {
+		fields["scopes"] = []string{err.Error()}
+	} else if !helper.ContainsScopes(grantedScopes, scopes) {
+		fields["scopes"] = []string{"can't grant scopes the current token doesn't have"}
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	clientId, err := helper.RandomToken(18)
+	if err != nil {
+		return nil, err
+	}
+
+	client := models.OAuthClient{
+		ClientID:     clientId,
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Scopes:       scopes,
+		Confidential: confidential,
+		UserID:       uint(userId),
+	}
+
+	var secret string
+	if confidential {
+		secret, err = helper.RandomToken(32)
+		if err != nil {
+			return nil, err
+		}
+
+		client.SecretHash = helper.HashToken(secret)
+	}
+
+	if err = s.OAuthRepository.CreateClient(ctx, &client); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &api.OAuthClientCreatedResponse{ClientSecret: secret, Client: &client}, nil
+}
+
+func (s *OAuthService) GetClients(ctx context.Context, userId int) ([]models.OAuthClient, error) {
+	clients, err := s.OAuthRepository.GetClientsByUserId(ctx, uint(userId))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return clients, nil
+}
+
+// DeleteClient deletes a client owned by the user, with its consents and tokens.
+func (s *OAuthService) DeleteClient(ctx context.Context, userId int, clientId string) error {
+	client, err := s.OAuthRepository.GetClientByClientId(ctx, clientId)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return err
+	}
+
+	if client.UserID != uint(userId) {
+		return gorm.ErrRecordNotFound
+	}
+
+	if err = s.OAuthRepository.DeleteClient(ctx, client.ID); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// DescribeAuthorization validates the request and returns what the user is asked to consent to.
+func (s *OAuthService) DescribeAuthorization(ctx context.Context, userId int, grantedScopes []string, req AuthorizationRequest) (*api.OAuthAuthorizationResponse, error) {
+	client, scopes, err := s.validateAuthorization(ctx, grantedScopes, req)
+	if err != nil {
+		return nil, err
+	}
+
+	consent, err := s.OAuthRepository.GetConsent(ctx, uint(userId), client.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &api.OAuthAuthorizationResponse{
+		Client:         client,
+		Scopes:         scopes,
+		ConsentGranted: err == nil && helper.ContainsScopes(consent.Scopes, scopes),
+	}, nil
+}
+
+// Authorize records the decision of the user and returns the URL to redirect the user agent to,
+// carrying either the authorization code or the `access_denied` error. The user can only approve scopes of their
+// `grantedScopes`.
+func (s *OAuthService) Authorize(ctx context.Context, userId int, grantedScopes []string, req AuthorizationRequest, approved bool) (string, error) {
+	client, scopes, err := s.validateAuthorization(ctx, grantedScopes, req)
+	if err != nil {
+		return "", err
+	}
+
+	if !approved {
+		return redirectWith(req.RedirectURI, map[string]string{"error": "access_denied", "state": req.State}), nil
+	}
+
+	consent, err := s.OAuthRepository.GetConsent(ctx, uint(userId), client.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logrus.Error(err)
+		return "", err
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		consent = &models.OAuthConsent{UserID: uint(userId), OAuthClientID: client.ID}
+	}
+
+	for _, scope := range scopes {
+		if !helper.ContainsScopes(consent.Scopes, []string{scope}) {
+			consent.Scopes = append(consent.Scopes, scope)
+		}
+	}
+
+	if err = s.OAuthRepository.SaveConsent(ctx, consent); err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	code, err := helper.RandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.OAuthRepository.CreateCode(ctx, &models.OAuthAuthorizationCode{
+		CodeHash:      helper.HashToken(code),
+		OAuthClientID: client.ID,
+		UserID:        uint(userId),
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oauthCodeTTL),
+	})
+	if err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	return redirectWith(req.RedirectURI, map[string]string{"code": code, "state": req.State}), nil
+}
+
+// Token implements the token endpoint for the authorization code and client credentials grants.
+func (s *OAuthService) Token(ctx context.Context, req TokenRequest) (*api.OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		return s.exchangeCode(ctx, client, req)
+	case GrantTypeClientCredentials:
+		if !client.Confidential {
+			return nil, &OAuthError{"unauthorized_client", "Public clients can't use the client credentials grant"}
+		}
+
+		scopes := req.Scopes
+		if len(scopes) == 0 {
+			scopes = client.Scopes
+		}
+
+		if !helper.ContainsScopes(client.Scopes, scopes) {
+			return nil, &OAuthError{"invalid_scope", "The client isn't allowed to request those scopes"}
+		}
+
+		// Client credentials tokens act on behalf of the user who registered the client
+		return s.issueToken(ctx, client, client.UserID, scopes)
+	default:
+		return nil, &OAuthError{"unsupported_grant_type", "Supported grant types are authorization_code and client_credentials"}
+	}
+}
+
+// Introspect describes a token issued to the authenticated client, tokens of other clients are reported inactive.
+func (s *OAuthService) Introspect(ctx context.Context, clientId string, clientSecret string, token string) (*api.OAuthIntrospectionResponse, error) {
+	client, err := s.authenticateClient(ctx, clientId, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	inactive := &api.OAuthIntrospectionResponse{Active: false}
+
+	claims, err := s.jwtHelper.ParseClaims(token)
+	if err != nil || claims.ClientID != client.ClientID || claims.TokenID == "" {
+		return inactive, nil
+	}
+
+	revoked, err := s.IsTokenRevoked(ctx, claims.TokenID)
+	if err != nil {
+		return nil, err
+	}
+
+	if revoked {
+		return inactive, nil
+	}
+
+	return &api.OAuthIntrospectionResponse{
+		Active:    true,
+		Scope:     strings.Join(claims.Scopes, " "),
+		ClientID:  claims.ClientID,
+		Subject:   claims.UserID,
+		TokenType: "Bearer",
+		ExpiresAt: claims.ExpiresAt.Unix(),
+		IssuedAt:  claims.IssuedAt.Unix(),
+	}, nil
+}
+
+// Revoke revokes a token issued to the authenticated client, unknown or invalid tokens are ignored (RFC 7009 section 2.2).
+func (s *OAuthService) Revoke(ctx context.Context, clientId string, clientSecret string, token string) error {
+	client, err := s.authenticateClient(ctx, clientId, clientSecret)
+	if err != nil {
+		return err
+	}
+
+	claims, err := s.jwtHelper.ParseClaims(token)
+	if err != nil || claims.ClientID != client.ClientID || claims.TokenID == "" {
+		return nil
+	}
+
+	if err = s.OAuthRepository.RevokeToken(ctx, claims.TokenID, time.Now()); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// IsTokenRevoked reports whether the token issued by the authorization server was revoked,
+// tokens this server doesn't know about are considered revoked.
+func (s *OAuthService) IsTokenRevoked(ctx context.Context, tokenId string) (bool, error) {
+	token, err := s.OAuthRepository.GetTokenByTokenId(ctx, tokenId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+
+		logrus.Error(err)
+		return false, err
+	}
+
+	return token.RevokedAt != nil, nil
+}
+
+func (s *OAuthService) GetConsents(ctx context.Context, userId int) ([]models.OAuthConsent, error) {
+	consents, err := s.OAuthRepository.GetConsentsByUserId(ctx, uint(userId))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return consents, nil
+}
+
+// RevokeConsent withdraws the consent given to a client and revokes the tokens it got from it.
+func (s *OAuthService) RevokeConsent(ctx context.Context, userId int, clientId string) error {
+	client, err := s.OAuthRepository.GetClientByClientId(ctx, clientId)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return err
+	}
+
+	consent, err := s.OAuthRepository.GetConsent(ctx, uint(userId), client.ID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return err
+	}
+
+	if err = s.OAuthRepository.DeleteConsent(ctx, consent.ID); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	if err = s.OAuthRepository.RevokeTokensByUserClient(ctx, uint(userId), client.ID, time.Now()); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *OAuthService) validateAuthorization(ctx context.Context, grantedScopes []string, req AuthorizationRequest) (*models.OAuthClient, []string, error) {
+	client, err := s.OAuthRepository.GetClientByClientId(ctx, req.ClientID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, &OAuthError{"invalid_request", "Unknown client_id"}
+		}
+
+		logrus.Error(err)
+		return nil, nil, err
+	}
+
+	// The redirect URI has to be checked before anything else, errors can't be redirected to an unverified URI
+	registered := false
+	for _, uri := range client.RedirectURIs {
+		if uri == req.RedirectURI {
+			registered = true
+			break
+		}
+	}
+
+	if !registered {
+		return nil, nil, &OAuthError{"invalid_request", "redirect_uri isn't registered for this client"}
+	}
+
+	if req.ResponseType != "code" {
+		return nil, nil, &OAuthError{"unsupported_response_type", "Only the code response type is supported"}
+	}
+
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, nil, &OAuthError{"invalid_request", "PKCE with the S256 code challenge method is required"}
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = client.Scopes
+	}
+
+	if !helper.ContainsScopes(client.Scopes, scopes) {
+		return nil, nil, &OAuthError{"invalid_scope", "The client isn't allowed to request those scopes"}
+	}
+
+	// Clients registered before the scopes were checked could still ask for scopes that can't be delegated
+	if helper.ValidateScopes(scopes) != nil || !helper.ContainsScopes(grantedScopes, scopes) {
+		return nil, nil, &OAuthError{"invalid_scope", "The current token can't grant those scopes"}
+	}
+
+	return client, scopes, nil
+}
+
+func (s *OAuthService) authenticateClient(ctx context.Context, clientId string, clientSecret string) (*models.OAuthClient, error) {
+	if clientId == "" {
+		return nil, ErrOAuthInvalidClient
+	}
+
+	client, err := s.OAuthRepository.GetClientByClientId(ctx, clientId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidClient
+		}
+
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if client.Confidential && !helper.CompareTokenHash(client.SecretHash, clientSecret) {
+		return nil, ErrOAuthInvalidClient
+	}
+
+	return client, nil
+}
+
+func (s *OAuthService) exchangeCode(ctx context.Context, client *models.OAuthClient, req TokenRequest) (*api.OAuthTokenResponse, error) {
+	code, err := s.OAuthRepository.GetCodeByHash(ctx, helper.HashToken(req.Code))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOAuthInvalidGrant
+		}
+
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if code.OAuthClientID != client.ID || code.RedirectURI != req.RedirectURI || time.Now().After(code.ExpiresAt) {
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	challenge := sha256.Sum256([]byte(req.CodeVerifier))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != code.CodeChallenge {
+		return nil, &OAuthError{"invalid_grant", "code_verifier doesn't match the code challenge"}
+	}
+
+	used, err := s.OAuthRepository.MarkCodeUsed(ctx, code.ID, time.Now())
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if !used {
+		// The code leaked or is replayed, revoke what was issued with it (RFC 6749 section 4.1.2)
+		if err = s.OAuthRepository.RevokeTokensByUserClient(ctx, code.UserID, client.ID, time.Now()); err != nil {
+			logrus.Error(err)
+		}
+
+		return nil, ErrOAuthInvalidGrant
+	}
+
+	return s.issueToken(ctx, client, code.UserID, code.Scopes)
+}
+
+func (s *OAuthService) issueToken(ctx context.Context, client *models.OAuthClient, userId uint, scopes []string) (*api.OAuthTokenResponse, error) {
+	tokenId, err := helper.RandomToken(18)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.jwtHelper.CreateTokenWithOptions(int(userId), helper.TokenOptions{
+		Scopes:   scopes,
+		ClientID: client.ClientID,
+		TokenID:  tokenId,
+		TTL:      oauthAccessTokenTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.OAuthRepository.CreateToken(ctx, &models.OAuthToken{
+		TokenID:       tokenId,
+		OAuthClientID: client.ID,
+		UserID:        userId,
+		Scopes:        scopes,
+		ExpiresAt:     time.Now().Add(oauthAccessTokenTTL),
+	})
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &api.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(oauthAccessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+func redirectWith(redirectURI string, params map[string]string) string {
+	u, _ := url.Parse(redirectURI)
+
+	query := u.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
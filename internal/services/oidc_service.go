@@ -0,0 +1,369 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+)
+
+// OIDCLoginTTL how long the user has to come back from the provider after starting a login.
+const OIDCLoginTTL = 10 * time.Minute
+
+var (
+	ErrInvalidOIDCState      = errors.New("The login request is invalid or has expired, please try again")
+	ErrIdentityAlreadyLinked = errors.New("This account is already linked to another user")
+)
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+type OIDCConfig struct {
+	ProviderName string
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// oidcLogin what we need to remember between redirecting the user to the provider and the callback.
+type oidcLogin struct {
+	verifier   string
+	nonce      string
+	linkUserId uint
+	expiresAt  time.Time
+}
+
+type oidcClaims struct {
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+type OIDCService struct {
+	IdentityRepository repository.IdentityRepo
+	UserRepository     repository.UserRepo
+	SessionRepository  repository.SessionRepo
+	AuditRepository    repository.AuditRepo
+	PasswordCrypto     helper.PasswordCrypto
+	Transactor         repository.Transactor
+	jwtHelper          helper.JWTHelper
+
+	providerName string
+	oauth2Config oauth2.Config
+	verifier     *oidc.IDTokenVerifier
+
+	// logins pending logins keyed by their `state`, kept in memory so a login has to
+	// come back to the same instance that started it.
+	logins   map[string]oidcLogin
+	loginsMu sync.Mutex
+	// stateKey signs the state handed to the browser that started a login, it only has to outlive the pending logins.
+	stateKey []byte
+}
+
+// NewOIDCService fetches the provider discovery document, so the provider has to be reachable.
+func NewOIDCService(config OIDCConfig, identityRepo repository.IdentityRepo, userRepo repository.UserRepo, sessionRepo repository.SessionRepo, auditRepo repository.AuditRepo, passwordCrypto helper.PasswordCrypto, jwtHelper helper.JWTHelper, transactor repository.Transactor) (*OIDCService, error) {
+	provider, err := oidc.NewProvider(context.Background(), config.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+
+	stateKey := make([]byte, 32)
+	if _, err = rand.Read(stateKey); err != nil {
+		return nil, err
+	}
+
+	return &OIDCService{
+		IdentityRepository: identityRepo,
+		UserRepository:     userRepo,
+		SessionRepository:  sessionRepo,
+		AuditRepository:    auditRepo,
+		PasswordCrypto:     passwordCrypto,
+		Transactor:         transactor,
+		jwtHelper:          jwtHelper,
+		providerName:       config.ProviderName,
+		oauth2Config: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+		logins:   map[string]oidcLogin{},
+		stateKey: stateKey,
+	}, nil
+}
+
+// AuthCodeURL starts an authorization code + PKCE login and returns the provider URL the user has to visit, along
+// with the signed state the browser has to send back with the callback (in a cookie) so nobody else can finish the
+// login. When linkUserId isn't 0 the external account gets linked to that user instead of logging in.
+func (s *OIDCService) AuthCodeURL(linkUserId int) (authURL string, signedState string, err error) {
+	state, err := helper.RandomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := helper.RandomToken(24)
+	if err != nil {
+		return "", "", err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+
+	s.loginsMu.Lock()
+	now := time.Now()
+	for key, login := range s.logins {
+		if now.After(login.expiresAt) {
+			delete(s.logins, key)
+		}
+	}
+	s.logins[state] = oidcLogin{
+		verifier:   verifier,
+		nonce:      nonce,
+		linkUserId: uint(linkUserId),
+		expiresAt:  now.Add(OIDCLoginTTL),
+	}
+	s.loginsMu.Unlock()
+
+	authURL = s.oauth2Config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+	return authURL, state + "." + s.signState(state), nil
+}
+
+func (s *OIDCService) signState(state string) string {
+	mac := hmac.New(sha256.New, s.stateKey)
+	mac.Write([]byte(state))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyState whether `signedState` was returned by AuthCodeURL for `state`.
+func (s *OIDCService) verifyState(state string, signedState string) bool {
+	cookieState, signature, ok := strings.Cut(signedState, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signState(cookieState))) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookieState), []byte(state)) == 1
+}
+
+// HandleCallback exchanges the authorization code, creating the user on the first login
+// (or linking the account when the login was started with a user to link), and returns our own token.
+// `signedState` is the one AuthCodeURL returned to the browser that started the login.
+func (s *OIDCService) HandleCallback(ctx context.Context, state string, signedState string, code string, client ClientInfo) (*api.RegisterSuccessResponse, error) {
+	// Someone else's login finished in this browser would link the wrong account, or log in as someone else
+	if !s.verifyState(state, signedState) {
+		return nil, ErrInvalidOIDCState
+	}
+
+	s.loginsMu.Lock()
+	login, ok := s.logins[state]
+	delete(s.logins, state)
+	s.loginsMu.Unlock()
+
+	if !ok || time.Now().After(login.expiresAt) {
+		return nil, ErrInvalidOIDCState
+	}
+
+	oauth2Token, err := s.oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(login.verifier))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("the provider didn't return an id_token")
+	}
+
+	idToken, err := s.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if idToken.Nonce != login.nonce {
+		return nil, ErrInvalidOIDCState
+	}
+
+	var claims oidcClaims
+	if err = idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userForIdentity(ctx, login.linkUserId, idToken.Subject, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := startSession(ctx, s.SessionRepository, s.jwtHelper, user.ID, scopesForUser(user), sessionTTL, client)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+		Action:     models.AuditLogin,
+		ActorID:    &user.ID,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditID(user.ID),
+		After:      map[string]string{"provider": s.providerName},
+	})
+
+	return &api.RegisterSuccessResponse{
+		Token: token,
+		User:  user,
+	}, nil
+}
+
+func (s *OIDCService) GetIdentities(ctx context.Context, userId int) ([]models.Identity, error) {
+	identities, err := s.IdentityRepository.GetAllByUserId(ctx, uint(userId))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return identities, nil
+}
+
+func (s *OIDCService) userForIdentity(ctx context.Context, linkUserId uint, subject string, claims oidcClaims) (*models.User, error) {
+	identity, err := s.IdentityRepository.GetByProviderSubject(ctx, s.providerName, subject)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	if err == nil {
+		return s.identityUser(ctx, linkUserId, identity)
+	}
+
+	// A user created without its identity couldn't log in
+	var user *models.User
+	err = s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		var err error
+		if linkUserId != 0 {
+			user, err = repos.Users.GetById(ctx, linkUserId)
+		} else {
+			user, err = s.createUser(ctx, repos.Users, claims)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		return repos.Identities.Create(ctx, &models.Identity{
+			UserID:   user.ID,
+			Provider: s.providerName,
+			Subject:  subject,
+			Email:    claims.Email,
+		})
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		// A concurrent first login of the same account linked it first, its user is logged in instead
+		if identity, lookupErr := s.IdentityRepository.GetByProviderSubject(ctx, s.providerName, subject); lookupErr == nil {
+			return s.identityUser(ctx, linkUserId, identity)
+		}
+	}
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// identityUser the user the identity is linked to, which has to be the user linking it when `linkUserId` is set.
+func (s *OIDCService) identityUser(ctx context.Context, linkUserId uint, identity *models.Identity) (*models.User, error) {
+	if linkUserId != 0 && identity.UserID != linkUserId {
+		return nil, ErrIdentityAlreadyLinked
+	}
+
+	return s.UserRepository.GetById(ctx, identity.UserID)
+}
+
+// createUser registers a user for an external account, the password is random because
+// the user is only ever going to log in through the provider (until they set one).
+func (s *OIDCService) createUser(ctx context.Context, users repository.UserRepo, claims oidcClaims) (*models.User, error) {
+	username, err := s.availableUsername(ctx, users, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	password, err := helper.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := s.PasswordCrypto.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	name := claims.Name
+	if name == "" {
+		name = username
+	}
+
+	err = users.Create(ctx, &models.User{
+		Name:     name,
+		Username: username,
+		Password: hashedPassword,
+	})
+	if err != nil {
+		return nil, usernameTaken(err)
+	}
+
+	return users.GetByUsername(ctx, username)
+}
+
+func (s *OIDCService) availableUsername(ctx context.Context, users repository.UserRepo, claims oidcClaims) (string, error) {
+	base := claims.PreferredUsername
+	if base == "" {
+		base, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	base = usernameInvalidChars.ReplaceAllString(base, "")
+	if base == "" {
+		base = "user"
+	}
+
+	username := base
+	for attempt := 0; attempt < 5; attempt++ {
+		user, err := users.GetByUsername(ctx, username)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return username, nil
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if user == nil || user.ID == 0 {
+			return username, nil
+		}
+
+		suffix := make([]byte, 2)
+		if _, err = rand.Read(suffix); err != nil {
+			return "", err
+		}
+		username = base + "_" + hex.EncodeToString(suffix)
+	}
+
+	return "", ErrUserExist
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// outboxBatch number of events dispatched on each run.
+	outboxBatch = 100
+	// outboxMaxAttempts an event whose dispatch failed that many times is given up, it is left in the outbox.
+	outboxMaxAttempts = 10
+	// outboxRetryBase delay before the first retry of a failed dispatch, doubled after each failure.
+	outboxRetryBase = 5 * time.Second
+	// outboxMaxBackoff longest delay between two dispatches of an event.
+	outboxMaxBackoff = 10 * time.Minute
+	// outboxLease how long the claimed events are left alone by the other dispatchers, the events of a dispatcher that
+	// stopped while dispatching them are dispatched again after it.
+	outboxLease = 5 * time.Minute
+)
+
+// OutboxDispatcher publishes the events of the outbox, in the order they were recorded. The events are claimed before
+// being published, so the dispatchers of several instances don't publish the same events. An event is marked as
+// dispatched only once every subscriber received it, the subscribers that already received it aren't published it
+// again when it is retried. A dispatcher stopping between publishing an event and recording it can still publish it
+// twice.
+type OutboxDispatcher struct {
+	OutboxRepository repository.OutboxRepo
+	Events           *EventBus
+	wake             chan struct{}
+}
+
+func NewOutboxDispatcher(outboxRepo repository.OutboxRepo, events *EventBus) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		OutboxRepository: outboxRepo,
+		Events:           events,
+		wake:             make(chan struct{}, 1),
+	}
+}
+
+// Wake dispatches the pending events right away instead of waiting for the next run, it never blocks.
+func (d *OutboxDispatcher) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run dispatches the pending events every `interval` or when woken up, and removes the events dispatched
+// more than `retention` ago. It returns once `ctx` is cancelled.
+func (d *OutboxDispatcher) Run(ctx context.Context, interval time.Duration, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastCleanup := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+
+		// A full batch means more events are probably pending
+		for d.Dispatch(ctx) == outboxBatch {
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			lastCleanup = time.Now()
+
+			if err := d.OutboxRepository.DeleteDispatchedBefore(ctx, lastCleanup.Add(-retention)); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Dispatch publishes the pending events and returns how many were claimed. An event that fails, or can't be marked as
+// dispatched, holds back the following events of its aggregate, so the events of an aggregate are always published in
+// order. The events of the other aggregates are still published.
+func (d *OutboxDispatcher) Dispatch(ctx context.Context) int {
+	now := time.Now()
+	events, err := d.OutboxRepository.ClaimPending(ctx, now, now.Add(outboxLease), outboxBatch)
+	if err != nil {
+		logrus.Error(err)
+		return 0
+	}
+
+	held := map[string]bool{}
+	var heldBack []uint
+
+	for _, event := range events {
+		aggregate := event.Aggregate()
+		if held[aggregate] {
+			heldBack = append(heldBack, event.ID)
+			continue
+		}
+
+		if err = d.publish(ctx, event); err != nil {
+			d.fail(ctx, event, err)
+			held[aggregate] = true
+			continue
+		}
+
+		// Failing here only means the event is published again once its lease runs out
+		if err = d.OutboxRepository.MarkDispatched(ctx, event.ID, time.Now()); err != nil {
+			logrus.Error(err)
+			held[aggregate] = true
+		}
+	}
+
+	// Published after the event that held them back, left for their lease to run out otherwise
+	if len(heldBack) > 0 {
+		if err = d.OutboxRepository.Release(ctx, heldBack); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	return len(events)
+}
+
+// fail counts the failed dispatch of the event, it is retried with a backoff until it failed outboxMaxAttempts times.
+func (d *OutboxDispatcher) fail(ctx context.Context, event models.OutboxEvent, err error) {
+	logger := logrus.WithFields(logrus.Fields{"event": event.Type, "id": event.ID})
+	logger.Error(err)
+
+	lastError := truncate(err.Error(), 512)
+	attempts := event.Attempts + 1
+
+	if attempts >= outboxMaxAttempts {
+		logger.Warn("Outbox event given up after too many failed dispatches")
+		err = d.OutboxRepository.MarkDead(ctx, event.ID, lastError, time.Now())
+	} else {
+		err = d.OutboxRepository.MarkFailed(ctx, event.ID, lastError, time.Now().Add(outboxBackoff(attempts)))
+	}
+
+	if err != nil {
+		logrus.Error(err)
+	}
+}
+
+// publish a subscriber panicking fails the dispatch instead of taking the dispatcher down.
+func (d *OutboxDispatcher) publish(ctx context.Context, event models.OutboxEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("subscriber panicked: %v", r)
+		}
+	}()
+
+	domainEvent, err := outboxDomainEvent(event)
+	if err != nil {
+		return err
+	}
+
+	received, err := d.OutboxRepository.GetReceipts(ctx, event.ID)
+	if err != nil {
+		return err
+	}
+
+	return d.Events.PublishExcept(ctx, domainEvent, received, func(subscriber string) {
+		// Published to the subscriber again on the next attempt otherwise
+		if err := d.OutboxRepository.AddReceipt(ctx, event.ID, subscriber); err != nil {
+			logrus.WithFields(logrus.Fields{"event": event.Type, "id": event.ID}).Error(err)
+		}
+	})
+}
+
+// outboxBackoff delay before the next dispatch once `attempts` dispatches failed.
+func outboxBackoff(attempts int) time.Duration {
+	delay := outboxRetryBase
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, outboxMaxBackoff)
+}
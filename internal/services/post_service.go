@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 
 	"github.com/simple-crud-go/internal/models"
@@ -11,20 +12,32 @@ import (
 
 var ErrMismatchAuthorID = errors.New("You do not own this post")
 
+const (
+	// PostSortMostReacted lists the posts with the most reactions first.
+	PostSortMostReacted = "most_reacted"
+)
+
 type PostService struct {
-	PostRepository repository.PostRepo
-	UserRepository repository.UserRepo
+	PostRepository     repository.PostRepo
+	UserRepository     repository.UserRepo
+	ReactionRepository repository.ReactionRepo
+	AuditRepository    repository.AuditRepo
+	Transactor         repository.Transactor
 }
 
-func NewPostService(postRepo repository.PostRepo, userRepo repository.UserRepo) *PostService {
+func NewPostService(postRepo repository.PostRepo, userRepo repository.UserRepo, reactionRepo repository.ReactionRepo, auditRepo repository.AuditRepo, transactor repository.Transactor) *PostService {
 	return &PostService{
-		PostRepository: postRepo,
-		UserRepository: userRepo,
+		PostRepository:     postRepo,
+		UserRepository:     userRepo,
+		ReactionRepository: reactionRepo,
+		AuditRepository:    auditRepo,
+		Transactor:         transactor,
 	}
 }
 
-func (s *PostService) GetPostById(id int) (*models.Post, error) {
-	user, err := s.PostRepository.GetById(id)
+// GetPostById `viewerId` is the authenticated caller, 0 for anonymous requests.
+func (s *PostService) GetPostById(ctx context.Context, id int, viewerId int) (*models.Post, error) {
+	post, err := s.PostRepository.GetById(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logrus.WithField("id", id).Error("Post doesn't exist")
@@ -33,20 +46,46 @@ func (s *PostService) GetPostById(id int) (*models.Post, error) {
 		}
 		return nil, err
 	}
-	return user, nil
+
+	posts := []models.Post{*post}
+	if err = attachReactions(ctx, s.ReactionRepository, posts, uint(viewerId)); err != nil {
+		return nil, err
+	}
+	*post = posts[0]
+
+	return post, nil
 }
 
-func (s *PostService) GetAllPost() ([]models.Post, error) {
-	users, err := s.PostRepository.GetAll()
+// GetAllPost `sort` is empty or PostSortMostReacted, `viewerId` is the authenticated caller, 0 for anonymous requests.
+func (s *PostService) GetAllPost(ctx context.Context, sort string, viewerId int) ([]models.Post, error) {
+	var (
+		posts []models.Post
+		err   error
+	)
+
+	switch sort {
+	case "":
+		posts, err = s.PostRepository.GetAll(ctx)
+	case PostSortMostReacted:
+		posts, err = s.PostRepository.GetMostReacted(ctx)
+	default:
+		return nil, &ValidationError{Fields: map[string][]string{"sort": {"must be " + PostSortMostReacted}}}
+	}
+
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
 	}
-	return users, nil
+
+	if err = attachReactions(ctx, s.ReactionRepository, posts, uint(viewerId)); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
 }
 
-func (s *PostService) CreatePost(authorId int, title string, body string) error {
-	author, err := s.UserRepository.GetById(uint(authorId))
+func (s *PostService) CreatePost(ctx context.Context, authorId int, title string, body string, client ClientInfo) error {
+	author, err := s.UserRepository.GetById(ctx, uint(authorId))
 	if err != nil {
 		return err
 	}
@@ -57,17 +96,31 @@ func (s *PostService) CreatePost(authorId int, title string, body string) error
 		Body:   body,
 	}
 
-	err = s.PostRepository.Create(&post)
+	err = s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Posts.Create(ctx, &post); err != nil {
+			return err
+		}
+
+		return recordEvent(ctx, repos.Outbox, PostCreated{EventMeta: EventMeta{ActorID: author.ID, UserID: author.ID, PostID: post.ID}, Post: post})
+	})
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
 
+	recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+		Action:     models.AuditPostCreate,
+		ActorID:    &author.ID,
+		TargetType: models.AuditTargetPost,
+		TargetID:   auditID(post.ID),
+		After:      postSummary(&post),
+	})
+
 	return nil
 }
 
-func (s *PostService) UpdatePost(authAuthorID int, postId int, title string, body string) error {
-	post, err := s.PostRepository.GetById(postId)
+func (s *PostService) UpdatePost(ctx context.Context, authAuthorID int, postId int, title string, body string, client ClientInfo) error {
+	post, err := s.PostRepository.GetById(ctx, postId)
 	if err != nil {
 		return err
 	}
@@ -78,25 +131,54 @@ func (s *PostService) UpdatePost(authAuthorID int, postId int, title string, bod
 		return ErrMismatchAuthorID
 	}
 
-	if title != "" {
-		post.Title = title
-	}
+	var before map[string]string
 
-	if body != "" {
-		post.Body = body
-	}
+	// The post read above may come from the cache or a replica, the changes are applied to the post as it is in the
+	// transaction and only the changed columns are written, not to undo a concurrent change of the other ones.
+	err = s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		current, err := repos.Posts.GetById(ctx, postId)
+		if err != nil {
+			return err
+		}
+
+		post = current
+		before = postSummary(post)
+
+		var columns []string
+		if title != "" {
+			post.Title = title
+			columns = append(columns, "title")
+		}
+		if body != "" {
+			post.Body = body
+			columns = append(columns, "body")
+		}
+
+		if err := repos.Posts.Update(ctx, post, columns...); err != nil {
+			return err
+		}
 
-	err = s.PostRepository.Update(post)
+		return recordEvent(ctx, repos.Outbox, PostUpdated{EventMeta: EventMeta{ActorID: post.UserID, UserID: post.UserID, PostID: post.ID}, Post: *post})
+	})
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
 
+	recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+		Action:     models.AuditPostUpdate,
+		ActorID:    &post.UserID,
+		TargetType: models.AuditTargetPost,
+		TargetID:   auditID(post.ID),
+		Before:     before,
+		After:      postSummary(post),
+	})
+
 	return nil
 }
 
-func (s *PostService) DeletePostById(authAuthorID int, postId int) error {
-	post, err := s.PostRepository.GetById(postId)
+func (s *PostService) DeletePostById(ctx context.Context, authAuthorID int, postId int, client ClientInfo) error {
+	post, err := s.PostRepository.GetById(ctx, postId)
 	if err != nil {
 		return err
 	}
@@ -107,11 +189,17 @@ func (s *PostService) DeletePostById(authAuthorID int, postId int) error {
 		return ErrMismatchAuthorID
 	}
 
-	err = s.PostRepository.Delete(uint(postId))
-	if err != nil {
-		logrus.Error(err)
+	if err = deletePost(ctx, s.Transactor, post, post.UserID); err != nil {
 		return err
 	}
 
+	recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+		Action:     models.AuditPostDelete,
+		ActorID:    &post.UserID,
+		TargetType: models.AuditTargetPost,
+		TargetID:   auditID(post.ID),
+		Before:     postSummary(post),
+	})
+
 	return nil
 }
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+)
+
+type ReactionService struct {
+	ReactionRepository repository.ReactionRepo
+	PostRepository     repository.PostRepo
+	Transactor         repository.Transactor
+}
+
+func NewReactionService(reactionRepo repository.ReactionRepo, postRepo repository.PostRepo, transactor repository.Transactor) *ReactionService {
+	return &ReactionService{
+		ReactionRepository: reactionRepo,
+		PostRepository:     postRepo,
+		Transactor:         transactor,
+	}
+}
+
+// React reacting twice with the same kind is not an error.
+func (s *ReactionService) React(ctx context.Context, userId int, postId int, kind string) error {
+	post, err := s.checkReaction(ctx, postId, kind)
+	if err != nil {
+		return err
+	}
+
+	err = s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		created, err := repos.Reactions.Create(ctx, &models.Reaction{PostID: post.ID, UserID: uint(userId), Kind: kind})
+		if err != nil || !created {
+			return err
+		}
+
+		return recordEvent(ctx, repos.Outbox, PostReacted{EventMeta: EventMeta{ActorID: uint(userId), UserID: post.UserID, PostID: post.ID}, Kind: kind})
+	})
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// Unreact removing a reaction the user didn't make is not an error.
+func (s *ReactionService) Unreact(ctx context.Context, userId int, postId int, kind string) error {
+	if _, err := s.checkReaction(ctx, postId, kind); err != nil {
+		return err
+	}
+
+	if err := s.ReactionRepository.Delete(ctx, uint(postId), uint(userId), kind); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// checkReaction returns the post reacted to, not found when it doesn't exist.
+func (s *ReactionService) checkReaction(ctx context.Context, postId int, kind string) (*models.Post, error) {
+	if !slices.Contains(models.ReactionKinds, kind) {
+		return nil, &ValidationError{Fields: map[string][]string{
+			"kind": {fmt.Sprintf("must be one of %v", strings.Join(models.ReactionKinds, ", "))},
+		}}
+	}
+
+	return s.PostRepository.GetById(ctx, postId)
+}
+
+// attachReactions fills the reaction counts of the posts, and the reactions of `viewerId` unless it is 0.
+func attachReactions(ctx context.Context, reactionRepo repository.ReactionRepo, posts []models.Post, viewerId uint) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(posts))
+	byId := make(map[uint]*models.Post, len(posts))
+	for i := range posts {
+		ids[i] = posts[i].ID
+		byId[posts[i].ID] = &posts[i]
+
+		posts[i].Reactions = make(map[string]int64, len(models.ReactionKinds))
+		for _, kind := range models.ReactionKinds {
+			posts[i].Reactions[kind] = 0
+		}
+	}
+
+	counts, err := reactionRepo.CountByPostIds(ctx, ids)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	for _, count := range counts {
+		if post, ok := byId[count.PostID]; ok {
+			post.Reactions[count.Kind] = count.Count
+		}
+	}
+
+	if viewerId == 0 {
+		return nil
+	}
+
+	reactions, err := reactionRepo.GetByUserAndPostIds(ctx, viewerId, ids)
+	if err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	for _, reaction := range reactions {
+		if post, ok := byId[reaction.PostID]; ok {
+			post.ViewerReaction = append(post.ViewerReaction, reaction.Kind)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// sessionTTL lifetime of a login, the token issued with it expires at the same time.
+	sessionTTL = 7 * 24 * time.Hour
+	// sessionTouchInterval limits how often `last_seen_at` is written, not every request needs a database write.
+	sessionTouchInterval = time.Minute
+	userAgentMaxLength   = 255
+)
+
+// ClientInfo describes the device a request comes from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type SessionService struct {
+	SessionRepository repository.SessionRepo
+}
+
+func NewSessionService(sessionRepo repository.SessionRepo) *SessionService {
+	return &SessionService{
+		SessionRepository: sessionRepo,
+	}
+}
+
+// GetSessions lists the active sessions of the user, `currentSessionId` is flagged as the current one.
+func (s *SessionService) GetSessions(ctx context.Context, userId int, currentSessionId string) ([]models.Session, error) {
+	sessions, err := s.SessionRepository.GetActiveByUserId(ctx, uint(userId), time.Now())
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = strconv.Itoa(int(sessions[i].ID)) == currentSessionId
+	}
+
+	return sessions, nil
+}
+
+func (s *SessionService) RevokeSession(ctx context.Context, userId int, sessionId int) error {
+	session, err := s.SessionRepository.GetById(ctx, uint(sessionId))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return err
+	}
+
+	if session.UserID != uint(userId) || session.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+
+	if err = s.SessionRepository.Revoke(ctx, session.ID, time.Now()); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions logs the user out everywhere but the session making the request,
+// every session is revoked when the request isn't made with a session (e.g. with an API key).
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, userId int, currentSessionId string) error {
+	var exceptId uint
+	if id, err := strconv.Atoi(currentSessionId); err == nil {
+		exceptId = uint(id)
+	}
+
+	if err := s.SessionRepository.RevokeAllByUserId(ctx, uint(userId), exceptId, time.Now()); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// IsSessionActive reports whether the session can still be used and records the activity on it.
+func (s *SessionService) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	id, err := strconv.Atoi(sessionId)
+	if err != nil {
+		return false, nil
+	}
+
+	session, err := s.SessionRepository.GetById(ctx, uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+
+		logrus.Error(err)
+		return false, err
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return false, nil
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionTouchInterval {
+		// Not worth failing the request for
+		if err = s.SessionRepository.TouchLastSeen(ctx, session.ID, now); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	return true, nil
+}
+
+// startSession records a new login of the user and returns the token bound to it.
+func startSession(ctx context.Context, sessionRepo repository.SessionRepo, jwtHelper helper.JWTHelper, userId uint, scopes []string, ttl time.Duration, client ClientInfo) (string, error) {
+	now := time.Now()
+	session := models.Session{
+		UserID:     userId,
+		UserAgent:  truncate(client.UserAgent, userAgentMaxLength),
+		IP:         client.IP,
+		Scopes:     scopes,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+
+	if err := sessionRepo.Create(ctx, &session); err != nil {
+		logrus.Error(err)
+		return "", err
+	}
+
+	return jwtHelper.CreateTokenWithOptions(int(userId), helper.TokenOptions{
+		Scopes:    scopes,
+		SessionID: strconv.Itoa(int(session.ID)),
+		TTL:       ttl,
+	})
+}
+
+// scopesForUser the scopes granted when the user logs in.
+func scopesForUser(user *models.User) []string {
+	scopes := append(append([]string{}, helper.DefaultScopes...), helper.ScopeCredentials)
+	if user.Role == models.RoleAdmin {
+		scopes = append(scopes, helper.ScopeAdmin)
+	}
+
+	return scopes
+}
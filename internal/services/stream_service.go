@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamSubscriberBuffer events queued for a client, a client falling further behind is disconnected
+// and has to resume with Last-Event-ID.
+const streamSubscriberBuffer = 64
+
+// StreamEventReset sent first to a resuming client which missed events that can't be replayed anymore, because they
+// fell out of the buffer or the API restarted since. The client has to refetch what it displays.
+const StreamEventReset = "stream.reset"
+
+// streamEventTypes the domain events pushed to the stream clients.
+var streamEventTypes = map[string]bool{
+	EventPostCreated:         true,
+	EventPostUpdated:         true,
+	EventPostDeleted:         true,
+	EventNotificationCreated: true,
+}
+
+// StreamEvent an event sent to the stream clients.
+type StreamEvent struct {
+	// ID `<epoch>-<seq>`, the epoch changes every time the API starts so the ids of a previous run aren't mistaken
+	// for ids of this one.
+	ID   string
+	Seq  uint64
+	Type string
+	// UserID the only user the event is sent to, 0 for public events.
+	UserID uint
+	Data   []byte
+}
+
+// StreamSubscription the events of a connected client, Events is closed when the client is disconnected.
+type StreamSubscription struct {
+	Events <-chan StreamEvent
+	events chan StreamEvent
+	userId uint
+}
+
+// StreamService pushes the events to the connected clients. The last events are kept in memory so a client
+// can resume after a reconnection, it only works within a single instance of the API.
+type StreamService struct {
+	mu          sync.Mutex
+	epoch       string
+	lastId      uint64
+	buffer      []StreamEvent
+	bufferSize  int
+	subscribers map[*StreamSubscription]struct{}
+}
+
+func NewStreamService(bufferSize int) *StreamService {
+	return &StreamService{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		bufferSize:  bufferSize,
+		subscribers: map[*StreamSubscription]struct{}{},
+	}
+}
+
+func (s *StreamService) Publish(ctx context.Context, event DomainEvent) error {
+	if !streamEventTypes[event.EventType()] {
+		return nil
+	}
+
+	data, err := json.Marshal(event.Payload())
+	if err != nil {
+		return err
+	}
+
+	// Only the notifications are private
+	var userId uint
+	if _, ok := event.(NotificationCreated); ok {
+		userId = event.Meta().UserID
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastId++
+	streamEvent := StreamEvent{ID: s.eventId(s.lastId), Seq: s.lastId, Type: event.EventType(), UserID: userId, Data: data}
+
+	s.buffer = append(s.buffer, streamEvent)
+	if len(s.buffer) > s.bufferSize {
+		s.buffer = s.buffer[len(s.buffer)-s.bufferSize:]
+	}
+
+	for subscription := range s.subscribers {
+		if !subscription.receives(streamEvent) {
+			continue
+		}
+
+		select {
+		case subscription.events <- streamEvent:
+		default:
+			s.unsubscribe(subscription)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe connects a client, `userId` is 0 for anonymous clients. The buffered events after `lastEventId`
+// are returned to be sent first, pass "" when the client isn't resuming. When the events after `lastEventId` can't
+// all be replayed a StreamEventReset is returned instead.
+func (s *StreamService) Subscribe(userId uint, lastEventId string) (*StreamSubscription, []StreamEvent) {
+	events := make(chan StreamEvent, streamSubscriberBuffer)
+	subscription := &StreamSubscription{Events: events, events: events, userId: userId}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers[subscription] = struct{}{}
+
+	if lastEventId == "" {
+		return subscription, nil
+	}
+
+	// Every event after the last one the client received has to be still buffered
+	lastSeq, ok := s.parseEventId(lastEventId)
+	oldest := s.lastId - uint64(len(s.buffer)) + 1
+	if !ok || lastSeq > s.lastId || lastSeq+1 < oldest {
+		// The id of the reset lets the client resume from there next time
+		return subscription, []StreamEvent{{ID: s.eventId(s.lastId), Seq: s.lastId, Type: StreamEventReset, Data: []byte("{}")}}
+	}
+
+	var missed []StreamEvent
+	for _, event := range s.buffer {
+		if event.Seq > lastSeq && subscription.receives(event) {
+			missed = append(missed, event)
+		}
+	}
+
+	return subscription, missed
+}
+
+func (s *StreamService) eventId(seq uint64) string {
+	return fmt.Sprintf("%s-%d", s.epoch, seq)
+}
+
+// parseEventId the sequence number of an event id of this epoch, ok is false for any other id.
+func (s *StreamService) parseEventId(id string) (seq uint64, ok bool) {
+	epoch, rawSeq, found := strings.Cut(id, "-")
+	if !found || epoch != s.epoch {
+		return 0, false
+	}
+
+	seq, err := strconv.ParseUint(rawSeq, 10, 64)
+	return seq, err == nil
+}
+
+func (s *StreamService) Unsubscribe(subscription *StreamSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.unsubscribe(subscription)
+}
+
+func (s *StreamService) unsubscribe(subscription *StreamSubscription) {
+	if _, ok := s.subscribers[subscription]; ok {
+		delete(s.subscribers, subscription)
+		close(subscription.events)
+	}
+}
+
+func (s *StreamSubscription) receives(event StreamEvent) bool {
+	return event.UserID == 0 || event.UserID == s.userId
+}
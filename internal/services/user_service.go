@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 
 	"github.com/simple-crud-go/internal/helper"
@@ -13,20 +14,38 @@ import (
 var ErrUserExist = errors.New("User with the same username already exist")
 var ErrMismatchID = errors.New("Unauthorized")
 
+// usernameTaken maps the violation of the unique index on the usernames to ErrUserExist, the index catches the
+// registrations racing past the lookups done before.
+func usernameTaken(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrUserExist
+	}
+
+	return err
+}
+
 type UserService struct {
-	UserRepository repository.UserRepo
-	PasswordCrypto helper.PasswordCrypto
+	UserRepository   repository.UserRepo
+	FollowRepository repository.FollowRepo
+	AuditRepository  repository.AuditRepo
+	PasswordCrypto   helper.PasswordCrypto
+	PasswordPolicy   helper.PasswordPolicy
+	Transactor       repository.Transactor
 }
 
-func NewUserService(userRepo repository.UserRepo, passwordCrypto helper.PasswordCrypto) *UserService {
+func NewUserService(userRepo repository.UserRepo, followRepo repository.FollowRepo, auditRepo repository.AuditRepo, passwordCrypto helper.PasswordCrypto, passwordPolicy helper.PasswordPolicy, transactor repository.Transactor) *UserService {
 	return &UserService{
-		UserRepository: userRepo,
-		PasswordCrypto: passwordCrypto,
+		UserRepository:   userRepo,
+		FollowRepository: followRepo,
+		AuditRepository:  auditRepo,
+		PasswordCrypto:   passwordCrypto,
+		PasswordPolicy:   passwordPolicy,
+		Transactor:       transactor,
 	}
 }
 
-func (s *UserService) GetUserById(id int) (*models.User, error) {
-	user, err := s.UserRepository.GetById(uint(id))
+func (s *UserService) GetUserById(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.UserRepository.GetById(ctx, uint(id))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logrus.WithField("id", id).Error("User doesn't exist")
@@ -38,21 +57,46 @@ func (s *UserService) GetUserById(id int) (*models.User, error) {
 	return user, nil
 }
 
-func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
-	user, err := s.UserRepository.GetByUsername(username)
+// GetAuthUser the user a token or an API key was issued to, without its posts.
+func (s *UserService) GetAuthUser(ctx context.Context, id int) (*models.User, error) {
+	user, err := s.UserRepository.GetAuthUser(ctx, uint(id))
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			logrus.WithField("username", username).Error("User doesn't exist")
-		} else {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
 			logrus.Error(err)
 		}
 		return nil, err
 	}
+
 	return user, nil
 }
 
-func (s *UserService) GetAllUser() ([]models.User, error) {
-	user, err := s.UserRepository.GetAll()
+// GetUserByUsername the profile of the user, with the follower and following counts.
+func (s *UserService) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	user, err := findUser(ctx, s.UserRepository, username)
+	if err != nil {
+		return nil, err
+	}
+
+	followers, err := s.FollowRepository.CountFollowers(ctx, user.ID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	following, err := s.FollowRepository.CountFollowing(ctx, user.ID)
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	user.FollowersCount = &followers
+	user.FollowingCount = &following
+
+	return user, nil
+}
+
+func (s *UserService) GetAllUser(ctx context.Context) ([]models.User, error) {
+	user, err := s.UserRepository.GetAll(ctx)
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
@@ -61,9 +105,9 @@ func (s *UserService) GetAllUser() ([]models.User, error) {
 	return user, nil
 }
 
-func (s *UserService) CreateUser(username string, name string, password string) error {
+func (s *UserService) CreateUser(ctx context.Context, username string, name string, password string) error {
 	var err error
-	user, err := s.UserRepository.GetByUsername(username)
+	user, err := s.UserRepository.GetByUsername(ctx, username)
 
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		logrus.Error(err)
@@ -75,6 +119,10 @@ func (s *UserService) CreateUser(username string, name string, password string)
 		return ErrUserExist
 	}
 
+	if err := validatePassword(s.PasswordPolicy, password, username, name); err != nil {
+		return err
+	}
+
 	hashedPass, err := s.PasswordCrypto.HashPassword(password)
 	if err != nil {
 		return err
@@ -86,11 +134,11 @@ func (s *UserService) CreateUser(username string, name string, password string)
 		Password: hashedPass,
 	}
 
-	return s.UserRepository.Create(newUser)
+	return usernameTaken(s.UserRepository.Create(ctx, &newUser))
 }
 
-func (s *UserService) UpdateUser(id int, username string, name string, password string) error {
-	user, err := s.UserRepository.GetById(uint(id))
+func (s *UserService) UpdateUser(ctx context.Context, id int, username string, name string, password string, client ClientInfo) error {
+	user, err := s.UserRepository.GetById(ctx, uint(id))
 	if err != nil {
 		logrus.Error(err)
 		return err
@@ -100,41 +148,105 @@ func (s *UserService) UpdateUser(id int, username string, name string, password
 		return ErrMismatchID
 	}
 
+	changes := models.User{Username: user.Username, Name: user.Name}
+	var columns []string
+
 	if username != "" {
 		if user.Username != username {
-			userWithUsername, err := s.UserRepository.GetByUsername(username)
+			userWithUsername, err := s.UserRepository.GetByUsername(ctx, username)
 			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 				logrus.Error(err)
 				return err
 			}
 
-			if userWithUsername.ID != 0 {
+			// Only changing the case of one's own username finds the user itself
+			if userWithUsername.ID != 0 && userWithUsername.ID != user.ID {
 				return ErrUserExist
 			}
 		}
 
-		user.Username = username
+		changes.Username = username
+		columns = append(columns, "username")
 	}
 
 	if name != "" {
-		user.Name = name
+		changes.Name = name
+		columns = append(columns, "name")
 	}
 
 	if password != "" {
+		if err := validatePassword(s.PasswordPolicy, password, changes.Username, changes.Name); err != nil {
+			return err
+		}
+
 		hashed, err := s.PasswordCrypto.HashPassword(password)
 		if err != nil {
 			logrus.Error(err)
 			return err
 		}
 
-		user.Password = hashed
+		changes.Password = hashed
+		columns = append(columns, "password")
 	}
 
-	return s.UserRepository.Update(*user)
+	var previousUsername string
+
+	// The user read above may come from the cache or a replica, the changes are applied to the row as it is in the
+	// transaction and only the changed columns are written, not to undo a concurrent change of the other ones.
+	err = s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		current, err := repos.Users.GetById(ctx, uint(id))
+		if err != nil {
+			return err
+		}
+
+		user = current
+		previousUsername = user.Username
+
+		if username != "" {
+			user.Username = changes.Username
+		}
+		if name != "" {
+			user.Name = changes.Name
+		}
+		if password != "" {
+			user.Password = changes.Password
+		}
+
+		if err := repos.Users.Update(ctx, *user, columns...); err != nil {
+			return usernameTaken(err)
+		}
+
+		return recordEvent(ctx, repos.Outbox, UserUpdated{EventMeta: EventMeta{ActorID: user.ID, UserID: user.ID}, User: *user})
+	})
+	if err != nil {
+		return err
+	}
+
+	if user.Username != previousUsername {
+		recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+			Action:     models.AuditUsernameChange,
+			ActorID:    &user.ID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   auditID(user.ID),
+			Before:     map[string]string{"username": previousUsername},
+			After:      map[string]string{"username": user.Username},
+		})
+	}
+
+	if password != "" {
+		recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+			Action:     models.AuditPasswordChange,
+			ActorID:    &user.ID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   auditID(user.ID),
+		})
+	}
+
+	return nil
 }
 
-func (s *UserService) DeleteUserById(id int) error {
-	user, err := s.UserRepository.GetById(uint(id))
+func (s *UserService) DeleteUserById(ctx context.Context, id int, client ClientInfo) error {
+	user, err := s.UserRepository.GetById(ctx, uint(id))
 
 	if err != nil {
 		logrus.Error(err)
@@ -145,12 +257,25 @@ func (s *UserService) DeleteUserById(id int) error {
 		return ErrMismatchID
 	}
 
-	err = s.UserRepository.DeleteById(uint(id))
+	err = s.Transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Users.DeleteById(ctx, uint(id)); err != nil {
+			return err
+		}
 
+		return recordEvent(ctx, repos.Outbox, UserDeleted{EventMeta: EventMeta{ActorID: user.ID, UserID: user.ID}})
+	})
 	if err != nil {
 		logrus.Error(err)
 		return err
 	}
 
+	recordAudit(ctx, s.AuditRepository, client, models.AuditEvent{
+		Action:     models.AuditUserDelete,
+		ActorID:    &user.ID,
+		TargetType: models.AuditTargetUser,
+		TargetID:   auditID(user.ID),
+		Before:     map[string]string{"username": user.Username, "name": user.Name},
+	})
+
 	return nil
 }
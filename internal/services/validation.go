@@ -0,0 +1,42 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/simple-crud-go/internal/helper"
+)
+
+// ValidationError holds the messages of every invalid field, keyed by the field name.
+type ValidationError struct {
+	Fields map[string][]string
+}
+
+func (e *ValidationError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%v %v", field, strings.Join(e.Fields[field], ", ")))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func validatePassword(policy helper.PasswordPolicy, password string, username string, name string) error {
+	violations, err := policy.Validate(password, username, name)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Fields: map[string][]string{"password": violations}}
+	}
+
+	return nil
+}
@@ -0,0 +1,614 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Headers sent along every webhook payload.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	// WebhookSignatureHeader `sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">` keyed with the webhook secret.
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	webhookSecretPrefix = "whsec_"
+	// webhookMaxBackoff caps the delay between two attempts.
+	webhookMaxBackoff = time.Hour
+	// webhookDueBatch number of due deliveries sent on each run.
+	webhookDueBatch = 100
+	// webhookMinLease how long the claimed deliveries are left alone by the other dispatchers at least, the deliveries of
+	// a dispatcher that stopped while sending them are retried after it.
+	webhookMinLease = 5 * time.Minute
+	// webhookLookupTimeout how long the validation of a webhook waits for its host to resolve.
+	webhookLookupTimeout = 2 * time.Second
+)
+
+var errWebhookAddressNotPublic = errors.New("the webhook address isn't public")
+
+// nonPublicNetworks reserved ranges the checks of net.IP don't cover.
+var nonPublicNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	// Carrier-grade NAT, also used for the internal networks of some cloud providers
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"),
+	mustParseCIDR("240.0.0.0/4"),
+	// NAT64, reaches the IPv4 addresses embedded in it
+	mustParseCIDR("64:ff9b::/96"),
+}
+
+// WebhookEvents the events a webhook can subscribe to.
+var WebhookEvents = []string{EventPostCreated, EventPostUpdated, EventPostDeleted, EventPostReacted, EventUserRegistered, EventUserFollowed, EventUserUpdated, EventUserDeleted}
+
+type WebhookConfig struct {
+	// MaxAttempts a delivery is marked as failed after that many attempts.
+	MaxAttempts int
+	// RetryBase delay before the first retry, doubled after each failed attempt.
+	RetryBase time.Duration
+	// DisableAfter consecutive failed attempts after which the webhook is disabled, 0 never disables it.
+	DisableAfter int
+	Timeout      time.Duration
+	// AllowPrivateNetworks lets the webhooks reach loopback, private and link-local addresses, only meant for development.
+	AllowPrivateNetworks bool
+}
+
+// WebhookPayload the body POSTed to the webhooks.
+type WebhookPayload struct {
+	Event     string      `json:"event"`
+	ActorID   uint        `json:"actor_id"`
+	UserID    uint        `json:"user_id"`
+	PostID    uint        `json:"post_id,omitempty"`
+	Kind      string      `json:"kind,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type WebhookService struct {
+	WebhookRepository repository.WebhookRepo
+	Client            *http.Client
+	Config            WebhookConfig
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepo, config WebhookConfig) *WebhookService {
+	client := &http.Client{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		client.Transport = publicOnlyTransport()
+	}
+
+	return &WebhookService{
+		WebhookRepository: webhookRepo,
+		Client:            client,
+		Config:            config,
+	}
+}
+
+// publicOnlyTransport refuses to connect to addresses that aren't public. The address is checked once resolved, right
+// before connecting, so a host resolving to another address than when the webhook was validated (or a redirect) can't
+// reach the internal network either.
+func publicOnlyTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network string, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %v", errWebhookAddressNotPublic, host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would connect on our behalf, out of reach of the check
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+
+	for _, network := range nonPublicNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return network
+}
+
+// SignWebhookPayload the signature sent in the WebhookSignatureHeader, receivers compute it the same way to check the payload.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateWebhook the secret is only returned here. Global webhooks receive the events of every user.
+func (s *WebhookService) CreateWebhook(ctx context.Context, userId int, rawURL string, events []string, global bool) (*api.WebhookCreatedResponse, error) {
+	if err := s.validateWebhook(ctx, rawURL, events); err != nil {
+		return nil, err
+	}
+
+	secret, err := helper.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := models.Webhook{
+		UserID: uint(userId),
+		URL:    rawURL,
+		Secret: webhookSecretPrefix + secret,
+		Events: events,
+		Global: global,
+	}
+
+	if err = s.WebhookRepository.Create(ctx, &webhook); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &api.WebhookCreatedResponse{Secret: webhook.Secret, Webhook: &webhook}, nil
+}
+
+func (s *WebhookService) GetWebhooks(ctx context.Context, userId int) ([]models.Webhook, error) {
+	webhooks, err := s.WebhookRepository.GetByUserId(ctx, uint(userId))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// UpdateWebhook empty values are left unchanged. Enabling a disabled webhook resumes its pending deliveries.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, userId int, webhookId int, rawURL string, events []string, enabled *bool) error {
+	webhook, err := s.ownedWebhook(ctx, userId, webhookId)
+	if err != nil {
+		return err
+	}
+
+	if rawURL != "" {
+		webhook.URL = rawURL
+	}
+
+	if len(events) != 0 {
+		webhook.Events = events
+	}
+
+	if err = s.validateWebhook(ctx, webhook.URL, webhook.Events); err != nil {
+		return err
+	}
+
+	if enabled != nil {
+		if *enabled {
+			webhook.DisabledAt = nil
+			webhook.ConsecutiveFailures = 0
+		} else if webhook.DisabledAt == nil {
+			now := time.Now()
+			webhook.DisabledAt = &now
+		}
+	}
+
+	if err = s.WebhookRepository.Update(ctx, webhook); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userId int, webhookId int) error {
+	webhook, err := s.ownedWebhook(ctx, userId, webhookId)
+	if err != nil {
+		return err
+	}
+
+	if err = s.WebhookRepository.Delete(ctx, webhook.ID); err != nil {
+		logrus.Error(err)
+		return err
+	}
+
+	return nil
+}
+
+// GetDeliveries the delivery log of the webhook, newest first.
+func (s *WebhookService) GetDeliveries(ctx context.Context, userId int, webhookId int, beforeId uint, limit int) ([]models.WebhookDelivery, error) {
+	webhook, err := s.ownedWebhook(ctx, userId, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.WebhookRepository.GetDeliveries(ctx, webhook.ID, beforeId, pageLimit(limit))
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver queues the payload of a past delivery again, as a new delivery.
+func (s *WebhookService) Redeliver(ctx context.Context, userId int, webhookId int, deliveryId int) (*models.WebhookDelivery, error) {
+	webhook, err := s.ownedWebhook(ctx, userId, webhookId)
+	if err != nil {
+		return nil, err
+	}
+
+	if webhook.DisabledAt != nil {
+		return nil, &ValidationError{Fields: map[string][]string{"webhook": {"is disabled, enable it first"}}}
+	}
+
+	delivery, err := s.WebhookRepository.GetDeliveryById(ctx, uint(deliveryId))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return nil, err
+	}
+
+	if delivery.WebhookID != webhook.ID {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	now := time.Now()
+	redelivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		Event:         delivery.Event,
+		Payload:       delivery.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &delivery.ID,
+	}
+
+	if err = s.WebhookRepository.CreateDelivery(ctx, &redelivery); err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+
+	return &redelivery, nil
+}
+
+// Publish queues a delivery for every enabled webhook subscribed to the event,
+// webhooks that aren't global only receive the events involving their owner.
+func (s *WebhookService) Publish(ctx context.Context, event DomainEvent) error {
+	eventType, meta := event.EventType(), event.Meta()
+	if !slices.Contains(WebhookEvents, eventType) {
+		return nil
+	}
+
+	webhooks, err := s.WebhookRepository.GetEnabled(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		payload    []byte
+		deliveries []models.WebhookDelivery
+		now        = time.Now()
+	)
+
+	for _, webhook := range webhooks {
+		if !slices.Contains(webhook.Events, eventType) || !(webhook.Global || webhook.UserID == meta.UserID || webhook.UserID == meta.ActorID) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(webhookPayload(event, now))
+			if err != nil {
+				return err
+			}
+		}
+
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			Event:         eventType,
+			Payload:       string(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	// All at once, the event being published again doesn't queue it twice for some of the webhooks
+	return s.WebhookRepository.CreateDeliveries(ctx, deliveries)
+}
+
+func webhookPayload(event DomainEvent, now time.Time) WebhookPayload {
+	meta := event.Meta()
+	payload := WebhookPayload{
+		Event:     event.EventType(),
+		ActorID:   meta.ActorID,
+		UserID:    meta.UserID,
+		PostID:    meta.PostID,
+		Data:      event.Payload(),
+		CreatedAt: now,
+	}
+
+	if reacted, ok := event.(PostReacted); ok {
+		payload.Kind = reacted.Kind
+	}
+
+	return payload
+}
+
+// Run sends the due deliveries every `interval`, it returns once `ctx` is cancelled.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// A full batch means more deliveries are probably due
+		for s.DeliverDue(ctx) == webhookDueBatch {
+		}
+	}
+}
+
+// DeliverDue sends the deliveries due now and returns how many were attempted.
+func (s *WebhookService) DeliverDue(ctx context.Context) int {
+	now := time.Now()
+	deliveries, err := s.WebhookRepository.ClaimDueDeliveries(ctx, now, now.Add(s.lease()), webhookDueBatch)
+	if err != nil {
+		logrus.Error(err)
+		return 0
+	}
+
+	// The same webhook is shared by its deliveries, so one getting disabled stops the following ones
+	webhooks := map[uint]*models.Webhook{}
+	var skipped []uint
+
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook = delivery.Webhook
+			webhooks[delivery.WebhookID] = webhook
+		}
+
+		if webhook == nil || webhook.DisabledAt != nil {
+			skipped = append(skipped, delivery.ID)
+			continue
+		}
+
+		s.deliver(ctx, webhook, delivery)
+	}
+
+	// Given back rather than left claimed, they wait with the other pending deliveries of the webhook for it to be
+	// enabled again.
+	if len(skipped) > 0 {
+		if err := s.WebhookRepository.ReleaseDeliveries(ctx, skipped, now); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	return len(deliveries)
+}
+
+func (s *WebhookService) deliver(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	now := time.Now()
+	status, err := s.send(ctx, webhook, delivery, now)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = status
+
+	if err == nil {
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.Error = ""
+	} else {
+		delivery.Error = truncate(err.Error(), 512)
+
+		if delivery.Attempts >= s.Config.MaxAttempts {
+			delivery.Status = models.WebhookDeliveryFailed
+			delivery.NextAttemptAt = nil
+		} else {
+			next := now.Add(s.backoff(delivery.Attempts))
+			delivery.NextAttemptAt = &next
+		}
+	}
+
+	if err := s.WebhookRepository.UpdateDelivery(ctx, delivery); err != nil {
+		logrus.WithField("webhook", webhook.ID).Error(err)
+	}
+
+	// Only the failure count is written, the webhook may have been edited while it was being sent
+	if err == nil {
+		if err = s.WebhookRepository.ResetFailures(ctx, webhook.ID); err != nil {
+			logrus.WithField("webhook", webhook.ID).Error(err)
+			return
+		}
+
+		webhook.ConsecutiveFailures = 0
+		return
+	}
+
+	updated, err := s.WebhookRepository.RecordFailure(ctx, webhook.ID, s.Config.DisableAfter, now)
+	if err != nil {
+		logrus.WithField("webhook", webhook.ID).Error(err)
+		return
+	}
+
+	if webhook.DisabledAt == nil && updated.DisabledAt != nil {
+		logrus.WithField("webhook", webhook.ID).Warn("Webhook disabled after too many failed deliveries")
+	}
+
+	webhook.ConsecutiveFailures = updated.ConsecutiveFailures
+	webhook.DisabledAt = updated.DisabledAt
+}
+
+// lease how long the claimed deliveries are left alone, long enough for the whole batch to be sent one after another.
+func (s *WebhookService) lease() time.Duration {
+	return max(webhookMinLease, s.Config.Timeout*webhookDueBatch)
+}
+
+// send POSTs the signed payload, anything but a 2xx response is a failure.
+func (s *WebhookService) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery, now time.Time) (*int, error) {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "simple-crud-webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	// Drained so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &res.StatusCode, fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+
+	return &res.StatusCode, nil
+}
+
+// backoff delay before the next attempt once `attempts` attempts failed.
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.Config.RetryBase
+	for i := 1; i < attempts && delay < webhookMaxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, webhookMaxBackoff)
+}
+
+// ownedWebhook returns the webhook only if it belongs to the user, other users webhooks are reported as not found.
+func (s *WebhookService) ownedWebhook(ctx context.Context, userId int, webhookId int) (*models.Webhook, error) {
+	webhook, err := s.WebhookRepository.GetById(ctx, uint(webhookId))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Error(err)
+		}
+		return nil, err
+	}
+
+	if webhook.UserID != uint(userId) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return webhook, nil
+}
+
+func (s *WebhookService) validateWebhook(ctx context.Context, rawURL string, events []string) error {
+	fields := map[string][]string{}
+
+	if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		fields["url"] = append(fields["url"], "must be an absolute http or https URL")
+	} else if len(rawURL) > 2048 {
+		fields["url"] = append(fields["url"], "must be at most 2048 characters")
+	} else if !s.Config.AllowPrivateNetworks && !isPublicHost(ctx, u.Hostname()) {
+		fields["url"] = append(fields["url"], "must point to a public address")
+	}
+
+	if len(events) == 0 {
+		fields["events"] = append(fields["events"], "at least one event is required")
+	}
+
+	for _, event := range events {
+		if !slices.Contains(WebhookEvents, event) {
+			fields["events"] = append(fields["events"], fmt.Sprintf("'%v' must be one of %v", event, strings.Join(WebhookEvents, ", ")))
+		}
+	}
+
+	if len(fields) != 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	return nil
+}
+
+// isPublicHost whether `host` is a public address, or a name resolving to public addresses only. A name that doesn't
+// resolve (yet) is accepted, the address is checked again when connecting anyway.
+func isPublicHost(ctx context.Context, host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return isPublicIP(ip)
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookLookupTimeout)
+	defer cancel()
+
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return true
+	}
+
+	for _, address := range addresses {
+		if !isPublicIP(address.IP) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -3,15 +3,16 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"os"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
 	_ "github.com/simple-crud-go/docs"
 	"github.com/simple-crud-go/internal/configs"
 	"github.com/simple-crud-go/internal/database"
+	"github.com/simple-crud-go/internal/database/migrations"
 	"github.com/simple-crud-go/internal/handlers"
 	"github.com/simple-crud-go/internal/middleware"
-	"github.com/simple-crud-go/internal/models"
 	"github.com/sirupsen/logrus"
 )
 
@@ -30,21 +31,39 @@ import (
 // @in header
 // @name Authorization
 // @description Type "Bearer" followed by a space and the JWT Token
+
+// @securityDefinitions.apikey ApiKey
+// @in header
+// @name X-API-Key
+// @description A personal API key created with POST /me/api-keys
 func main() {
 	err := godotenv.Load()
 	if err != nil {
 		logrus.Error(fmt.Sprintf("Error loading .env file, error: %v", err))
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err = runMigrate(os.Args[2:]); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+
 	db := database.InitDB()
-	err = db.AutoMigrate(&models.User{}, &models.Post{})
-	if err != nil {
-		panic("failed to migrate")
+	migrator := database.NewMigrator(db, migrations.All(), configs.GetDBMigrationLockTimeout())
+	if configs.GetDBMigrateOnStart() {
+		if _, err = migrator.Up(0); err != nil {
+			panic(fmt.Sprintf("failed to migrate, error: %v", err))
+		}
+	}
+
+	if err = migrator.Check(); err != nil {
+		panic(fmt.Sprintf("%v, apply them with `simple-crud migrate up`", err))
 	}
 
 	r := mux.NewRouter()
 	// r.Use(middleware.OnlyJson)
-	handlers.RouteHandler(r, db)
+	handlers.RouteHandler(r, db, database.InitReplicas())
 
 	port := configs.GetPort()
 	fmt.Printf("Listening at http://127.0.0.1:%v\n", port)
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/simple-crud-go/internal/configs"
+	"github.com/simple-crud-go/internal/database"
+	"github.com/simple-crud-go/internal/database/migrations"
+)
+
+// migrationsDir where `migrate create` writes the new migration, relative to the root of the repository.
+const migrationsDir = "internal/database/migrations"
+
+const migrateUsage = `usage: simple-crud migrate <command>
+
+  up [n]         apply the pending migrations, only the first n when given
+  down [n]       revert the last n applied migrations, 1 by default
+  status         list the migrations and whether they are applied
+  create <name>  write a new empty migration to ` + migrationsDir
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// runMigrate the `migrate` subcommand.
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+
+		return createMigration(args[1])
+	}
+
+	migrator := database.NewMigrator(database.InitDB(), migrations.All(), configs.GetDBMigrationLockTimeout())
+
+	switch args[0] {
+	case "up":
+		steps, err := migrateSteps(args[1:], 0)
+		if err != nil {
+			return err
+		}
+
+		applied, err := migrator.Up(steps)
+		for _, migration := range applied {
+			fmt.Printf("applied  %d_%v\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Println("the database is up to date")
+		}
+
+		return err
+	case "down":
+		steps, err := migrateSteps(args[1:], 1)
+		if err != nil {
+			return err
+		}
+
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %d_%v\n", migration.Version, migration.Name)
+		}
+
+		return err
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Unknown {
+				appliedAt += " (not in this build)"
+			}
+
+			fmt.Fprintf(w, "%d\t%v\t%v\n", status.Version, status.Name, appliedAt)
+		}
+
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+func migrateSteps(args []string, defaultSteps int) (int, error) {
+	if len(args) == 0 {
+		return defaultSteps, nil
+	}
+
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 || len(args) > 1 {
+		return 0, errors.New(migrateUsage)
+	}
+
+	return steps, nil
+}
+
+func createMigration(name string) error {
+	if !migrationNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid migration name '%v', only lowercase letters, digits and underscores are allowed", name)
+	}
+
+	version := time.Now().UTC().Format("20060102150405")
+	path := filepath.Join(migrationsDir, version+"_"+name+".go")
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err = fmt.Fprintf(file, migrationTemplate, version, name); err != nil {
+		return err
+	}
+
+	fmt.Println("created", path)
+	return nil
+}
+
+const migrationTemplate = `package migrations
+
+import (
+	"github.com/simple-crud-go/internal/database"
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(database.Migration{
+		Version: %v,
+		Name:    %q,
+		Up: func(tx *gorm.DB) error {
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return nil
+		},
+	})
+}
+`
@@ -0,0 +1,71 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Least recently used is evicted", func(t *testing.T) {
+		lru := cache.NewLRU(2)
+		assert.NoError(t, lru.Set(ctx, "a", []byte("1"), time.Minute))
+		assert.NoError(t, lru.Set(ctx, "b", []byte("2"), time.Minute))
+
+		// Reading "a" makes "b" the least recently used
+		value, ok, err := lru.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, []byte("1"), value)
+
+		assert.NoError(t, lru.Set(ctx, "c", []byte("3"), time.Minute))
+		assert.Equal(t, 2, lru.Len())
+
+		_, ok, _ = lru.Get(ctx, "b")
+		assert.False(t, ok)
+		_, ok, _ = lru.Get(ctx, "a")
+		assert.True(t, ok)
+		_, ok, _ = lru.Get(ctx, "c")
+		assert.True(t, ok)
+	})
+
+	t.Run("Set replaces the value", func(t *testing.T) {
+		lru := cache.NewLRU(2)
+		assert.NoError(t, lru.Set(ctx, "a", []byte("1"), time.Minute))
+		assert.NoError(t, lru.Set(ctx, "a", []byte("2"), time.Minute))
+
+		value, ok, _ := lru.Get(ctx, "a")
+		assert.True(t, ok)
+		assert.Equal(t, []byte("2"), value)
+		assert.Equal(t, 1, lru.Len())
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		lru := cache.NewLRU(2)
+		assert.NoError(t, lru.Set(ctx, "a", []byte("1"), 20*time.Millisecond))
+
+		time.Sleep(40 * time.Millisecond)
+
+		_, ok, err := lru.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Equal(t, 0, lru.Len())
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		lru := cache.NewLRU(3)
+		assert.NoError(t, lru.Set(ctx, "a", []byte("1"), time.Minute))
+		assert.NoError(t, lru.Set(ctx, "b", []byte("2"), time.Minute))
+
+		assert.NoError(t, lru.Delete(ctx, "a", "b", "missing"))
+
+		_, ok, _ := lru.Get(ctx, "a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, lru.Len())
+	})
+}
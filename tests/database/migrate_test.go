@@ -0,0 +1,177 @@
+package database_test
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/database"
+	"github.com/simple-crud-go/internal/database/migrations"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func openSQLite(t *testing.T, path string) *gorm.DB {
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return db
+}
+
+func tableMigration(version int64, table string) database.Migration {
+	return database.Migration{
+		Version: version,
+		Name:    "create_" + table,
+		Up: func(tx *gorm.DB) error {
+			return tx.Exec("CREATE TABLE " + table + " (id INTEGER PRIMARY KEY)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE " + table).Error
+		},
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+	irreversible := database.Migration{Version: 3, Name: "irreversible", Up: func(tx *gorm.DB) error { return nil }}
+	// Out of order on purpose, they are applied by version
+	migrator := database.NewMigrator(db, []database.Migration{irreversible, tableMigration(2, "gadgets"), tableMigration(1, "widgets")}, time.Second)
+
+	assert.ErrorIs(t, migrator.Check(), database.ErrSchemaNotMigrated)
+
+	applied, err := migrator.Up(1)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+	assert.Equal(t, int64(1), applied[0].Version)
+	assert.True(t, db.Migrator().HasTable("widgets"))
+	assert.False(t, db.Migrator().HasTable("gadgets"))
+
+	applied, err = migrator.Up(0)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 2)
+	assert.NoError(t, migrator.Check())
+
+	_, err = migrator.Down(1)
+	assert.EqualError(t, err, "migration 3_irreversible can't be reverted")
+
+	statuses, err := migrator.Status()
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 3)
+	for _, status := range statuses {
+		assert.NotNil(t, status.AppliedAt)
+	}
+
+	// A build that doesn't know migration 3 can't revert it, but its own schema is there
+	older := database.NewMigrator(db, []database.Migration{tableMigration(1, "widgets"), tableMigration(2, "gadgets")}, time.Second)
+	assert.NoError(t, older.Check())
+	statuses, err = older.Status()
+	assert.NoError(t, err)
+	assert.True(t, statuses[2].Unknown)
+	_, err = older.Down(1)
+	assert.EqualError(t, err, "migration 3_irreversible isn't known to this build")
+
+	assert.NoError(t, db.Delete(&database.SchemaMigration{}, 3).Error)
+	reverted, err := migrator.Down(2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 1}, []int64{reverted[0].Version, reverted[1].Version})
+	assert.False(t, db.Migrator().HasTable("widgets"))
+	assert.False(t, db.Migrator().HasTable("gadgets"))
+}
+
+func TestMigratorFailedMigration(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+	broken := database.Migration{
+		Version: 2,
+		Name:    "broken",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("CREATE TABLE gadgets (id INTEGER PRIMARY KEY)").Error; err != nil {
+				return err
+			}
+
+			return tx.Exec("INSERT INTO missing VALUES (1)").Error
+		},
+	}
+	migrator := database.NewMigrator(db, []database.Migration{tableMigration(1, "widgets"), broken}, time.Second)
+
+	applied, err := migrator.Up(0)
+
+	assert.ErrorContains(t, err, "migration 2_broken failed")
+	assert.Len(t, applied, 1)
+	assert.True(t, db.Migrator().HasTable("widgets"))
+	assert.False(t, db.Migrator().HasTable("gadgets"))
+	assert.ErrorIs(t, migrator.Check(), database.ErrSchemaNotMigrated)
+}
+
+func TestMigratorConcurrentReplicas(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	var runs atomic.Int32
+	slow := database.Migration{
+		Version: 1,
+		Name:    "slow",
+		Up: func(tx *gorm.DB) error {
+			runs.Add(1)
+			time.Sleep(200 * time.Millisecond)
+			return tx.Exec("CREATE TABLE widgets (id INTEGER PRIMARY KEY)").Error
+		},
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		migrator := database.NewMigrator(openSQLite(t, path), []database.Migration{slow}, 5*time.Second)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = migrator.Up(0)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), runs.Load())
+}
+
+func TestInitialSchema(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+	migrator := database.NewMigrator(db, migrations.All(), time.Second)
+
+	_, err := migrator.Up(0)
+	assert.NoError(t, err)
+	assert.True(t, db.Migrator().HasTable("users"))
+	assert.True(t, db.Migrator().HasTable("outbox_events"))
+
+	_, err = migrator.Down(len(migrations.All()))
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasTable("users"))
+	assert.False(t, db.Migrator().HasTable("outbox_events"))
+}
+
+func TestUsernameUniqueMigration(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"))
+	migrator := database.NewMigrator(db, migrations.All(), time.Second)
+
+	_, err := migrator.Up(1)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Exec("INSERT INTO users (name, username, password) VALUES ('Jane', 'jane', ''), ('Jane', 'Jane', '')").Error)
+
+	_, err = migrator.Up(1)
+	assert.ErrorContains(t, err, "the usernames jane are used by more than one user")
+
+	assert.NoError(t, db.Exec("UPDATE users SET deleted_at = CURRENT_TIMESTAMP WHERE username = 'Jane'").Error)
+
+	applied, err := migrator.Up(1)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 1)
+}
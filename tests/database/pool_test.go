@@ -0,0 +1,43 @@
+package database_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnect(t *testing.T) {
+	t.Run("Pool configured", func(t *testing.T) {
+		db, err := database.Connect(database.Config{
+			Driver: database.DriverSQLite,
+			Path:   filepath.Join(t.TempDir(), "test.db"),
+			Pool:   database.PoolConfig{MaxOpenConns: 3, MaxIdleConns: 2},
+		}, time.Second)
+		assert.NoError(t, err)
+
+		stats, err := database.PoolStats(db)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, stats.MaxOpenConnections)
+	})
+
+	t.Run("Invalid config isn't retried", func(t *testing.T) {
+		start := time.Now()
+		_, err := database.Connect(database.Config{Driver: database.DriverSQLite}, time.Minute)
+
+		assert.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("Retried until the timeout", func(t *testing.T) {
+		start := time.Now()
+		// Nothing listens on the port
+		_, err := database.Connect(database.Config{Driver: database.DriverMySQL, Host: "127.0.0.1", Port: "1", User: "root", Name: "simple"}, 2*time.Second)
+
+		assert.ErrorContains(t, err, "connection refused")
+		assert.GreaterOrEqual(t, time.Since(start), 1500*time.Millisecond)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
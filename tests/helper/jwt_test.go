@@ -18,6 +18,10 @@ func jwtHelperWithMock(t *testing.T) (helper.JWTHelper, *mock_helper.MockJWTMana
 	return helper.NewJWTHelper(jwtManager), jwtManager
 }
 
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{"sub": "1", "iss": "simple-crud", "aud": "simple-crud-api", "jti": "id"}
+}
+
 func TestCheckToken(t *testing.T) {
 	jwtHelper, jwtManager := jwtHelperWithMock(t)
 
@@ -29,7 +33,7 @@ func TestCheckToken(t *testing.T) {
 		{
 			"Valid token",
 			func() {
-				jwtManager.EXPECT().ParseToken(gomock.Any()).Return(&jwt.Token{Valid: true}, nil).Times(1)
+				jwtManager.EXPECT().ParseToken(gomock.Any()).Return(&jwt.Token{Claims: validClaims(), Valid: true}, nil).Times(1)
 			},
 			nil,
 		},
@@ -97,32 +101,60 @@ func TestCreateToken(t *testing.T) {
 	}
 }
 
-func TestExtractAudienceToken(t *testing.T) {
-	var (
-		aud                   = "1"
-		jwtHelper, jwtManager = jwtHelperWithMock(t)
-	)
+func TestCreateTokenClaims(t *testing.T) {
+	jwtHelper, jwtManager := jwtHelperWithMock(t)
+
+	jwtManager.EXPECT().SignToken(gomock.Any()).DoAndReturn(func(claims jwt.MapClaims) (string, error) {
+		assert.Equal(t, "1", claims["sub"])
+		assert.Equal(t, "simple-crud", claims["iss"])
+		assert.Equal(t, "simple-crud-api", claims["aud"])
+		assert.NotEmpty(t, claims["jti"])
+		return "token", nil
+	}).Times(1)
+
+	_, err := jwtHelper.CreateToken(1)
+
+	assert.NoError(t, err)
+}
+
+func TestExtractSubjectToken(t *testing.T) {
+	jwtHelper, jwtManager := jwtHelperWithMock(t)
+
+	withClaims := func(claims jwt.MapClaims) func() {
+		return func() {
+			jwtManager.EXPECT().ParseToken(gomock.Any()).Return(&jwt.Token{Claims: claims, Valid: true}, nil).Times(1)
+		}
+	}
 
 	cases := []struct {
 		name     string
 		mockFunc func()
 		err      error
-		aud      string
+		sub      string
 	}{
 		{
 			"Success",
-			func() {
-				jwtManager.EXPECT().ParseToken(gomock.Any()).Return(&jwt.Token{
-					Claims: jwt.RegisteredClaims{
-						Audience: jwt.ClaimStrings{aud},
-					},
-					Valid: true,
-				},
-					nil,
-				).Times(1)
-			},
+			withClaims(validClaims()),
 			nil,
-			aud,
+			"1",
+		},
+		{
+			"Legacy token",
+			withClaims(jwt.MapClaims{"aud": "2"}),
+			nil,
+			"2",
+		},
+		{
+			"Wrong issuer",
+			withClaims(jwt.MapClaims{"sub": "1", "iss": "someone-else", "aud": "simple-crud-api"}),
+			jwt.ErrTokenInvalidIssuer,
+			"",
+		},
+		{
+			"Wrong audience",
+			withClaims(jwt.MapClaims{"sub": "1", "iss": "simple-crud", "aud": "another-api"}),
+			jwt.ErrTokenInvalidAudience,
+			"",
 		},
 		{
 			"Invalid Token",
@@ -145,10 +177,33 @@ func TestExtractAudienceToken(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			aud, err := jwtHelper.ExtractAudienceToken("completelynormaltoken")
+			sub, err := jwtHelper.ExtractSubjectToken("completelynormaltoken")
 
-			assert.Equal(t, aud, c.aud)
-			assert.Equal(t, err, c.err)
+			assert.Equal(t, c.sub, sub)
+			assert.Equal(t, c.err, err)
 		})
 	}
 }
+
+func TestLegacyTokensRejected(t *testing.T) {
+	t.Setenv("JWT_ACCEPT_LEGACY_TOKENS", "false")
+	jwtHelper, jwtManager := jwtHelperWithMock(t)
+
+	jwtManager.EXPECT().ParseToken(gomock.Any()).Return(&jwt.Token{Claims: jwt.MapClaims{"aud": "2"}, Valid: true}, nil).Times(1)
+
+	_, err := jwtHelper.ParseClaims("legacy")
+
+	assert.Equal(t, jwt.ErrTokenInvalidSubject, err)
+}
+
+func TestDefaultJWTManagerPinsAlgorithm(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	manager := helper.NewDefaultJWTManager()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS512, validClaims()).SignedString([]byte("secret"))
+	assert.NoError(t, err)
+
+	_, err = manager.ParseToken(token)
+
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
@@ -0,0 +1,68 @@
+package helper_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultPasswordPolicy(t *testing.T) {
+	breached, err := helper.NewHashPrefixBreachedChecker(strings.NewReader(
+		"# sha1 of Password123\nB2E98AD6F6EB8508DD6A14CFA704BAD7F05F6FB1:12\n",
+	))
+	assert.NoError(t, err)
+
+	policy := helper.DefaultPasswordPolicy{
+		MinLength:            8,
+		RequireUpper:         true,
+		RequireLower:         true,
+		RequireDigit:         true,
+		RequireSymbol:        false,
+		DisallowPersonalInfo: true,
+		Breached:             breached,
+	}
+
+	cases := []struct {
+		name       string
+		password   string
+		violations []string
+	}{
+		{"valid password", "Tr0ub4dorAnd3", []string{}},
+		{"too short", "Ab1", []string{"must be at least 8 characters long"}},
+		{"missing character classes", "abcdefghij", []string{"must contain an uppercase letter", "must contain a digit"}},
+		{"contains username", "XIbkaanhar1", []string{"must not contain your username or name"}},
+		{"contains part of the name", "Fatcha2024x", []string{"must not contain your username or name"}},
+		{"breached password", "Password123", []string{"is too common or has appeared in a data breach"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			violations, err := policy.Validate(c.password, "ibkaanhar", "Ibka Anhar Fatcha")
+
+			assert.NoError(t, err)
+			assert.Equal(t, c.violations, violations)
+		})
+	}
+}
+
+func TestHashPrefixBreachedChecker(t *testing.T) {
+	t.Run("invalid hash", func(t *testing.T) {
+		_, err := helper.NewHashPrefixBreachedChecker(strings.NewReader("ABC\n"))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("bundled list", func(t *testing.T) {
+		checker := helper.NewDefaultBreachedPasswordChecker()
+
+		breached, err := checker.IsBreached("qwerty123")
+		assert.NoError(t, err)
+		assert.True(t, breached)
+
+		breached, err = checker.IsBreached("c0rrect-Horse-battery-st4ple")
+		assert.NoError(t, err)
+		assert.False(t, breached)
+	})
+}
@@ -0,0 +1,184 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/simple-crud-go/internal/helper"
+	mock_helper "github.com/simple-crud-go/internal/helper/mocks"
+	"github.com/simple-crud-go/internal/middleware"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+type stubUsers map[int]*models.User
+
+func (s stubUsers) GetAuthUser(ctx context.Context, id int) (*models.User, error) {
+	if user, ok := s[id]; ok {
+		return user, nil
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+type stubSessions map[string]bool
+
+func (s stubSessions) IsSessionActive(ctx context.Context, sessionId string) (bool, error) {
+	return s[sessionId], nil
+}
+
+func authWithMock(t *testing.T) (*mock_helper.MockJWTHelper, *middleware.Auth) {
+	ctrl := gomock.NewController(t)
+
+	jwtHelper := mock_helper.NewMockJWTHelper(ctrl)
+	users := stubUsers{1: {ID: 1, Username: "jane", Role: models.RoleUser}}
+	sessions := stubSessions{"active": true}
+
+	return jwtHelper, middleware.NewAuth(jwtHelper, users, nil, nil, sessions)
+}
+
+// serve runs the request through the middleware and returns the status code and the principal seen by the handler.
+func serve(mw func(http.Handler) http.Handler, authorization string) (int, *middleware.Principal) {
+	var principal *middleware.Principal
+
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ = middleware.PrincipalFrom(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec.Code, principal
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	jwtHelper, auth := authWithMock(t)
+
+	cases := []struct {
+		name          string
+		authorization string
+		mockFunc      func()
+		code          int
+	}{
+		{
+			"Missing authentication",
+			"",
+			func() {},
+			http.StatusUnauthorized,
+		},
+		{
+			"Invalid token",
+			"Bearer token",
+			func() {
+				jwtHelper.EXPECT().ParseClaims("token").Return(nil, errors.New("token is expired")).Times(1)
+			},
+			http.StatusUnauthorized,
+		},
+		{
+			"Revoked session",
+			"Bearer token",
+			func() {
+				jwtHelper.EXPECT().ParseClaims("token").Return(&helper.TokenClaims{UserID: "1", SessionID: "revoked"}, nil).Times(1)
+			},
+			http.StatusUnauthorized,
+		},
+		{
+			"Deleted user",
+			"Bearer token",
+			func() {
+				jwtHelper.EXPECT().ParseClaims("token").Return(&helper.TokenClaims{UserID: "2", SessionID: "active"}, nil).Times(1)
+			},
+			http.StatusUnauthorized,
+		},
+		{
+			"Valid token",
+			"Bearer token",
+			func() {
+				jwtHelper.EXPECT().ParseClaims("token").Return(&helper.TokenClaims{UserID: "1", SessionID: "active", TokenID: "jti"}, nil).Times(1)
+			},
+			http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			code, principal := serve(auth.AuthMiddleware, c.authorization)
+
+			assert.Equal(t, c.code, code)
+
+			if c.code == http.StatusOK {
+				assert.Equal(t, &middleware.Principal{
+					UserID:    1,
+					Username:  "jane",
+					Roles:     []string{models.RoleUser},
+					Scopes:    helper.DefaultScopes,
+					TokenID:   "jti",
+					SessionID: "active",
+				}, principal)
+			}
+		})
+	}
+}
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+	jwtHelper, auth := authWithMock(t)
+
+	t.Run("Anonymous request", func(t *testing.T) {
+		code, principal := serve(auth.OptionalAuthMiddleware, "")
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Nil(t, principal)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		jwtHelper.EXPECT().ParseClaims("token").Return(nil, errors.New("signature is invalid")).Times(1)
+
+		code, _ := serve(auth.OptionalAuthMiddleware, "Bearer token")
+
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("Authenticated request", func(t *testing.T) {
+		jwtHelper.EXPECT().ParseClaims("token").Return(&helper.TokenClaims{UserID: "1"}, nil).Times(1)
+
+		code, principal := serve(auth.OptionalAuthMiddleware, "Bearer token")
+
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, 1, principal.UserID)
+	})
+}
+
+func TestRequireScopes(t *testing.T) {
+	jwtHelper, auth := authWithMock(t)
+
+	requireAdmin := func(next http.Handler) http.Handler {
+		return auth.AuthMiddleware(middleware.RequireScopes(helper.ScopeAdmin)(next))
+	}
+
+	t.Run("Missing scope", func(t *testing.T) {
+		jwtHelper.EXPECT().ParseClaims("token").Return(&helper.TokenClaims{UserID: "1", Scopes: helper.DefaultScopes}, nil).Times(1)
+
+		code, _ := serve(requireAdmin, "Bearer token")
+
+		assert.Equal(t, http.StatusForbidden, code)
+	})
+
+	t.Run("Granted scope", func(t *testing.T) {
+		jwtHelper.EXPECT().ParseClaims("token").Return(&helper.TokenClaims{UserID: "1", Scopes: []string{helper.ScopeAdmin}}, nil).Times(1)
+
+		code, _ := serve(requireAdmin, "Bearer token")
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+}
@@ -0,0 +1,56 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/simple-crud-go/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestDeadline(t *testing.T) {
+	var (
+		deadline    time.Time
+		hasDeadline bool
+	)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		deadline, hasDeadline = r.Context().Deadline()
+	}
+
+	r := mux.NewRouter().PathPrefix("/api").Subrouter()
+	r.Use(middleware.RequestDeadline(time.Minute, map[string]time.Duration{
+		"GET /api/post/{id}": time.Hour,
+		"GET /api/stream":    0,
+	}))
+	r.HandleFunc("/post", handler).Methods("GET")
+	r.HandleFunc("/post/{id}", handler).Methods("GET", "PUT")
+	r.HandleFunc("/stream", handler).Methods("GET")
+
+	cases := []struct {
+		name        string
+		method      string
+		path        string
+		hasDeadline bool
+		timeout     time.Duration
+	}{
+		{"Default timeout", "GET", "/api/post", true, time.Minute},
+		{"Timeout of the route", "GET", "/api/post/1", true, time.Hour},
+		{"Other method of the route", "PUT", "/api/post/1", true, time.Minute},
+		{"No deadline", "GET", "/api/stream", false, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			start := time.Now()
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(c.method, c.path, nil))
+
+			assert.Equal(t, c.hasDeadline, hasDeadline)
+			if c.hasDeadline {
+				assert.WithinDuration(t, start.Add(c.timeout), deadline, time.Second)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/simple-crud-go/internal/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocketTokenQuery(t *testing.T) {
+	var authorization string
+	handler := middleware.WebSocketTokenQuery(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+	}))
+
+	t.Run("Handshake", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/collaborate?access_token=token", nil)
+		req.Header.Set("Upgrade", "websocket")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "Bearer token", authorization)
+	})
+
+	t.Run("The header wins", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/collaborate?access_token=token", nil)
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Authorization", "Bearer other")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, "Bearer other", authorization)
+	})
+
+	t.Run("Not a handshake", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/collaborate?access_token=token", nil)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Empty(t, authorization)
+	})
+}
@@ -3,10 +3,13 @@ package repository_test
 import (
 	"database/sql"
 	"database/sql/driver"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/simple-crud-go/internal/database"
+	"github.com/simple-crud-go/internal/database/migrations"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -39,3 +42,23 @@ func DB(t *testing.T) (*sql.DB, *gorm.DB, sqlmock.Sqlmock) {
 
 	return sqlDB, gormDB, sqlMock
 }
+
+// SQLiteDB a migrated SQLite database, removed when the test ends.
+func SQLiteDB(t *testing.T) *gorm.DB {
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("'%s' occured when opening a SQLite database", err)
+	}
+
+	if _, err = database.NewMigrator(db, migrations.All(), time.Second).Up(0); err != nil {
+		t.Fatalf("'%s' occured when migrating the SQLite database", err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	return db
+}
@@ -1,6 +1,7 @@
 package repository_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -20,7 +21,7 @@ func TestPostGetById(t *testing.T) {
 
 	_, db, mock := DB(t)
 
-	repo := repository.NewPostRepository(db)
+	repo := repository.NewPostRepository(db, nil)
 
 	post := sqlmock.NewRows([]string{
 		"id", "title", "body", "user_id",
@@ -31,7 +32,7 @@ func TestPostGetById(t *testing.T) {
 	mock.ExpectQuery(query).WithArgs(id, 1).WillReturnRows(post)
 	// Preload (association) query
 	mock.ExpectQuery(preloadUserQuery).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{}))
-	p, err := repo.GetById(1)
+	p, err := repo.GetById(context.Background(), 1)
 
 	assert.NoError(t, err)
 	assert.Equal(t, title, p.Title)
@@ -47,7 +48,7 @@ func TestPostGetAll(t *testing.T) {
 
 	_, db, mock := DB(t)
 
-	repo := repository.NewPostRepository(db)
+	repo := repository.NewPostRepository(db, nil)
 
 	post := sqlmock.NewRows([]string{
 		"id", "title", "body", "user_id",
@@ -58,7 +59,7 @@ func TestPostGetAll(t *testing.T) {
 	mock.ExpectQuery(query).WillReturnRows(post)
 	// Preload (association) query
 	mock.ExpectQuery(preloadUserQuery).WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{}))
-	p, err := repo.GetAll()
+	p, err := repo.GetAll(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, firstTitle, p[0].Title)
@@ -74,14 +75,14 @@ func TestPostUpdate(t *testing.T) {
 		UserID: 1,
 	}
 
-	repo := repository.NewPostRepository(db)
+	repo := repository.NewPostRepository(db, nil)
 
 	query := "INSERT INTO `posts`"
 	mock.ExpectBegin()
 	mock.ExpectExec(query).WithArgs(newPost.Title, newPost.Body, newPost.UserID, AnyTime{}, AnyTime{}, nil).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.Create(&newPost)
+	err := repo.Create(context.Background(), &newPost)
 
 	assert.NoError(t, err)
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -97,14 +98,14 @@ func TestPostCreate(t *testing.T) {
 		UserID: 1,
 	}
 
-	repo := repository.NewPostRepository(db)
+	repo := repository.NewPostRepository(db, nil)
 
-	query := "UPDATE `posts` SET"
+	query := "UPDATE `posts` SET `title`=\\?,`body`=\\?,`updated_at`=\\? WHERE `posts`.`deleted_at` IS NULL AND `id` = \\?"
 	mock.ExpectBegin()
-	mock.ExpectExec(query).WithArgs(updatedPost.Title, updatedPost.Body, updatedPost.UserID, AnyTime{}, AnyTime{}, nil, updatedPost.ID).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query).WithArgs(updatedPost.Title, updatedPost.Body, AnyTime{}, updatedPost.ID).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.Update(&updatedPost)
+	err := repo.Update(context.Background(), &updatedPost, "title", "body")
 
 	assert.NoError(t, err)
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -113,7 +114,7 @@ func TestPostCreate(t *testing.T) {
 func TestPostDelete(t *testing.T) {
 	_, db, mock := DB(t)
 
-	repo := repository.NewPostRepository(db)
+	repo := repository.NewPostRepository(db, nil)
 
 	postIdToBeDeleted := 1
 
@@ -123,8 +124,28 @@ func TestPostDelete(t *testing.T) {
 	mock.ExpectExec(query).WithArgs(AnyTime{}, postIdToBeDeleted).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.Delete(uint(postIdToBeDeleted))
+	err := repo.Delete(context.Background(), uint(postIdToBeDeleted))
 
 	assert.NoError(t, err)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
+
+func TestPostGetFeed(t *testing.T) {
+	_, db, mock := DB(t)
+
+	repo := repository.NewPostRepository(db, nil)
+
+	post := sqlmock.NewRows([]string{
+		"id", "title", "body", "user_id",
+	}).AddRow(7, "Followed author post", "Body", 2)
+
+	query := "SELECT `posts`.(.+) FROM `posts` JOIN follows ON follows.followee_id = posts.user_id AND follows.follower_id = \\? WHERE posts.id < \\? AND `posts`.`deleted_at` IS NULL ORDER BY posts.id DESC LIMIT \\?"
+	mock.ExpectQuery(query).WithArgs(1, 10, 20).WillReturnRows(post)
+	// Preload (association) query
+	mock.ExpectQuery(preloadUserQuery).WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{}))
+	posts, err := repo.GetFeed(context.Background(), 1, 10, 20)
+
+	assert.NoError(t, err)
+	assert.Len(t, posts, 1)
+	assert.Nil(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,87 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simple-crud-go/internal/database"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// usernames of the users listed by `repo`, telling which database the listing was read from.
+func usernames(t *testing.T, ctx context.Context, repo repository.UserRepo) []string {
+	users, err := repo.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+
+	return names
+}
+
+func TestReplicas(t *testing.T) {
+	var (
+		primary  = SQLiteDB(t)
+		replica  = SQLiteDB(t)
+		replicas = database.NewReplicas([]*gorm.DB{replica})
+		repo     = repository.NewUserRepository(primary, replicas)
+	)
+
+	seedUsers(t, primary, "primary")
+	seedUsers(t, replica, "replica")
+
+	t.Run("Not checked yet", func(t *testing.T) {
+		assert.Equal(t, []string{"primary"}, usernames(t, context.Background(), repo))
+	})
+
+	replicas.CheckHealth(context.Background())
+
+	t.Run("Healthy replica", func(t *testing.T) {
+		assert.Equal(t, []string{"replica"}, usernames(t, context.Background(), repo))
+
+		user, err := repo.GetByUsername(context.Background(), "replica")
+		assert.NoError(t, err)
+		assert.Equal(t, "replica", user.Username)
+	})
+
+	t.Run("Read after a write", func(t *testing.T) {
+		ctx := database.TrackWrites(context.Background())
+		assert.Equal(t, []string{"replica"}, usernames(t, ctx, repo))
+
+		assert.NoError(t, repo.Create(ctx, &models.User{Name: "Jane", Username: "jane", Password: "hashed"}))
+
+		assert.Equal(t, []string{"primary", "jane"}, usernames(t, ctx, repo))
+		assert.Equal(t, []string{"replica"}, usernames(t, context.Background(), repo))
+	})
+
+	t.Run("Replica down", func(t *testing.T) {
+		sqlDB, err := replica.DB()
+		assert.NoError(t, err)
+		assert.NoError(t, sqlDB.Close())
+
+		replicas.CheckHealth(context.Background())
+
+		assert.Equal(t, []string{"primary", "jane"}, usernames(t, context.Background(), repo))
+	})
+}
+
+func TestReplicasUnreachable(t *testing.T) {
+	primary := SQLiteDB(t)
+	seedUsers(t, primary, "primary")
+
+	// Nothing listens on the port
+	replica, err := database.OpenReplica(database.DriverMySQL, "root@tcp(127.0.0.1:1)/simple", database.PoolConfig{})
+	assert.NoError(t, err)
+
+	replicas := database.NewReplicas([]*gorm.DB{replica})
+	replicas.CheckHealth(context.Background())
+
+	assert.Equal(t, []string{"primary"}, usernames(t, context.Background(), repository.NewUserRepository(primary, replicas)))
+}
@@ -0,0 +1,378 @@
+package repository_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/cache"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+func newRepositoryCache() *repository.RepositoryCache {
+	return repository.NewRepositoryCache(cache.NewLRU(100), time.Minute, time.Minute)
+}
+
+func TestCachedUserRepository(t *testing.T) {
+	var (
+		ctx  = context.Background()
+		user = models.User{ID: 1, Name: "Jane", Username: "jane", Posts: &[]models.Post{{ID: 2, Title: "Title", UserID: 1}}}
+	)
+
+	t.Run("Cached after the first lookup", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repo := repository.NewCachedUserRepository(users, newRepositoryCache())
+
+		users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&user, nil).Times(1)
+
+		for range 2 {
+			found, err := repo.GetById(ctx, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, "Jane", found.Name)
+			assert.Len(t, *found.Posts, 1)
+		}
+	})
+
+	t.Run("Username lookup fills the id lookup", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repo := repository.NewCachedUserRepository(users, newRepositoryCache())
+
+		users.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&user, nil).Times(1)
+
+		for range 2 {
+			found, err := repo.GetByUsername(ctx, "jane")
+			assert.NoError(t, err)
+			assert.Equal(t, uint(1), found.ID)
+		}
+
+		found, err := repo.GetById(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "jane", found.Username)
+
+		found, err = repo.GetByUsername(ctx, "Jane")
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), found.ID)
+	})
+
+	t.Run("Not found is cached", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repo := repository.NewCachedUserRepository(users, newRepositoryCache())
+
+		users.EXPECT().GetById(gomock.Any(), uint(3)).Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+		users.EXPECT().GetByUsername(gomock.Any(), "john").Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+
+		for range 2 {
+			_, err := repo.GetById(ctx, 3)
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+			_, err = repo.GetByUsername(ctx, "john")
+			assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		}
+	})
+
+	t.Run("Created user isn't reported missing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repo := repository.NewCachedUserRepository(users, newRepositoryCache())
+
+		gomock.InOrder(
+			users.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{}, gorm.ErrRecordNotFound),
+			users.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, created *models.User) error {
+				created.ID = 1
+				return nil
+			}),
+			users.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&user, nil),
+		)
+
+		_, err := repo.GetByUsername(ctx, "jane")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		assert.NoError(t, repo.Create(ctx, &models.User{Name: "Jane", Username: "jane"}))
+
+		found, err := repo.GetByUsername(ctx, "jane")
+		assert.NoError(t, err)
+		assert.Equal(t, uint(1), found.ID)
+	})
+
+	t.Run("Invalidated on update and delete", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repo := repository.NewCachedUserRepository(users, newRepositoryCache())
+
+		renamed := user
+		renamed.Name = "Janet"
+
+		gomock.InOrder(
+			users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&user, nil),
+			users.EXPECT().Update(gomock.Any(), renamed).Return(nil),
+			users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&renamed, nil),
+			users.EXPECT().DeleteById(gomock.Any(), uint(1)).Return(nil),
+			users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&models.User{}, gorm.ErrRecordNotFound),
+		)
+
+		found, err := repo.GetById(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane", found.Name)
+
+		assert.NoError(t, repo.Update(ctx, renamed))
+
+		found, err = repo.GetById(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Janet", found.Name)
+
+		assert.NoError(t, repo.DeleteById(ctx, 1))
+
+		_, err = repo.GetById(ctx, 1)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Renamed user", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repositoryCache := newRepositoryCache()
+		repo := repository.NewCachedUserRepository(users, repositoryCache)
+
+		renamed := user
+		renamed.Username = "janet"
+
+		gomock.InOrder(
+			users.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&user, nil),
+			users.EXPECT().Update(gomock.Any(), renamed).Return(nil),
+			users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&renamed, nil),
+			users.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{}, gorm.ErrRecordNotFound),
+		)
+
+		_, err := repo.GetByUsername(ctx, "jane")
+		assert.NoError(t, err)
+
+		assert.NoError(t, repo.Update(ctx, renamed))
+
+		// The old username still points to the user, who is called differently now
+		_, err = repo.GetByUsername(ctx, "jane")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Updated while it was loaded", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repo := repository.NewCachedUserRepository(users, newRepositoryCache())
+
+		renamed := user
+		renamed.Name = "Janet"
+
+		gomock.InOrder(
+			users.EXPECT().GetById(gomock.Any(), uint(1)).DoAndReturn(func(ctx context.Context, id uint) (*models.User, error) {
+				// The user was read before the update committed
+				assert.NoError(t, repo.Update(ctx, renamed))
+				return &user, nil
+			}),
+			users.EXPECT().Update(gomock.Any(), renamed).Return(nil),
+			users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&renamed, nil),
+		)
+
+		found, err := repo.GetById(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane", found.Name)
+
+		// What the load read isn't cached
+		found, err = repo.GetById(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Janet", found.Name)
+	})
+
+	t.Run("Deleted while looked up by username", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repo := repository.NewCachedUserRepository(users, newRepositoryCache())
+
+		gomock.InOrder(
+			users.EXPECT().GetByUsername(gomock.Any(), "jane").DoAndReturn(func(ctx context.Context, username string) (*models.User, error) {
+				assert.NoError(t, repo.DeleteById(ctx, 1))
+				return &user, nil
+			}),
+			users.EXPECT().DeleteById(gomock.Any(), uint(1)).Return(nil),
+			// The user isn't cached along with the username
+			users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&models.User{}, gorm.ErrRecordNotFound),
+			users.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{}, gorm.ErrRecordNotFound),
+		)
+
+		_, err := repo.GetByUsername(ctx, "jane")
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("Concurrent misses load once", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repo := repository.NewCachedUserRepository(users, newRepositoryCache())
+
+		users.EXPECT().GetById(gomock.Any(), uint(1)).DoAndReturn(func(ctx context.Context, id uint) (*models.User, error) {
+			time.Sleep(50 * time.Millisecond)
+			return &user, nil
+		}).Times(1)
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				found, err := repo.GetById(ctx, 1)
+				assert.NoError(t, err)
+				assert.Equal(t, "Jane", found.Name)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Canceled load", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		users := mock_repository.NewMockUserRepo(ctrl)
+		repo := repository.NewCachedUserRepository(users, newRepositoryCache())
+
+		canceled, cancel := context.WithCancel(ctx)
+		started := make(chan struct{})
+
+		users.EXPECT().GetById(gomock.Any(), uint(1)).DoAndReturn(func(ctx context.Context, id uint) (*models.User, error) {
+			close(started)
+			cancel()
+			time.Sleep(50 * time.Millisecond)
+			return &models.User{}, ctx.Err()
+		})
+		users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&user, nil)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			_, err := repo.GetById(canceled, 1)
+			assert.ErrorIs(t, err, context.Canceled)
+		}()
+
+		// Waits for the load of the canceled request, then loads the user itself
+		<-started
+		found, err := repo.GetById(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane", found.Name)
+
+		wg.Wait()
+	})
+}
+
+func TestCachedPostRepository(t *testing.T) {
+	var (
+		ctx    = context.Background()
+		post   = models.Post{ID: 2, Title: "Title", UserID: 1, User: &models.User{ID: 1, Username: "jane"}}
+		author = models.User{ID: 1, Username: "jane", Posts: &[]models.Post{}}
+	)
+
+	ctrl := gomock.NewController(t)
+	posts := mock_repository.NewMockPostRepo(ctrl)
+	users := mock_repository.NewMockUserRepo(ctrl)
+	repositoryCache := newRepositoryCache()
+	postRepo := repository.NewCachedPostRepository(posts, repositoryCache)
+	userRepo := repository.NewCachedUserRepository(users, repositoryCache)
+
+	edited := post
+	edited.Title = "Edited"
+
+	gomock.InOrder(
+		posts.EXPECT().GetById(gomock.Any(), 2).Return(&post, nil),
+		users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&author, nil),
+		posts.EXPECT().Update(gomock.Any(), &edited).Return(nil),
+		posts.EXPECT().GetById(gomock.Any(), 2).Return(&edited, nil),
+		users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&author, nil),
+		// Delete looks up the author before the post is gone
+		posts.EXPECT().GetById(gomock.Any(), 2).Return(&edited, nil),
+		posts.EXPECT().Delete(gomock.Any(), uint(2)).Return(nil),
+		posts.EXPECT().GetById(gomock.Any(), 2).Return(&models.Post{}, gorm.ErrRecordNotFound),
+		users.EXPECT().GetById(gomock.Any(), uint(1)).Return(&author, nil),
+	)
+
+	for range 2 {
+		found, err := postRepo.GetById(ctx, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, "Title", found.Title)
+		assert.Equal(t, "jane", found.User.Username)
+
+		_, err = userRepo.GetById(ctx, 1)
+		assert.NoError(t, err)
+	}
+
+	// The author is cached with its posts, they are invalidated together
+	assert.NoError(t, postRepo.Update(ctx, &edited))
+
+	found, err := postRepo.GetById(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "Edited", found.Title)
+
+	_, err = userRepo.GetById(ctx, 1)
+	assert.NoError(t, err)
+
+	assert.NoError(t, postRepo.Delete(ctx, 2))
+
+	for range 2 {
+		_, err = postRepo.GetById(ctx, 2)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	}
+
+	_, err = userRepo.GetById(ctx, 1)
+	assert.NoError(t, err)
+}
+
+func TestCachingTransactor(t *testing.T) {
+	var (
+		ctx             = context.Background()
+		db              = SQLiteDB(t)
+		repositoryCache = newRepositoryCache()
+		repo            = repository.NewCachedUserRepository(repository.NewUserRepository(db, nil), repositoryCache)
+		transactor      = repository.NewCachingTransactor(repository.NewTransactor(db, nil), repositoryCache)
+	)
+
+	jane := seedUsers(t, db, "jane")[0]
+
+	_, err := repo.GetByUsername(ctx, "john")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	found, err := repo.GetById(ctx, jane.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "jane", found.Name)
+
+	err = transactor.RunInTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Users.Create(ctx, &models.User{Name: "john", Username: "john", Password: "hashed"}); err != nil {
+			return err
+		}
+
+		return repos.Tx.RunInTx(ctx, func(repos repository.Repositories) error {
+			jane.Name = "Jane"
+			if err := repos.Users.Update(ctx, jane, "name"); err != nil {
+				return err
+			}
+
+			// The transaction reads what it wrote, not the cache
+			found, err := repos.Users.GetById(ctx, jane.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, "Jane", found.Name)
+
+			return nil
+		})
+	})
+	assert.NoError(t, err)
+
+	found, err = repo.GetByUsername(ctx, "john")
+	assert.NoError(t, err)
+	assert.Equal(t, "john", found.Name)
+
+	found, err = repo.GetById(ctx, jane.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane", found.Name)
+}
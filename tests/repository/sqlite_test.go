@@ -0,0 +1,379 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func seedUsers(t *testing.T, db *gorm.DB, usernames ...string) []models.User {
+	users := make([]models.User, len(usernames))
+	for i, username := range usernames {
+		users[i] = models.User{Name: username, Username: username, Password: "hashed"}
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return users
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	db := SQLiteDB(t)
+	repo := repository.NewUserRepository(db, nil)
+
+	assert.NoError(t, repo.Create(context.Background(), &models.User{Name: "Jane", Username: "jane", Password: "hashed"}))
+
+	user, err := repo.GetByUsername(context.Background(), "jane")
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane", user.Name)
+
+	assert.NoError(t, db.Create(&models.Post{UserID: user.ID, Title: "Title", Body: "Body"}).Error)
+
+	user, err = repo.GetById(context.Background(), user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, *user.Posts, 1)
+
+	// Writing some columns of a stale copy leaves the others as they are now
+	stale := *user
+	assert.NoError(t, db.Model(&models.User{}).Where("id = ?", user.ID).Update("role", models.RoleAdmin).Error)
+	stale.Name = "Janet"
+	assert.NoError(t, repo.Update(context.Background(), stale, "name"))
+
+	user, err = repo.GetById(context.Background(), user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Janet", user.Name)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+
+	authUser, err := repo.GetAuthUser(context.Background(), user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "jane", authUser.Username)
+	assert.Equal(t, user.Role, authUser.Role)
+	assert.Nil(t, authUser.Posts)
+
+	assert.NoError(t, repo.DeleteById(context.Background(), user.ID))
+
+	_, err = repo.GetByUsername(context.Background(), "jane")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+	_, err = repo.GetAuthUser(context.Background(), user.ID)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSQLitePostRepository(t *testing.T) {
+	db := SQLiteDB(t)
+	users := seedUsers(t, db, "jane", "john")
+	posts := repository.NewPostRepository(db, nil)
+	follows := repository.NewFollowRepository(db)
+	reactions := repository.NewReactionRepository(db)
+
+	first := models.Post{UserID: users[0].ID, Title: "First", Body: "Body"}
+	second := models.Post{UserID: users[0].ID, Title: "Second", Body: "Body"}
+	assert.NoError(t, posts.Create(context.Background(), &first))
+	assert.NoError(t, posts.Create(context.Background(), &second))
+
+	created, err := reactions.Create(context.Background(), &models.Reaction{PostID: first.ID, UserID: users[1].ID, Kind: models.ReactionLike})
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	mostReacted, err := posts.GetMostReacted(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{first.ID, second.ID}, []uint{mostReacted[0].ID, mostReacted[1].ID})
+
+	created, err = follows.Create(context.Background(), &models.Follow{FollowerID: users[1].ID, FolloweeID: users[0].ID})
+	assert.NoError(t, err)
+	assert.True(t, created)
+
+	feed, err := posts.GetFeed(context.Background(), users[1].ID, 0, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{second.ID, first.ID}, []uint{feed[0].ID, feed[1].ID})
+	assert.Equal(t, "jane", feed[0].User.Username)
+
+	assert.NoError(t, posts.Delete(context.Background(), first.ID))
+
+	_, err = posts.GetById(context.Background(), int(first.ID))
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSQLiteConflicts(t *testing.T) {
+	db := SQLiteDB(t)
+	users := seedUsers(t, db, "jane", "john")
+	post := models.Post{UserID: users[0].ID, Title: "Title", Body: "Body"}
+	assert.NoError(t, db.Create(&post).Error)
+
+	follows := repository.NewFollowRepository(db)
+	reactions := repository.NewReactionRepository(db)
+	bookmarks := repository.NewBookmarkRepository(db)
+
+	created, err := follows.Create(context.Background(), &models.Follow{FollowerID: users[1].ID, FolloweeID: users[0].ID})
+	assert.NoError(t, err)
+	assert.True(t, created)
+	created, err = follows.Create(context.Background(), &models.Follow{FollowerID: users[1].ID, FolloweeID: users[0].ID})
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	created, err = reactions.Create(context.Background(), &models.Reaction{PostID: post.ID, UserID: users[1].ID, Kind: models.ReactionLike})
+	assert.NoError(t, err)
+	assert.True(t, created)
+	created, err = reactions.Create(context.Background(), &models.Reaction{PostID: post.ID, UserID: users[1].ID, Kind: models.ReactionLike})
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	assert.NoError(t, bookmarks.Save(context.Background(), &models.Bookmark{UserID: users[1].ID, PostID: post.ID, Collection: "later"}))
+	assert.NoError(t, bookmarks.Save(context.Background(), &models.Bookmark{UserID: users[1].ID, PostID: post.ID, Collection: "golang"}))
+
+	saved, err := bookmarks.GetByUserId(context.Background(), users[1].ID, nil, 0, 10)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 1)
+	assert.Equal(t, "golang", saved[0].Collection)
+}
+
+func TestSQLiteUsernameUnique(t *testing.T) {
+	db := SQLiteDB(t)
+	repo := repository.NewUserRepository(db, nil)
+
+	jane := &models.User{Name: "Jane", Username: "jane", Password: "hashed"}
+	assert.NoError(t, repo.Create(context.Background(), jane))
+
+	err := repo.Create(context.Background(), &models.User{Name: "Jane", Username: "JANE", Password: "hashed"})
+	assert.ErrorIs(t, err, gorm.ErrDuplicatedKey)
+
+	// The lookup matches the index, whatever the case
+	found, err := repo.GetByUsername(context.Background(), "JANE")
+	assert.NoError(t, err)
+	assert.Equal(t, jane.ID, found.ID)
+
+	john := &models.User{Name: "John", Username: "john", Password: "hashed"}
+	assert.NoError(t, repo.Create(context.Background(), john))
+	john.Username = "Jane"
+	assert.ErrorIs(t, repo.Update(context.Background(), *john, "username"), gorm.ErrDuplicatedKey)
+
+	// The username of a deleted user is free again
+	assert.NoError(t, repo.DeleteById(context.Background(), jane.ID))
+	assert.NoError(t, repo.Create(context.Background(), &models.User{Name: "Jane", Username: "Jane", Password: "hashed"}))
+}
+
+func TestSQLiteOutboxRepository(t *testing.T) {
+	db := SQLiteDB(t)
+	repo := repository.NewOutboxRepository(db)
+	now := time.Now()
+
+	first := models.OutboxEvent{Type: "post.created", UserID: 1, PostID: 1, Data: "{}"}
+	second := models.OutboxEvent{Type: "post.deleted", UserID: 1, PostID: 1, Data: "{}"}
+	other := models.OutboxEvent{Type: "post.created", UserID: 1, PostID: 2, Data: "{}"}
+	assert.NoError(t, repo.Add(context.Background(), &first))
+	assert.NoError(t, repo.Add(context.Background(), &second))
+	assert.NoError(t, repo.Add(context.Background(), &other))
+
+	claimed, err := repo.ClaimPending(context.Background(), now, now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, claimed, 3)
+
+	// Leased to the first dispatcher
+	claimed, err = repo.ClaimPending(context.Background(), now, now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	assert.NoError(t, repo.MarkFailed(context.Background(), first.ID, "subscriber failed", now.Add(time.Second)))
+	assert.NoError(t, repo.Release(context.Background(), []uint{second.ID, other.ID}))
+
+	// The second event waits for the first one, the event of another post doesn't
+	claimed, err = repo.ClaimPending(context.Background(), now, now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{other.ID}, []uint{claimed[0].ID})
+
+	claimed, err = repo.ClaimPending(context.Background(), now.Add(time.Second), now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{first.ID, second.ID}, []uint{claimed[0].ID, claimed[1].ID})
+	assert.Equal(t, 1, claimed[0].Attempts)
+
+	// Held events filling a page don't keep the following ones from being claimed
+	assert.NoError(t, repo.Release(context.Background(), []uint{second.ID, other.ID}))
+	claimed, err = repo.ClaimPending(context.Background(), now.Add(time.Second), now.Add(time.Minute), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{other.ID}, []uint{claimed[0].ID})
+
+	// Given up, it doesn't hold back the following events anymore
+	assert.NoError(t, repo.MarkDead(context.Background(), first.ID, "subscriber failed", now))
+	claimed, err = repo.ClaimPending(context.Background(), now.Add(time.Second), now.Add(time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{second.ID}, []uint{claimed[0].ID})
+
+	t.Run("Receipts", func(t *testing.T) {
+		assert.NoError(t, repo.AddReceipt(context.Background(), first.ID, "notifications"))
+		// Recorded again by a dispatch that couldn't tell
+		assert.NoError(t, repo.AddReceipt(context.Background(), first.ID, "notifications"))
+		assert.NoError(t, repo.AddReceipt(context.Background(), first.ID, "stream"))
+
+		received, err := repo.GetReceipts(context.Background(), first.ID)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{"notifications", "stream"}, received)
+
+		// Not needed anymore once the event is dispatched
+		assert.NoError(t, repo.MarkDispatched(context.Background(), first.ID, now))
+		received, err = repo.GetReceipts(context.Background(), first.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, received)
+	})
+}
+
+func TestSQLiteWebhookRepository(t *testing.T) {
+	db := SQLiteDB(t)
+	users := seedUsers(t, db, "jane")
+	repo := repository.NewWebhookRepository(db)
+	now := time.Now()
+
+	webhook := models.Webhook{UserID: users[0].ID, URL: "https://example.com/hook", Events: []string{"post.created"}}
+	assert.NoError(t, repo.Create(context.Background(), &webhook))
+	assert.NoError(t, repo.CreateDelivery(context.Background(), &models.WebhookDelivery{WebhookID: webhook.ID, Event: "post.created", Status: models.WebhookDeliveryPending, NextAttemptAt: &now}))
+
+	t.Run("Claimed once", func(t *testing.T) {
+		claimed, err := repo.ClaimDueDeliveries(context.Background(), now, now.Add(time.Minute), 10)
+		assert.NoError(t, err)
+		assert.Len(t, claimed, 1)
+		assert.Equal(t, webhook.ID, claimed[0].Webhook.ID)
+
+		claimed, err = repo.ClaimDueDeliveries(context.Background(), now, now.Add(time.Minute), 10)
+		assert.NoError(t, err)
+		assert.Empty(t, claimed)
+
+		// Due again once the lease ran out
+		claimed, err = repo.ClaimDueDeliveries(context.Background(), now.Add(time.Minute), now.Add(2*time.Minute), 10)
+		assert.NoError(t, err)
+		assert.Len(t, claimed, 1)
+
+		// Or right away once released
+		assert.NoError(t, repo.ReleaseDeliveries(context.Background(), []uint{claimed[0].ID}, now.Add(time.Minute)))
+		claimed, err = repo.ClaimDueDeliveries(context.Background(), now.Add(time.Minute), now.Add(3*time.Minute), 10)
+		assert.NoError(t, err)
+		assert.Len(t, claimed, 1)
+	})
+
+	t.Run("Failures counted in place", func(t *testing.T) {
+		// Edited while a delivery was being sent
+		assert.NoError(t, db.Model(&models.Webhook{}).Where("id = ?", webhook.ID).UpdateColumn("url", "https://example.com/edited").Error)
+
+		updated, err := repo.RecordFailure(context.Background(), webhook.ID, 2, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, updated.ConsecutiveFailures)
+		assert.Nil(t, updated.DisabledAt)
+		assert.Equal(t, "https://example.com/edited", updated.URL)
+
+		updated, err = repo.RecordFailure(context.Background(), webhook.ID, 2, now)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, updated.ConsecutiveFailures)
+		assert.NotNil(t, updated.DisabledAt)
+
+		assert.NoError(t, repo.ResetFailures(context.Background(), webhook.ID))
+
+		updated, err = repo.GetById(context.Background(), webhook.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, updated.ConsecutiveFailures)
+		assert.NotNil(t, updated.DisabledAt)
+	})
+}
+
+func TestSQLiteTransactorRollback(t *testing.T) {
+	db := SQLiteDB(t)
+	committed := 0
+	transactor := repository.NewTransactor(db, func() { committed++ })
+
+	errFailed := errors.New("failed")
+	err := transactor.RunInTx(context.Background(), func(repos repository.Repositories) error {
+		if err := repos.Users.Create(context.Background(), &models.User{Name: "Jane", Username: "jane", Password: "hashed"}); err != nil {
+			return err
+		}
+
+		return errFailed
+	})
+
+	assert.ErrorIs(t, err, errFailed)
+	assert.Equal(t, 0, committed)
+
+	_, err = repository.NewUserRepository(db, nil).GetByUsername(context.Background(), "jane")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSQLiteTransactorPanic(t *testing.T) {
+	db := SQLiteDB(t)
+	committed := 0
+	transactor := repository.NewTransactor(db, func() { committed++ })
+
+	assert.PanicsWithValue(t, "boom", func() {
+		transactor.RunInTx(context.Background(), func(repos repository.Repositories) error {
+			if err := repos.Users.Create(context.Background(), &models.User{Name: "Jane", Username: "jane", Password: "hashed"}); err != nil {
+				return err
+			}
+
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 0, committed)
+
+	_, err := repository.NewUserRepository(db, nil).GetByUsername(context.Background(), "jane")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestSQLiteTransactorNested(t *testing.T) {
+	db := SQLiteDB(t)
+	committed := 0
+	transactor := repository.NewTransactor(db, func() { committed++ })
+
+	errFailed := errors.New("failed")
+	err := transactor.RunInTx(context.Background(), func(repos repository.Repositories) error {
+		if err := repos.Users.Create(context.Background(), &models.User{Name: "Jane", Username: "jane", Password: "hashed"}); err != nil {
+			return err
+		}
+
+		err := repos.Tx.RunInTx(context.Background(), func(repos repository.Repositories) error {
+			if err := repos.Users.Create(context.Background(), &models.User{Name: "John", Username: "john", Password: "hashed"}); err != nil {
+				return err
+			}
+
+			return errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+
+		assert.Panics(t, func() {
+			repos.Tx.RunInTx(context.Background(), func(repos repository.Repositories) error {
+				if err := repos.Users.Create(context.Background(), &models.User{Name: "Joe", Username: "joe", Password: "hashed"}); err != nil {
+					return err
+				}
+
+				panic("boom")
+			})
+		})
+
+		return repos.Tx.RunInTx(context.Background(), func(repos repository.Repositories) error {
+			return repos.Users.Create(context.Background(), &models.User{Name: "Jill", Username: "jill", Password: "hashed"})
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, committed)
+
+	users, err := repository.NewUserRepository(db, nil).GetAll(context.Background())
+	assert.NoError(t, err)
+
+	usernames := []string{}
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+	}
+	assert.ElementsMatch(t, []string{"jane", "jill"}, usernames)
+}
+
+func TestSQLiteCancelledContext(t *testing.T) {
+	db := SQLiteDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repository.NewUserRepository(db, nil).GetAll(ctx)
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -1,6 +1,7 @@
 package repository_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -14,7 +15,7 @@ var preloadPostsQuery = "SELECT (.+) FROM `posts` WHERE `posts`.`user_id` = ?"
 func TestUserGetById(t *testing.T) {
 	_, db, mock := DB(t)
 
-	repo := repository.NewUserRepository(db)
+	repo := repository.NewUserRepository(db, nil)
 
 	user := sqlmock.NewRows([]string{
 		"id", "name", "username", "password",
@@ -26,7 +27,7 @@ func TestUserGetById(t *testing.T) {
 	mock.ExpectQuery(query).WithArgs(1, 1).WillReturnRows(user)
 	// Preload (association) query
 	mock.ExpectQuery(preloadPostsQuery).WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{}))
-	u, err := repo.GetById(uint(1))
+	u, err := repo.GetById(context.Background(), uint(1))
 
 	assert.NoError(t, err)
 	assert.Equal(t, "Ibka", u.Name)
@@ -41,17 +42,17 @@ func TestUserGetByUsername(t *testing.T) {
 
 	_, db, mock := DB(t)
 
-	repo := repository.NewUserRepository(db)
+	repo := repository.NewUserRepository(db, nil)
 
 	user := sqlmock.NewRows([]string{
 		"id", "name", "username", "password",
 	}).AddRow(id, "Ibka", username, "")
 
-	query := "SELECT (.+) FROM `users` WHERE username = ?"
+	query := "SELECT (.+) FROM `users` WHERE LOWER\\(username\\) = LOWER\\(\\?\\)"
 	mock.ExpectQuery(query).WithArgs(username, 1).WillReturnRows(user)
 	mock.ExpectQuery(preloadPostsQuery).WithArgs(id).WillReturnRows(sqlmock.NewRows([]string{}))
 
-	u, err := repo.GetByUsername(username)
+	u, err := repo.GetByUsername(context.Background(), username)
 
 	assert.NoError(t, err)
 	assert.Equal(t, username, u.Username)
@@ -63,7 +64,7 @@ func TestUserGetAll(t *testing.T) {
 
 	_, db, mock := DB(t)
 
-	repo := repository.NewUserRepository(db)
+	repo := repository.NewUserRepository(db, nil)
 
 	users := sqlmock.NewRows([]string{
 		"id", "name", "username", "password",
@@ -72,7 +73,7 @@ func TestUserGetAll(t *testing.T) {
 	query := "SELECT (.+) FROM `users`"
 	mock.ExpectQuery(query).WillReturnRows(users)
 
-	u, err := repo.GetAll()
+	u, err := repo.GetAll(context.Background())
 
 	assert.NoError(t, err)
 	assert.Equal(t, u[0].Username, firstUserUsername)
@@ -82,7 +83,7 @@ func TestUserGetAll(t *testing.T) {
 func TestUserCreate(t *testing.T) {
 	_, db, mock := DB(t)
 
-	repo := repository.NewUserRepository(db)
+	repo := repository.NewUserRepository(db, nil)
 
 	newUser := models.User{
 		Name:     "Ibka",
@@ -93,34 +94,37 @@ func TestUserCreate(t *testing.T) {
 	query := "INSERT INTO `users`"
 
 	mock.ExpectBegin()
-	mock.ExpectExec(query).WithArgs(newUser.Name, newUser.Username, newUser.Password, AnyTime{}, AnyTime{}, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query).WithArgs(newUser.Name, newUser.Username, newUser.Password, models.RoleUser, AnyTime{}, AnyTime{}, nil).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.Create(newUser)
+	err := repo.Create(context.Background(), &newUser)
 
 	assert.NoError(t, err)
+	assert.Equal(t, uint(1), newUser.ID)
 	assert.Nil(t, mock.ExpectationsWereMet())
 }
 
 func TestUserUpdate(t *testing.T) {
 	_, db, mock := DB(t)
 
-	repo := repository.NewUserRepository(db)
+	repo := repository.NewUserRepository(db, nil)
 
 	updatedUser := models.User{
 		ID:       1,
 		Name:     "Ibka",
 		Username: "ibkaanhar",
 		Password: "123",
+		Role:     models.RoleUser,
 	}
 
-	query := "UPDATE `users`"
+	// Only the given columns are written
+	query := "UPDATE `users` SET `name`=\\?,`updated_at`=\\? WHERE `users`.`deleted_at` IS NULL AND `id` = \\?"
 
 	mock.ExpectBegin()
-	mock.ExpectExec(query).WithArgs(updatedUser.Name, updatedUser.Username, updatedUser.Password, AnyTime{}, AnyTime{}, nil, updatedUser.ID).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(query).WithArgs(updatedUser.Name, AnyTime{}, updatedUser.ID).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.Update(updatedUser)
+	err := repo.Update(context.Background(), updatedUser, "name")
 
 	assert.NoError(t, err)
 	assert.Nil(t, mock.ExpectationsWereMet())
@@ -129,7 +133,7 @@ func TestUserUpdate(t *testing.T) {
 func TestUserDeleteById(t *testing.T) {
 	_, db, mock := DB(t)
 
-	repo := repository.NewUserRepository(db)
+	repo := repository.NewUserRepository(db, nil)
 
 	idToBeDeleted := 1
 
@@ -139,7 +143,7 @@ func TestUserDeleteById(t *testing.T) {
 	mock.ExpectExec(query).WithArgs(AnyTime{}, idToBeDeleted).WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
-	err := repo.DeleteById(uint(idToBeDeleted))
+	err := repo.DeleteById(context.Background(), uint(idToBeDeleted))
 
 	assert.NoError(t, err)
 	assert.Nil(t, mock.ExpectationsWereMet())
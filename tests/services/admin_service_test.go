@@ -0,0 +1,120 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+type adminServiceMocks struct {
+	userRepo     *mock_repository.MockUserRepo
+	postRepo     *mock_repository.MockPostRepo
+	sessionRepo  *mock_repository.MockSessionRepo
+	bookmarkRepo *mock_repository.MockBookmarkRepo
+	auditRepo    *mock_repository.MockAuditRepo
+	outbox       *recordingOutbox
+}
+
+func adminServiceWithMock(t *testing.T) (adminServiceMocks, *services.AdminService) {
+	ctrl := gomock.NewController(t)
+
+	mocks := adminServiceMocks{
+		userRepo:     mock_repository.NewMockUserRepo(ctrl),
+		postRepo:     mock_repository.NewMockPostRepo(ctrl),
+		sessionRepo:  mock_repository.NewMockSessionRepo(ctrl),
+		bookmarkRepo: mock_repository.NewMockBookmarkRepo(ctrl),
+		auditRepo:    mock_repository.NewMockAuditRepo(ctrl),
+	}
+
+	transactor, outbox := transactorWith(repository.Repositories{Users: mocks.userRepo, Posts: mocks.postRepo, Bookmarks: mocks.bookmarkRepo})
+	mocks.outbox = outbox
+
+	return mocks, services.NewAdminService(mocks.userRepo, mocks.postRepo, mocks.sessionRepo, mocks.auditRepo, transactor)
+}
+
+func TestAdminSetRole(t *testing.T) {
+	mocks, service := adminServiceWithMock(t)
+
+	cases := []struct {
+		name     string
+		role     string
+		mockFunc func()
+		err      error
+	}{
+		{
+			"Unknown role",
+			"owner",
+			func() {},
+			&services.ValidationError{Fields: map[string][]string{"role": {"must be either 'user' or 'admin'"}}},
+		},
+		{
+			"Unknown user",
+			models.RoleAdmin,
+			func() {
+				mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+			},
+			gorm.ErrRecordNotFound,
+		},
+		{
+			"Same role",
+			models.RoleAdmin,
+			func() {
+				mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{ID: 2, Username: "jane", Role: models.RoleAdmin}, nil).Times(1)
+			},
+			nil,
+		},
+		{
+			"Promote, sessions are revoked",
+			models.RoleAdmin,
+			func() {
+				mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{ID: 2, Username: "jane", Role: models.RoleUser}, nil).Times(1)
+				mocks.userRepo.EXPECT().Update(gomock.Any(), models.User{ID: 2, Username: "jane", Role: models.RoleAdmin}, "role").Return(nil).Times(1)
+				mocks.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *models.AuditEvent) error {
+					assert.Equal(t, models.AuditAdminRoleChange, event.Action)
+					assert.Equal(t, uint(1), *event.ActorID)
+					assert.Equal(t, "2", event.TargetID)
+					assert.Equal(t, map[string]string{"role": models.RoleUser}, event.Before)
+					assert.Equal(t, map[string]string{"role": models.RoleAdmin}, event.After)
+					return nil
+				}).Times(1)
+				mocks.sessionRepo.EXPECT().RevokeAllByUserId(gomock.Any(), uint(2), uint(0), gomock.Any()).Return(nil).Times(1)
+			},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			err := service.SetRole(context.Background(), 1, "jane", c.role, services.ClientInfo{})
+
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
+
+func TestAdminDeletePost(t *testing.T) {
+	mocks, service := adminServiceWithMock(t)
+
+	t.Run("Post of any author", func(t *testing.T) {
+		mocks.postRepo.EXPECT().GetById(gomock.Any(), 3).Return(&models.Post{ID: 3, UserID: 7}, nil).Times(1)
+		mocks.postRepo.EXPECT().Delete(gomock.Any(), uint(3)).Return(nil).Times(1)
+		mocks.bookmarkRepo.EXPECT().DeleteByPostId(gomock.Any(), uint(3)).Return(nil).Times(1)
+		mocks.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		assert.NoError(t, service.DeletePost(context.Background(), 1, 3, services.ClientInfo{}))
+	})
+
+	t.Run("Unknown post", func(t *testing.T) {
+		mocks.postRepo.EXPECT().GetById(gomock.Any(), 3).Return(nil, gorm.ErrRecordNotFound).Times(1)
+
+		assert.Equal(t, gorm.ErrRecordNotFound, service.DeletePost(context.Background(), 1, 3, services.ClientInfo{}))
+	})
+}
@@ -0,0 +1,189 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/helper"
+	"github.com/simple-crud-go/internal/models"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+func apiKeyServiceWithMock(t *testing.T) (*mock_repository.MockAPIKeyRepo, *services.APIKeyService) {
+	ctrl := gomock.NewController(t)
+
+	apiKeyRepoMock := mock_repository.NewMockAPIKeyRepo(ctrl)
+
+	return apiKeyRepoMock, services.NewAPIKeyService(apiKeyRepoMock)
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	apiKeyRepo, service := apiKeyServiceWithMock(t)
+
+	t.Run("Unknown scope", func(t *testing.T) {
+		_, err := service.CreateKey(context.Background(), 1, helper.DefaultScopes, "ci", []string{"everything"}, nil)
+
+		assert.IsType(t, &services.ValidationError{}, err)
+	})
+
+	t.Run("Expiry in the past", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		_, err := service.CreateKey(context.Background(), 1, helper.DefaultScopes, "ci", nil, &past)
+
+		assert.IsType(t, &services.ValidationError{}, err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		var stored *models.APIKey
+		apiKeyRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, key *models.APIKey) error {
+			stored = key
+			return nil
+		}).Times(1)
+
+		data, err := service.CreateKey(context.Background(), 1, helper.DefaultScopes, "ci", []string{helper.ScopePostsWrite}, nil)
+
+		assert.NoError(t, err)
+		assert.Contains(t, data.Key, "sck_"+stored.Prefix+"_")
+		assert.NotContains(t, stored.KeyHash, data.Key)
+		assert.True(t, helper.CompareTokenHash(stored.KeyHash, data.Key))
+		assert.Equal(t, uint(1), stored.UserID)
+	})
+
+	t.Run("Scope the current token doesn't have", func(t *testing.T) {
+		_, err := service.CreateKey(context.Background(), 1, []string{helper.ScopePostsRead}, "ci", []string{helper.ScopePostsWrite}, nil)
+
+		assert.IsType(t, &services.ValidationError{}, err)
+	})
+
+	t.Run("Scopes of the current token by default", func(t *testing.T) {
+		var stored *models.APIKey
+		apiKeyRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, key *models.APIKey) error {
+			stored = key
+			return nil
+		}).Times(1)
+
+		_, err := service.CreateKey(context.Background(), 1, []string{helper.ScopePostsRead, helper.ScopeAdmin}, "ci", nil, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{helper.ScopePostsRead}, []string(stored.Scopes))
+	})
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	var (
+		plainKey            = "sck_0a1b2c3d_secretsecretsecret"
+		past                = time.Now().Add(-time.Hour)
+		apiKeyRepo, service = apiKeyServiceWithMock(t)
+	)
+
+	cases := []struct {
+		name     string
+		plainKey string
+		mockFunc func()
+		err      error
+	}{
+		{
+			"Malformed key",
+			"not-a-key",
+			func() {},
+			services.ErrInvalidAPIKey,
+		},
+		{
+			"Unknown prefix",
+			plainKey,
+			func() {
+				apiKeyRepo.EXPECT().GetByPrefix(gomock.Any(), "0a1b2c3d").Return(nil, gorm.ErrRecordNotFound).Times(1)
+			},
+			services.ErrInvalidAPIKey,
+		},
+		{
+			"Wrong secret",
+			plainKey,
+			func() {
+				apiKeyRepo.EXPECT().GetByPrefix(gomock.Any(), "0a1b2c3d").Return(&models.APIKey{ID: 1, KeyHash: helper.HashToken("other")}, nil).Times(1)
+			},
+			services.ErrInvalidAPIKey,
+		},
+		{
+			"Revoked key",
+			plainKey,
+			func() {
+				apiKeyRepo.EXPECT().GetByPrefix(gomock.Any(), "0a1b2c3d").Return(&models.APIKey{ID: 1, KeyHash: helper.HashToken(plainKey), RevokedAt: &past}, nil).Times(1)
+			},
+			services.ErrInvalidAPIKey,
+		},
+		{
+			"Expired key",
+			plainKey,
+			func() {
+				apiKeyRepo.EXPECT().GetByPrefix(gomock.Any(), "0a1b2c3d").Return(&models.APIKey{ID: 1, KeyHash: helper.HashToken(plainKey), ExpiresAt: &past}, nil).Times(1)
+			},
+			services.ErrInvalidAPIKey,
+		},
+		{
+			"Success",
+			plainKey,
+			func() {
+				apiKeyRepo.EXPECT().GetByPrefix(gomock.Any(), "0a1b2c3d").Return(&models.APIKey{ID: 1, UserID: 2, KeyHash: helper.HashToken(plainKey)}, nil).Times(1)
+				apiKeyRepo.EXPECT().TouchLastUsed(gomock.Any(), uint(1), gomock.Any()).Return(nil).Times(1)
+			},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			key, err := service.Authenticate(context.Background(), c.plainKey)
+
+			assert.Equal(t, c.err, err)
+			if c.err == nil {
+				assert.Equal(t, uint(2), key.UserID)
+				assert.NotNil(t, key.LastUsedAt)
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	apiKeyRepo, service := apiKeyServiceWithMock(t)
+
+	cases := []struct {
+		name     string
+		mockFunc func()
+		err      error
+	}{
+		{
+			"Key owned by another user",
+			func() {
+				apiKeyRepo.EXPECT().GetById(gomock.Any(), uint(1)).Return(&models.APIKey{ID: 1, UserID: 3}, nil).Times(1)
+			},
+			gorm.ErrRecordNotFound,
+		},
+		{
+			"Success",
+			func() {
+				apiKeyRepo.EXPECT().GetById(gomock.Any(), uint(1)).Return(&models.APIKey{ID: 1, UserID: 2}, nil).Times(1)
+				apiKeyRepo.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, key *models.APIKey) error {
+					assert.NotNil(t, key.RevokedAt)
+					return nil
+				}).Times(1)
+			},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			err := service.RevokeKey(context.Background(), 2, 1)
+
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
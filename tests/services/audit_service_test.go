@@ -0,0 +1,146 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	mock_helper "github.com/simple-crud-go/internal/helper/mocks"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// recordedEvents captures the events written to the audit repository mock.
+func recordedEvents(auditRepo *mock_repository.MockAuditRepo) *[]models.AuditEvent {
+	events := &[]models.AuditEvent{}
+
+	auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, event *models.AuditEvent) error {
+		*events = append(*events, *event)
+		return nil
+	}).AnyTimes()
+
+	return events
+}
+
+func TestAuditSearchLimit(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		auditRepo = mock_repository.NewMockAuditRepo(ctrl)
+		service   = services.NewAuditService(auditRepo)
+	)
+
+	cases := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{"Default limit", 0, 50},
+		{"Custom limit", 10, 10},
+		{"Limit is capped", 1000, 200},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			auditRepo.EXPECT().Find(gomock.Any(), repository.AuditFilter{Action: models.AuditLogin, Limit: c.want}).Return([]models.AuditEvent{}, nil).Times(1)
+
+			_, err := service.Search(context.Background(), repository.AuditFilter{Action: models.AuditLogin, Limit: c.limit})
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAuditFailedLogin(t *testing.T) {
+	mocks, service := authServiceWithMock(t)
+	events := recordedEvents(mocks.auditRepo)
+	client := services.ClientInfo{IP: "203.0.113.7", UserAgent: "curl/8.0"}
+
+	t.Run("Unknown username", func(t *testing.T) {
+		mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "ghost").Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+
+		_, err := service.Login(context.Background(), "ghost", "password", client)
+
+		assert.Error(t, err)
+		assert.Equal(t, models.AuditEvent{
+			Action:     models.AuditLoginFailed,
+			TargetType: models.AuditTargetUser,
+			IP:         "203.0.113.7",
+			UserAgent:  "curl/8.0",
+			After:      map[string]string{"username": "ghost"},
+		}, (*events)[len(*events)-1])
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{ID: 2, Password: "hashed"}, nil).Times(1)
+		mocks.passwordCrypto.EXPECT().ComparePassword("hashed", "wrong").Return(bcrypt.ErrMismatchedHashAndPassword).Times(1)
+
+		_, err := service.Login(context.Background(), "jane", "wrong", client)
+
+		assert.Error(t, err)
+		event := (*events)[len(*events)-1]
+		assert.Equal(t, models.AuditLoginFailed, event.Action)
+		assert.Equal(t, "2", event.TargetID)
+		assert.Nil(t, event.ActorID)
+	})
+}
+
+func TestAuditUserChanges(t *testing.T) {
+	var (
+		ctrl           = gomock.NewController(t)
+		userRepo       = mock_repository.NewMockUserRepo(ctrl)
+		auditRepo      = mock_repository.NewMockAuditRepo(ctrl)
+		passwordCrypto = mock_helper.NewMockPasswordCrypto(ctrl)
+		passwordPolicy = mock_helper.NewMockPasswordPolicy(ctrl)
+		service        = services.NewUserService(userRepo, mock_repository.NewMockFollowRepo(ctrl), auditRepo, passwordCrypto, passwordPolicy, &fakeTransactor{repos: repository.Repositories{Users: userRepo, Outbox: &recordingOutbox{}}})
+		events         = recordedEvents(auditRepo)
+	)
+
+	userRepo.EXPECT().GetById(gomock.Any(), uint(2)).Return(&models.User{ID: 2, Username: "jane", Name: "Jane"}, nil).Times(2)
+	userRepo.EXPECT().GetByUsername(gomock.Any(), "janet").Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+	passwordPolicy.EXPECT().Validate("NewPassw0rd", "janet", "Jane").Return(nil, nil).Times(1)
+	passwordCrypto.EXPECT().HashPassword("NewPassw0rd").Return("hashed", nil).Times(1)
+	userRepo.EXPECT().Update(gomock.Any(), gomock.Any(), "username", "password").Return(nil).Times(1)
+
+	err := service.UpdateUser(context.Background(), 2, "janet", "", "NewPassw0rd", services.ClientInfo{})
+
+	assert.NoError(t, err)
+	assert.Len(t, *events, 2)
+	assert.Equal(t, models.AuditUsernameChange, (*events)[0].Action)
+	assert.Equal(t, map[string]string{"username": "jane"}, (*events)[0].Before)
+	assert.Equal(t, map[string]string{"username": "janet"}, (*events)[0].After)
+	assert.Equal(t, models.AuditPasswordChange, (*events)[1].Action)
+	assert.Nil(t, (*events)[1].After, "the password must never be recorded")
+}
+
+func TestAuditPostUpdate(t *testing.T) {
+	var (
+		ctrl      = gomock.NewController(t)
+		postRepo  = mock_repository.NewMockPostRepo(ctrl)
+		userRepo  = mock_repository.NewMockUserRepo(ctrl)
+		auditRepo = mock_repository.NewMockAuditRepo(ctrl)
+		service   = services.NewPostService(postRepo, userRepo, mock_repository.NewMockReactionRepo(ctrl), auditRepo, &fakeTransactor{repos: repository.Repositories{Posts: postRepo, Outbox: &recordingOutbox{}}})
+		events    = recordedEvents(auditRepo)
+	)
+
+	postRepo.EXPECT().GetById(gomock.Any(), 3).Return(&models.Post{ID: 3, UserID: 2, Title: "Old", Body: "Body"}, nil).Times(1)
+	postRepo.EXPECT().GetById(gomock.Any(), 3).Return(&models.Post{ID: 3, UserID: 2, Title: "Old", Body: "Body"}, nil).Times(1)
+	postRepo.EXPECT().Update(gomock.Any(), gomock.Any(), "title").Return(nil).Times(1)
+
+	err := service.UpdatePost(context.Background(), 2, 3, "New", "", services.ClientInfo{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.AuditEvent{
+		Action:     models.AuditPostUpdate,
+		ActorID:    (*events)[0].ActorID,
+		TargetType: models.AuditTargetPost,
+		TargetID:   "3",
+		Before:     map[string]string{"title": "Old", "body": "Body"},
+		After:      map[string]string{"title": "New", "body": "Body"},
+	}, (*events)[0])
+	assert.Equal(t, uint(2), *(*events)[0].ActorID)
+}
@@ -0,0 +1,239 @@
+package services_test
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/database"
+	"github.com/simple-crud-go/internal/database/migrations"
+	"github.com/simple-crud-go/internal/helper"
+	mock_helper "github.com/simple-crud-go/internal/helper/mocks"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+type authServiceMocks struct {
+	userRepo       *mock_repository.MockUserRepo
+	sessionRepo    *mock_repository.MockSessionRepo
+	auditRepo      *mock_repository.MockAuditRepo
+	passwordCrypto *mock_helper.MockPasswordCrypto
+	passwordPolicy *mock_helper.MockPasswordPolicy
+	jwtHelper      *mock_helper.MockJWTHelper
+	outbox         *recordingOutbox
+}
+
+func authServiceWithMock(t *testing.T) (authServiceMocks, *services.AuthService) {
+	ctrl := gomock.NewController(t)
+
+	mocks := authServiceMocks{
+		userRepo:       mock_repository.NewMockUserRepo(ctrl),
+		sessionRepo:    mock_repository.NewMockSessionRepo(ctrl),
+		auditRepo:      mock_repository.NewMockAuditRepo(ctrl),
+		passwordCrypto: mock_helper.NewMockPasswordCrypto(ctrl),
+		passwordPolicy: mock_helper.NewMockPasswordPolicy(ctrl),
+		jwtHelper:      mock_helper.NewMockJWTHelper(ctrl),
+	}
+
+	transactor, outbox := transactorWith(repository.Repositories{Users: mocks.userRepo})
+	mocks.outbox = outbox
+
+	return mocks, services.NewAuthService(mocks.userRepo, mocks.sessionRepo, mocks.auditRepo, mocks.passwordCrypto, mocks.passwordPolicy, mocks.jwtHelper, transactor)
+}
+
+func TestLoginScopes(t *testing.T) {
+	mocks, service := authServiceWithMock(t)
+
+	cases := []struct {
+		name   string
+		role   string
+		scopes []string
+	}{
+		{"Regular user", models.RoleUser, append(append([]string{}, helper.DefaultScopes...), helper.ScopeCredentials)},
+		{"Admin", models.RoleAdmin, append(append([]string{}, helper.DefaultScopes...), helper.ScopeCredentials, helper.ScopeAdmin)},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{ID: 2, Password: "hashed", Role: c.role}, nil).Times(1)
+			mocks.passwordCrypto.EXPECT().ComparePassword("hashed", "password").Return(nil).Times(1)
+			mocks.sessionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, session *models.Session) error {
+				session.ID = 9
+				return nil
+			}).Times(1)
+			mocks.jwtHelper.EXPECT().CreateTokenWithOptions(2, gomock.Any()).DoAndReturn(func(id int, options helper.TokenOptions) (string, error) {
+				assert.Equal(t, c.scopes, options.Scopes)
+				assert.Equal(t, "9", options.SessionID)
+				return "token", nil
+			}).Times(1)
+			mocks.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+			token, err := service.Login(context.Background(), "jane", "password", services.ClientInfo{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, "token", token)
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		mocks, service := authServiceWithMock(t)
+
+		mocks.passwordPolicy.EXPECT().Validate("Passw0rd", "jane", "Jane").Return(nil, nil).Times(1)
+		mocks.passwordCrypto.EXPECT().HashPassword("Passw0rd").Return("hashed", nil).Times(1)
+		mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(nil, gorm.ErrRecordNotFound).Times(1)
+		mocks.userRepo.EXPECT().Create(gomock.Any(), &models.User{Name: "Jane", Username: "jane", Password: "hashed"}).DoAndReturn(func(ctx context.Context, user *models.User) error {
+			user.ID = 2
+			return nil
+		}).Times(1)
+		mocks.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		mocks.sessionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		mocks.jwtHelper.EXPECT().CreateTokenWithOptions(2, gomock.Any()).Return("token", nil).Times(1)
+
+		data, err := service.Register(context.Background(), "Jane", "jane", "Passw0rd", services.ClientInfo{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(2), data.User.ID)
+		assert.Len(t, mocks.outbox.events, 1)
+		assert.Equal(t, services.EventUserRegistered, mocks.outbox.events[0].Type)
+		assert.Equal(t, uint(2), mocks.outbox.events[0].UserID)
+	})
+
+	t.Run("Username taken", func(t *testing.T) {
+		mocks, service := authServiceWithMock(t)
+
+		mocks.passwordPolicy.EXPECT().Validate("Passw0rd", "jane", "Jane").Return(nil, nil).Times(1)
+		mocks.passwordCrypto.EXPECT().HashPassword("Passw0rd").Return("hashed", nil).Times(1)
+		mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{ID: 2, Username: "jane"}, nil).Times(1)
+
+		data, err := service.Register(context.Background(), "Jane", "jane", "Passw0rd", services.ClientInfo{})
+
+		assert.ErrorIs(t, err, services.ErrUserExist)
+		assert.Nil(t, data)
+		assert.Empty(t, mocks.outbox.events)
+	})
+}
+
+func TestRegisterConcurrently(t *testing.T) {
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	if _, err = database.NewMigrator(db, migrations.All(), time.Second).Up(0); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		ctrl           = gomock.NewController(t)
+		passwordCrypto = mock_helper.NewMockPasswordCrypto(ctrl)
+		passwordPolicy = mock_helper.NewMockPasswordPolicy(ctrl)
+		jwtHelper      = mock_helper.NewMockJWTHelper(ctrl)
+		service        = services.NewAuthService(repository.NewUserRepository(db, nil), repository.NewSessionRepository(db), repository.NewAuditRepository(db), passwordCrypto, passwordPolicy, jwtHelper, repository.NewTransactor(db, nil))
+	)
+
+	passwordPolicy.EXPECT().Validate(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	passwordCrypto.EXPECT().HashPassword(gomock.Any()).Return("hashed", nil).AnyTimes()
+	jwtHelper.EXPECT().CreateTokenWithOptions(gomock.Any(), gomock.Any()).Return("token", nil).AnyTimes()
+
+	// The usernames only differ in case, they are the same username
+	usernames := []string{"jane", "Jane", "JANE", "jAnE"}
+	errs := make([]error, 12)
+
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = service.Register(context.Background(), "Jane", usernames[i%len(usernames)], "Passw0rd", services.ClientInfo{})
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+
+		assert.ErrorIs(t, err, services.ErrUserExist)
+	}
+	assert.Equal(t, 1, succeeded)
+
+	var count int64
+	assert.NoError(t, db.Model(&models.User{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestCreateScopedToken(t *testing.T) {
+	mocks, service := authServiceWithMock(t)
+
+	granted := []string{helper.ScopePostsRead, helper.ScopePostsWrite}
+
+	cases := []struct {
+		name      string
+		scopes    []string
+		expiresIn time.Duration
+		mockFunc  func()
+		err       error
+	}{
+		{
+			"Scope not granted to the caller",
+			[]string{helper.ScopeUsersWrite},
+			0,
+			func() {},
+			&services.ValidationError{Fields: map[string][]string{"scopes": {"can't grant scopes the current token doesn't have"}}},
+		},
+		{
+			"Scope that can't be delegated",
+			[]string{helper.ScopeCredentials},
+			0,
+			func() {},
+			&services.ValidationError{Fields: map[string][]string{"scopes": {"scope 'credentials' can't be delegated"}}},
+		},
+		{
+			"Lifetime too long",
+			[]string{helper.ScopePostsRead},
+			365 * 24 * time.Hour,
+			func() {},
+			&services.ValidationError{Fields: map[string][]string{"expires_in": {"must be between 1 and 604800 seconds"}}},
+		},
+		{
+			"Success",
+			[]string{helper.ScopePostsRead},
+			time.Hour,
+			func() {
+				mocks.sessionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				mocks.jwtHelper.EXPECT().CreateTokenWithOptions(2, gomock.Any()).DoAndReturn(func(id int, options helper.TokenOptions) (string, error) {
+					assert.Equal(t, []string{helper.ScopePostsRead}, options.Scopes)
+					assert.Equal(t, time.Hour, options.TTL)
+					return "token", nil
+				}).Times(1)
+			},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			_, err := service.CreateScopedToken(context.Background(), 2, granted, c.scopes, c.expiresIn, services.ClientInfo{})
+
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
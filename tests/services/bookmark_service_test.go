@@ -0,0 +1,79 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/simple-crud-go/internal/models"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+func bookmarkServiceWithMock(t *testing.T) (*mock_repository.MockBookmarkRepo, *mock_repository.MockPostRepo, *services.BookmarkService) {
+	ctrl := gomock.NewController(t)
+
+	bookmarkRepoMock := mock_repository.NewMockBookmarkRepo(ctrl)
+	postRepoMock := mock_repository.NewMockPostRepo(ctrl)
+
+	return bookmarkRepoMock, postRepoMock, services.NewBookmarkService(bookmarkRepoMock, postRepoMock)
+}
+
+func TestBookmark(t *testing.T) {
+	bookmarkRepo, postRepo, service := bookmarkServiceWithMock(t)
+
+	cases := []struct {
+		name       string
+		collection string
+		mockFunc   func()
+		err        error
+	}{
+		{
+			"Collection name too long",
+			strings.Repeat("a", 65),
+			func() {},
+			&services.ValidationError{Fields: map[string][]string{"collection": {"must be at most 64 characters"}}},
+		},
+		{
+			"Post not found",
+			"",
+			func() {
+				postRepo.EXPECT().GetById(gomock.Any(), 3).Return(nil, gorm.ErrRecordNotFound).Times(1)
+			},
+			gorm.ErrRecordNotFound,
+		},
+		{
+			"Success",
+			" Weekend ",
+			func() {
+				postRepo.EXPECT().GetById(gomock.Any(), 3).Return(&models.Post{ID: 3}, nil).Times(1)
+				bookmarkRepo.EXPECT().Save(gomock.Any(), &models.Bookmark{UserID: 1, PostID: 3, Collection: "Weekend"}).Return(nil).Times(1)
+			},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			err := service.Bookmark(context.Background(), 1, 3, c.collection)
+
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
+
+func TestGetBookmarks(t *testing.T) {
+	bookmarkRepo, _, service := bookmarkServiceWithMock(t)
+	collection := "Weekend"
+
+	bookmarkRepo.EXPECT().GetByUserId(gomock.Any(), uint(1), &collection, uint(8), 20).Return([]models.Bookmark{{ID: 7, PostID: 3}}, nil).Times(1)
+
+	bookmarks, err := service.GetBookmarks(context.Background(), 1, &collection, 8, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, bookmarks, 1)
+}
@@ -0,0 +1,138 @@
+package services_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/simple-crud-go/internal/models"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+func collaborationHubWithMock(t *testing.T) (*mock_repository.MockPostRepo, *services.CollaborationHub) {
+	ctrl := gomock.NewController(t)
+
+	postRepoMock := mock_repository.NewMockPostRepo(ctrl)
+
+	return postRepoMock, services.NewCollaborationHub(postRepoMock)
+}
+
+// nextMessage the next message queued for the client.
+func nextMessage(t *testing.T, client *services.CollaborationClient) services.CollaborationMessage {
+	select {
+	case raw := <-client.Send:
+		var message services.CollaborationMessage
+		assert.NoError(t, json.Unmarshal(raw, &message))
+		return message
+	default:
+		t.Fatal("no message queued")
+		return services.CollaborationMessage{}
+	}
+}
+
+func TestCollaborationSubscribe(t *testing.T) {
+	var (
+		postRepo, hub = collaborationHubWithMock(t)
+		author        = hub.Connect(2, "jane")
+		viewer        = hub.Connect(3, "john")
+	)
+
+	t.Run("Unknown post", func(t *testing.T) {
+		postRepo.EXPECT().GetById(gomock.Any(), 9).Return(nil, gorm.ErrRecordNotFound).Times(1)
+
+		hub.Handle(context.Background(), viewer, []byte(`{"type":"subscribe","post_id":9}`))
+
+		assert.Equal(t, services.CollaborationError, nextMessage(t, viewer).Type)
+	})
+
+	t.Run("Presence", func(t *testing.T) {
+		postRepo.EXPECT().GetById(gomock.Any(), 1).Return(&models.Post{ID: 1, UserID: 2}, nil).Times(2)
+
+		hub.Handle(context.Background(), author, []byte(`{"type":"subscribe","post_id":1}`))
+		nextMessage(t, author)
+		hub.Handle(context.Background(), viewer, []byte(`{"type":"subscribe","post_id":1}`))
+
+		presence := nextMessage(t, author)
+		assert.Equal(t, services.CollaborationPresence, presence.Type)
+		assert.Equal(t, []services.CollaborationUser{{ID: 2, Username: "jane"}, {ID: 3, Username: "john"}}, presence.Users)
+		assert.Equal(t, presence, nextMessage(t, viewer))
+	})
+
+	t.Run("Typing is sent to the others", func(t *testing.T) {
+		hub.Handle(context.Background(), viewer, []byte(`{"type":"typing","post_id":1}`))
+
+		typing := nextMessage(t, author)
+		assert.Equal(t, services.CollaborationTyping, typing.Type)
+		assert.Equal(t, uint(3), typing.User.ID)
+		assert.True(t, *typing.Typing)
+		assert.Empty(t, viewer.Send)
+	})
+
+	t.Run("Only the author edits", func(t *testing.T) {
+		hub.Handle(context.Background(), viewer, []byte(`{"type":"edit","post_id":1,"title":"Mine now"}`))
+		assert.Equal(t, "only the author can edit the post", nextMessage(t, viewer).Message)
+
+		hub.Handle(context.Background(), author, []byte(`{"type":"edit","post_id":1,"title":"Draft"}`))
+		edit := nextMessage(t, viewer)
+		assert.Equal(t, services.CollaborationEdit, edit.Type)
+		assert.Equal(t, "Draft", *edit.Title)
+	})
+
+	t.Run("Disconnecting updates the presence", func(t *testing.T) {
+		hub.Disconnect(viewer)
+
+		_, open := <-viewer.Send
+		assert.False(t, open)
+		assert.Equal(t, []services.CollaborationUser{{ID: 2, Username: "jane"}}, nextMessage(t, author).Users)
+
+		// Disconnecting twice is a no-op
+		hub.Disconnect(viewer)
+	})
+}
+
+func TestCollaborationPostDeleted(t *testing.T) {
+	var (
+		postRepo, hub = collaborationHubWithMock(t)
+		viewer        = hub.Connect(3, "john")
+	)
+
+	postRepo.EXPECT().GetById(gomock.Any(), 1).Return(&models.Post{ID: 1, UserID: 2}, nil).Times(1)
+	hub.Handle(context.Background(), viewer, []byte(`{"type":"subscribe","post_id":1}`))
+	nextMessage(t, viewer)
+
+	hub.Publish(context.Background(), services.PostDeleted{EventMeta: services.EventMeta{PostID: 1}})
+
+	assert.Equal(t, services.EventPostDeleted, nextMessage(t, viewer).Type)
+
+	hub.Handle(context.Background(), viewer, []byte(`{"type":"typing","post_id":1}`))
+	assert.Equal(t, "not subscribed to the post", nextMessage(t, viewer).Message)
+}
+
+func TestCollaborationSlowClient(t *testing.T) {
+	var (
+		postRepo, hub = collaborationHubWithMock(t)
+		author        = hub.Connect(2, "jane")
+		slow          = hub.Connect(3, "john")
+	)
+
+	postRepo.EXPECT().GetById(gomock.Any(), 1).Return(&models.Post{ID: 1, UserID: 2}, nil).Times(2)
+	hub.Handle(context.Background(), slow, []byte(`{"type":"subscribe","post_id":1}`))
+	hub.Handle(context.Background(), author, []byte(`{"type":"subscribe","post_id":1}`))
+
+	for i := 0; i < 100; i++ {
+		hub.Handle(context.Background(), author, []byte(fmt.Sprintf(`{"type":"edit","post_id":1,"body":"draft %d"}`, i)))
+		// The author keeps up
+		for len(author.Send) > 0 {
+			<-author.Send
+		}
+	}
+
+	assert.True(t, slow.Slow)
+	for range slow.Send {
+	}
+}
@@ -0,0 +1,136 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+type followMocks struct {
+	followRepo   *mock_repository.MockFollowRepo
+	userRepo     *mock_repository.MockUserRepo
+	postRepo     *mock_repository.MockPostRepo
+	reactionRepo *mock_repository.MockReactionRepo
+	outbox       *recordingOutbox
+}
+
+func followServiceWithMock(t *testing.T) (followMocks, *services.FollowService) {
+	ctrl := gomock.NewController(t)
+
+	mocks := followMocks{
+		followRepo:   mock_repository.NewMockFollowRepo(ctrl),
+		userRepo:     mock_repository.NewMockUserRepo(ctrl),
+		postRepo:     mock_repository.NewMockPostRepo(ctrl),
+		reactionRepo: mock_repository.NewMockReactionRepo(ctrl),
+	}
+
+	transactor, outbox := transactorWith(repository.Repositories{Follows: mocks.followRepo})
+	mocks.outbox = outbox
+
+	return mocks, services.NewFollowService(mocks.followRepo, mocks.userRepo, mocks.postRepo, mocks.reactionRepo, transactor)
+}
+
+func TestFollow(t *testing.T) {
+	mocks, service := followServiceWithMock(t)
+
+	cases := []struct {
+		name     string
+		mockFunc func()
+		err      error
+	}{
+		{
+			"Unknown user",
+			func() {
+				mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "ghost").Return(nil, gorm.ErrRecordNotFound).Times(1)
+			},
+			gorm.ErrRecordNotFound,
+		},
+		{
+			"Following yourself",
+			func() {
+				mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "ghost").Return(&models.User{ID: 1}, nil).Times(1)
+			},
+			&services.ValidationError{Fields: map[string][]string{"username": {"can't follow yourself"}}},
+		},
+		{
+			"Already following",
+			func() {
+				mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "ghost").Return(&models.User{ID: 2}, nil).Times(1)
+				mocks.followRepo.EXPECT().Create(gomock.Any(), &models.Follow{FollowerID: 1, FolloweeID: 2}).Return(false, nil).Times(1)
+			},
+			nil,
+		},
+		{
+			"Success",
+			func() {
+				mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "ghost").Return(&models.User{ID: 2}, nil).Times(1)
+				mocks.followRepo.EXPECT().Create(gomock.Any(), &models.Follow{FollowerID: 1, FolloweeID: 2}).Return(true, nil).Times(1)
+			},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			err := service.Follow(context.Background(), 1, "ghost")
+
+			assert.Equal(t, c.err, err)
+		})
+	}
+
+	// Only the new follow is published
+	assert.Equal(t, []models.OutboxEvent{{Type: services.EventUserFollowed, ActorID: 1, UserID: 2, Data: `{"actor_id":1,"user_id":2}`}}, mocks.outbox.events)
+}
+
+func TestUnfollow(t *testing.T) {
+	mocks, service := followServiceWithMock(t)
+
+	mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{ID: 2}, nil).Times(1)
+	mocks.followRepo.EXPECT().Delete(gomock.Any(), uint(1), uint(2)).Return(nil).Times(1)
+
+	assert.NoError(t, service.Unfollow(context.Background(), 1, "jane"))
+}
+
+func TestGetFollowers(t *testing.T) {
+	mocks, service := followServiceWithMock(t)
+
+	mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{ID: 2}, nil).Times(1)
+	mocks.followRepo.EXPECT().GetFollowers(gomock.Any(), uint(2), uint(10), 100).Return([]models.Follow{{ID: 9, FollowerID: 1}}, nil).Times(1)
+
+	follows, err := service.GetFollowers(context.Background(), "jane", 10, 1000)
+
+	assert.NoError(t, err)
+	assert.Len(t, follows, 1)
+}
+
+func TestGetFeed(t *testing.T) {
+	mocks, service := followServiceWithMock(t)
+
+	cases := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{"Default limit", 0, 20},
+		{"Custom limit", 5, 5},
+		{"Limit is capped", 500, 100},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mocks.postRepo.EXPECT().GetFeed(gomock.Any(), uint(1), uint(0), c.want).Return([]models.Post{}, nil).Times(1)
+
+			_, err := service.GetFeed(context.Background(), 1, 0, c.limit)
+
+			assert.NoError(t, err)
+		})
+	}
+}
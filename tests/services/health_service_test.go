@@ -0,0 +1,57 @@
+package services_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/simple-crud-go/api"
+	"github.com/simple-crud-go/internal/database"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestHealthCheck(t *testing.T) {
+	db, err := database.Open(database.Config{Driver: database.DriverSQLite, Path: filepath.Join(t.TempDir(), "test.db"), Pool: database.PoolConfig{MaxOpenConns: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Healthy", func(t *testing.T) {
+		health := services.NewHealthService(db, nil).Check(context.Background())
+
+		assert.Equal(t, api.HealthOK, health.Status)
+		assert.Len(t, health.Databases, 1)
+		assert.True(t, health.Databases[0].Healthy)
+		assert.Equal(t, 4, health.Databases[0].Pool.MaxOpen)
+		assert.Equal(t, 1, health.Databases[0].Pool.Open)
+	})
+
+	t.Run("Replica down", func(t *testing.T) {
+		// Nothing listens on the port
+		replica, err := database.OpenReplica(database.DriverMySQL, "root@tcp(127.0.0.1:1)/simple", database.PoolConfig{})
+		assert.NoError(t, err)
+
+		replicas := database.NewReplicas([]*gorm.DB{replica})
+		replicas.CheckHealth(context.Background())
+
+		health := services.NewHealthService(db, replicas).Check(context.Background())
+
+		assert.Equal(t, api.HealthDegraded, health.Status)
+		assert.Len(t, health.Databases, 2)
+		assert.Equal(t, "replica 1", health.Databases[1].Name)
+		assert.False(t, health.Databases[1].Healthy)
+	})
+
+	t.Run("Primary down", func(t *testing.T) {
+		sqlDB, err := db.DB()
+		assert.NoError(t, err)
+		assert.NoError(t, sqlDB.Close())
+
+		health := services.NewHealthService(db, nil).Check(context.Background())
+
+		assert.Equal(t, api.HealthUnavailable, health.Status)
+		assert.False(t, health.Databases[0].Healthy)
+	})
+}
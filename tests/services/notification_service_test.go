@@ -0,0 +1,117 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+func notificationServiceWithMock(t *testing.T) (*mock_repository.MockNotificationRepo, *services.NotificationService, *recordingOutbox) {
+	ctrl := gomock.NewController(t)
+
+	notificationRepoMock := mock_repository.NewMockNotificationRepo(ctrl)
+	transactor, outbox := transactorWith(repository.Repositories{Notifications: notificationRepoMock})
+
+	return notificationRepoMock, services.NewNotificationService(notificationRepoMock, transactor), outbox
+}
+
+func TestNotificationPublish(t *testing.T) {
+	var (
+		notificationRepo, service, events = notificationServiceWithMock(t)
+		reacted                           = services.PostReacted{EventMeta: services.EventMeta{ActorID: 1, UserID: 2, PostID: 3}, Kind: models.ReactionLike}
+	)
+
+	t.Run("Own action", func(t *testing.T) {
+		assert.NoError(t, service.Publish(context.Background(), services.PostReacted{EventMeta: services.EventMeta{ActorID: 2, UserID: 2, PostID: 3}}))
+	})
+
+	t.Run("Type turned off", func(t *testing.T) {
+		notificationRepo.EXPECT().GetPreferences(gomock.Any(), uint(2)).Return([]models.NotificationPreference{{UserID: 2, Type: models.NotificationReaction, Enabled: false}}, nil).Times(1)
+
+		assert.NoError(t, service.Publish(context.Background(), reacted))
+	})
+
+	t.Run("Preferences can't be read", func(t *testing.T) {
+		notificationRepo.EXPECT().GetPreferences(gomock.Any(), uint(2)).Return(nil, errUnexpected).Times(1)
+
+		assert.ErrorIs(t, service.Publish(context.Background(), reacted), errUnexpected)
+	})
+
+	t.Run("Notified", func(t *testing.T) {
+		actorId, postId := uint(1), uint(3)
+		notificationRepo.EXPECT().GetPreferences(gomock.Any(), uint(2)).Return([]models.NotificationPreference{{UserID: 2, Type: models.NotificationFollow, Enabled: false}}, nil).Times(1)
+		notificationRepo.EXPECT().Create(gomock.Any(), &models.Notification{UserID: 2, Type: models.NotificationReaction, ActorID: &actorId, PostID: &postId}).Return(nil).Times(1)
+
+		assert.NoError(t, service.Publish(context.Background(), reacted))
+
+		assert.Len(t, events.events, 1)
+		assert.Equal(t, services.EventNotificationCreated, events.events[0].Type)
+	})
+
+	t.Run("Notification can't be created", func(t *testing.T) {
+		notificationRepo.EXPECT().GetPreferences(gomock.Any(), uint(2)).Return(nil, nil).Times(1)
+		notificationRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errUnexpected).Times(1)
+
+		assert.ErrorIs(t, service.Publish(context.Background(), reacted), errUnexpected)
+	})
+}
+
+func TestNotificationMarkRead(t *testing.T) {
+	notificationRepo, service, _ := notificationServiceWithMock(t)
+
+	t.Run("Notification of another user", func(t *testing.T) {
+		notificationRepo.EXPECT().GetById(gomock.Any(), uint(5)).Return(&models.Notification{ID: 5, UserID: 3}, nil).Times(1)
+
+		assert.Equal(t, gorm.ErrRecordNotFound, service.MarkRead(context.Background(), 2, 5))
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		notificationRepo.EXPECT().GetById(gomock.Any(), uint(5)).Return(&models.Notification{ID: 5, UserID: 2}, nil).Times(1)
+		notificationRepo.EXPECT().MarkRead(gomock.Any(), uint(5), gomock.Any()).Return(nil).Times(1)
+
+		assert.NoError(t, service.MarkRead(context.Background(), 2, 5))
+	})
+}
+
+func TestGetNotifications(t *testing.T) {
+	notificationRepo, service, _ := notificationServiceWithMock(t)
+
+	notificationRepo.EXPECT().GetByUserId(gomock.Any(), uint(2), true, uint(0), 20).Return([]models.Notification{{ID: 5}}, nil).Times(1)
+	notificationRepo.EXPECT().CountUnread(gomock.Any(), uint(2)).Return(int64(4), nil).Times(1)
+
+	data, err := service.GetNotifications(context.Background(), 2, true, 0, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, data.Notifications, 1)
+	assert.Equal(t, int64(4), data.UnreadCount)
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	notificationRepo, service, _ := notificationServiceWithMock(t)
+
+	t.Run("Defaults to enabled", func(t *testing.T) {
+		notificationRepo.EXPECT().GetPreferences(gomock.Any(), uint(2)).Return([]models.NotificationPreference{{UserID: 2, Type: models.NotificationFollow, Enabled: false}}, nil).Times(1)
+
+		preferences, err := service.GetPreferences(context.Background(), 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]bool{models.NotificationFollow: false, models.NotificationReaction: true}, preferences)
+	})
+
+	t.Run("Unknown type", func(t *testing.T) {
+		assert.IsType(t, &services.ValidationError{}, service.SetPreference(context.Background(), 2, "comment", false))
+	})
+
+	t.Run("Saved", func(t *testing.T) {
+		notificationRepo.EXPECT().SavePreference(gomock.Any(), &models.NotificationPreference{UserID: 2, Type: models.NotificationFollow, Enabled: true}).Return(nil).Times(1)
+
+		assert.NoError(t, service.SetPreference(context.Background(), 2, models.NotificationFollow, true))
+	})
+}
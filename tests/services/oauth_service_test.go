@@ -0,0 +1,318 @@
+package services_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/helper"
+	mock_helper "github.com/simple-crud-go/internal/helper/mocks"
+	"github.com/simple-crud-go/internal/models"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+func oauthServiceWithMock(t *testing.T) (*mock_repository.MockOAuthRepo, *mock_helper.MockJWTHelper, *services.OAuthService) {
+	ctrl := gomock.NewController(t)
+
+	oauthRepoMock := mock_repository.NewMockOAuthRepo(ctrl)
+	jwtHelperMock := mock_helper.NewMockJWTHelper(ctrl)
+
+	return oauthRepoMock, jwtHelperMock, services.NewOAuthService(oauthRepoMock, jwtHelperMock)
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestOAuthRegisterClient(t *testing.T) {
+	oauthRepo, _, service := oauthServiceWithMock(t)
+
+	t.Run("Scope the current token doesn't have", func(t *testing.T) {
+		_, err := service.RegisterClient(context.Background(), 2, []string{helper.ScopePostsRead}, "app", []string{"https://app.example.com/callback"}, []string{helper.ScopePostsWrite}, true)
+
+		assert.IsType(t, &services.ValidationError{}, err)
+	})
+
+	t.Run("Credentials can't be delegated", func(t *testing.T) {
+		granted := []string{helper.ScopePostsRead, helper.ScopeCredentials}
+		_, err := service.RegisterClient(context.Background(), 2, granted, "app", []string{"https://app.example.com/callback"}, []string{helper.ScopeCredentials}, true)
+
+		assert.IsType(t, &services.ValidationError{}, err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		oauthRepo.EXPECT().CreateClient(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		data, err := service.RegisterClient(context.Background(), 2, helper.DefaultScopes, "app", []string{"https://app.example.com/callback"}, []string{helper.ScopePostsRead}, true)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, data.ClientSecret)
+	})
+}
+
+func TestOAuthAuthorize(t *testing.T) {
+	var (
+		oauthRepo, _, service = oauthServiceWithMock(t)
+		client                = models.OAuthClient{
+			ID:           1,
+			ClientID:     "client",
+			RedirectURIs: []string{"https://app.example.com/callback"},
+			Scopes:       []string{helper.ScopePostsRead, helper.ScopePostsWrite},
+		}
+		req = services.AuthorizationRequest{
+			ResponseType:        "code",
+			ClientID:            "client",
+			RedirectURI:         "https://app.example.com/callback",
+			Scopes:              []string{helper.ScopePostsRead},
+			State:               "xyz",
+			CodeChallenge:       pkceChallenge("verifier"),
+			CodeChallengeMethod: "S256",
+		}
+	)
+
+	t.Run("Unregistered redirect URI", func(t *testing.T) {
+		badReq := req
+		badReq.RedirectURI = "https://evil.example.com/callback"
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "client").Return(&client, nil).Times(1)
+
+		_, err := service.Authorize(context.Background(), 2, helper.DefaultScopes, badReq, true)
+
+		assert.IsType(t, &services.OAuthError{}, err)
+	})
+
+	t.Run("PKCE is required", func(t *testing.T) {
+		badReq := req
+		badReq.CodeChallenge = ""
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "client").Return(&client, nil).Times(1)
+
+		_, err := service.Authorize(context.Background(), 2, helper.DefaultScopes, badReq, true)
+
+		assert.Equal(t, "invalid_request", err.(*services.OAuthError).Code)
+	})
+
+	t.Run("Scope not allowed for the client", func(t *testing.T) {
+		badReq := req
+		badReq.Scopes = []string{helper.ScopeUsersWrite}
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "client").Return(&client, nil).Times(1)
+
+		_, err := service.Authorize(context.Background(), 2, helper.DefaultScopes, badReq, true)
+
+		assert.Equal(t, "invalid_scope", err.(*services.OAuthError).Code)
+	})
+
+	t.Run("Scope the current token doesn't have", func(t *testing.T) {
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "client").Return(&client, nil).Times(1)
+
+		_, err := service.Authorize(context.Background(), 2, []string{helper.ScopeUsersWrite}, req, true)
+
+		assert.Equal(t, "invalid_scope", err.(*services.OAuthError).Code)
+	})
+
+	t.Run("Denied by the user", func(t *testing.T) {
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "client").Return(&client, nil).Times(1)
+
+		redirectTo, err := service.Authorize(context.Background(), 2, helper.DefaultScopes, req, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "https://app.example.com/callback?error=access_denied&state=xyz", redirectTo)
+	})
+
+	t.Run("Approved by the user", func(t *testing.T) {
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "client").Return(&client, nil).Times(1)
+		oauthRepo.EXPECT().GetConsent(gomock.Any(), uint(2), uint(1)).Return(nil, gorm.ErrRecordNotFound).Times(1)
+		oauthRepo.EXPECT().SaveConsent(gomock.Any(), &models.OAuthConsent{UserID: 2, OAuthClientID: 1, Scopes: []string{helper.ScopePostsRead}}).Return(nil).Times(1)
+
+		var storedCode *models.OAuthAuthorizationCode
+		oauthRepo.EXPECT().CreateCode(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+			storedCode = code
+			return nil
+		}).Times(1)
+
+		redirectTo, err := service.Authorize(context.Background(), 2, helper.DefaultScopes, req, true)
+		assert.NoError(t, err)
+
+		u, _ := url.Parse(redirectTo)
+		assert.Equal(t, "xyz", u.Query().Get("state"))
+		assert.Equal(t, helper.HashToken(u.Query().Get("code")), storedCode.CodeHash)
+		assert.Equal(t, req.CodeChallenge, storedCode.CodeChallenge)
+	})
+}
+
+func TestOAuthTokenAuthorizationCode(t *testing.T) {
+	var (
+		oauthRepo, jwtHelper, service = oauthServiceWithMock(t)
+		client                        = models.OAuthClient{ID: 1, ClientID: "client", Scopes: []string{helper.ScopePostsRead}}
+		code                          = models.OAuthAuthorizationCode{
+			ID:            3,
+			OAuthClientID: 1,
+			UserID:        2,
+			RedirectURI:   "https://app.example.com/callback",
+			Scopes:        []string{helper.ScopePostsRead},
+			CodeChallenge: pkceChallenge("verifier"),
+			ExpiresAt:     time.Now().Add(time.Minute),
+		}
+		req = services.TokenRequest{
+			GrantType:    services.GrantTypeAuthorizationCode,
+			ClientID:     "client",
+			Code:         "code",
+			RedirectURI:  "https://app.example.com/callback",
+			CodeVerifier: "verifier",
+		}
+	)
+
+	t.Run("Wrong code verifier", func(t *testing.T) {
+		badReq := req
+		badReq.CodeVerifier = "other"
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "client").Return(&client, nil).Times(1)
+		oauthRepo.EXPECT().GetCodeByHash(gomock.Any(), helper.HashToken("code")).Return(&code, nil).Times(1)
+
+		_, err := service.Token(context.Background(), badReq)
+
+		assert.Equal(t, "invalid_grant", err.(*services.OAuthError).Code)
+	})
+
+	t.Run("Code already used", func(t *testing.T) {
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "client").Return(&client, nil).Times(1)
+		oauthRepo.EXPECT().GetCodeByHash(gomock.Any(), helper.HashToken("code")).Return(&code, nil).Times(1)
+		oauthRepo.EXPECT().MarkCodeUsed(gomock.Any(), uint(3), gomock.Any()).Return(false, nil).Times(1)
+		oauthRepo.EXPECT().RevokeTokensByUserClient(gomock.Any(), uint(2), uint(1), gomock.Any()).Return(nil).Times(1)
+
+		_, err := service.Token(context.Background(), req)
+
+		assert.Equal(t, services.ErrOAuthInvalidGrant, err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "client").Return(&client, nil).Times(1)
+		oauthRepo.EXPECT().GetCodeByHash(gomock.Any(), helper.HashToken("code")).Return(&code, nil).Times(1)
+		oauthRepo.EXPECT().MarkCodeUsed(gomock.Any(), uint(3), gomock.Any()).Return(true, nil).Times(1)
+		jwtHelper.EXPECT().CreateTokenWithOptions(2, gomock.Any()).DoAndReturn(func(id int, options helper.TokenOptions) (string, error) {
+			assert.Equal(t, "client", options.ClientID)
+			assert.Equal(t, []string{helper.ScopePostsRead}, options.Scopes)
+			assert.NotEmpty(t, options.TokenID)
+			return "access-token", nil
+		}).Times(1)
+		oauthRepo.EXPECT().CreateToken(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		data, err := service.Token(context.Background(), req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "access-token", data.AccessToken)
+		assert.Equal(t, helper.ScopePostsRead, data.Scope)
+	})
+}
+
+func TestOAuthTokenClientCredentials(t *testing.T) {
+	var (
+		oauthRepo, jwtHelper, service = oauthServiceWithMock(t)
+		confidential                  = models.OAuthClient{ID: 1, ClientID: "server", SecretHash: helper.HashToken("secret"), Confidential: true, UserID: 4, Scopes: []string{helper.ScopePostsRead}}
+		public                        = models.OAuthClient{ID: 2, ClientID: "spa", Scopes: []string{helper.ScopePostsRead}}
+	)
+
+	cases := []struct {
+		name     string
+		req      services.TokenRequest
+		mockFunc func()
+		errCode  string
+	}{
+		{
+			"Wrong client secret",
+			services.TokenRequest{GrantType: services.GrantTypeClientCredentials, ClientID: "server", ClientSecret: "wrong"},
+			func() {
+				oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "server").Return(&confidential, nil).Times(1)
+			},
+			"invalid_client",
+		},
+		{
+			"Public client",
+			services.TokenRequest{GrantType: services.GrantTypeClientCredentials, ClientID: "spa"},
+			func() {
+				oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "spa").Return(&public, nil).Times(1)
+			},
+			"unauthorized_client",
+		},
+		{
+			"Scope not allowed",
+			services.TokenRequest{GrantType: services.GrantTypeClientCredentials, ClientID: "server", ClientSecret: "secret", Scopes: []string{helper.ScopePostsWrite}},
+			func() {
+				oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "server").Return(&confidential, nil).Times(1)
+			},
+			"invalid_scope",
+		},
+		{
+			"Success",
+			services.TokenRequest{GrantType: services.GrantTypeClientCredentials, ClientID: "server", ClientSecret: "secret"},
+			func() {
+				oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "server").Return(&confidential, nil).Times(1)
+				jwtHelper.EXPECT().CreateTokenWithOptions(4, gomock.Any()).Return("access-token", nil).Times(1)
+				oauthRepo.EXPECT().CreateToken(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			"",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			data, err := service.Token(context.Background(), c.req)
+
+			if c.errCode == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, "access-token", data.AccessToken)
+				return
+			}
+
+			assert.Equal(t, c.errCode, err.(*services.OAuthError).Code)
+		})
+	}
+}
+
+func TestOAuthIntrospect(t *testing.T) {
+	var (
+		oauthRepo, jwtHelper, service = oauthServiceWithMock(t)
+		client                        = models.OAuthClient{ID: 1, ClientID: "server", SecretHash: helper.HashToken("secret"), Confidential: true}
+		revokedAt                     = time.Now()
+	)
+
+	t.Run("Token of another client", func(t *testing.T) {
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "server").Return(&client, nil).Times(1)
+		jwtHelper.EXPECT().ParseClaims("token").Return(&helper.TokenClaims{UserID: "2", ClientID: "other", TokenID: "jti"}, nil).Times(1)
+
+		data, err := service.Introspect(context.Background(), "server", "secret", "token")
+
+		assert.NoError(t, err)
+		assert.False(t, data.Active)
+	})
+
+	t.Run("Revoked token", func(t *testing.T) {
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "server").Return(&client, nil).Times(1)
+		jwtHelper.EXPECT().ParseClaims("token").Return(&helper.TokenClaims{UserID: "2", ClientID: "server", TokenID: "jti"}, nil).Times(1)
+		oauthRepo.EXPECT().GetTokenByTokenId(gomock.Any(), "jti").Return(&models.OAuthToken{RevokedAt: &revokedAt}, nil).Times(1)
+
+		data, err := service.Introspect(context.Background(), "server", "secret", "token")
+
+		assert.NoError(t, err)
+		assert.False(t, data.Active)
+	})
+
+	t.Run("Active token", func(t *testing.T) {
+		oauthRepo.EXPECT().GetClientByClientId(gomock.Any(), "server").Return(&client, nil).Times(1)
+		jwtHelper.EXPECT().ParseClaims("token").Return(&helper.TokenClaims{UserID: "2", ClientID: "server", TokenID: "jti", Scopes: []string{helper.ScopePostsRead}}, nil).Times(1)
+		oauthRepo.EXPECT().GetTokenByTokenId(gomock.Any(), "jti").Return(&models.OAuthToken{}, nil).Times(1)
+
+		data, err := service.Introspect(context.Background(), "server", "secret", "token")
+
+		assert.NoError(t, err)
+		assert.True(t, data.Active)
+		assert.Equal(t, "2", data.Subject)
+		assert.Equal(t, helper.ScopePostsRead, data.Scope)
+	})
+}
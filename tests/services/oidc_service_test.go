@@ -0,0 +1,237 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	mock_helper "github.com/simple-crud-go/internal/helper/mocks"
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+type oidcServiceMocks struct {
+	identityRepo   *mock_repository.MockIdentityRepo
+	userRepo       *mock_repository.MockUserRepo
+	sessionRepo    *mock_repository.MockSessionRepo
+	auditRepo      *mock_repository.MockAuditRepo
+	passwordCrypto *mock_helper.MockPasswordCrypto
+	jwtHelper      *mock_helper.MockJWTHelper
+}
+
+func oidcServiceWithStubProvider(t *testing.T) (*stubOIDCProvider, *services.OIDCService, oidcServiceMocks) {
+	ctrl := gomock.NewController(t)
+
+	mocks := oidcServiceMocks{
+		identityRepo:   mock_repository.NewMockIdentityRepo(ctrl),
+		userRepo:       mock_repository.NewMockUserRepo(ctrl),
+		sessionRepo:    mock_repository.NewMockSessionRepo(ctrl),
+		auditRepo:      mock_repository.NewMockAuditRepo(ctrl),
+		passwordCrypto: mock_helper.NewMockPasswordCrypto(ctrl),
+		jwtHelper:      mock_helper.NewMockJWTHelper(ctrl),
+	}
+
+	mocks.auditRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	transactor, _ := transactorWith(repository.Repositories{Users: mocks.userRepo, Identities: mocks.identityRepo})
+
+	provider := newStubOIDCProvider(t, "simple-crud")
+
+	service, err := services.NewOIDCService(services.OIDCConfig{
+		ProviderName: "stub",
+		IssuerURL:    provider.URL(),
+		ClientID:     "simple-crud",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:5000/api/auth/oidc/callback",
+	}, mocks.identityRepo, mocks.userRepo, mocks.sessionRepo, mocks.auditRepo, mocks.passwordCrypto, mocks.jwtHelper, transactor)
+	if err != nil {
+		t.Fatalf("'%s' occured when creating the OIDC service", err)
+	}
+
+	return provider, service, mocks
+}
+
+func TestOIDCFirstLoginCreatesUser(t *testing.T) {
+	var (
+		provider, service, mocks = oidcServiceWithStubProvider(t)
+		createdUser              = models.User{ID: 5, Name: "Jane Doe", Username: "jane"}
+	)
+
+	authURL, signedState, err := service.AuthCodeURL(0)
+	assert.NoError(t, err)
+
+	state, code := provider.Authorize(t, authURL, jwt.MapClaims{
+		"sub":                "subject-1",
+		"email":              "jane@example.com",
+		"name":               "Jane Doe",
+		"preferred_username": "jane",
+	})
+
+	mocks.identityRepo.EXPECT().GetByProviderSubject(gomock.Any(), "stub", "subject-1").Return(nil, gorm.ErrRecordNotFound).Times(1)
+	mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+	mocks.passwordCrypto.EXPECT().HashPassword(gomock.Any()).Return("hashed", nil).Times(1)
+	mocks.userRepo.EXPECT().Create(gomock.Any(), &models.User{Name: "Jane Doe", Username: "jane", Password: "hashed"}).Return(nil).Times(1)
+	mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&createdUser, nil).Times(1)
+	mocks.identityRepo.EXPECT().Create(gomock.Any(), &models.Identity{UserID: 5, Provider: "stub", Subject: "subject-1", Email: "jane@example.com"}).Return(nil).Times(1)
+	mocks.sessionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mocks.jwtHelper.EXPECT().CreateTokenWithOptions(5, gomock.Any()).Return("token", nil).Times(1)
+
+	data, err := service.HandleCallback(context.Background(), state, signedState, code, services.ClientInfo{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "token", data.Token)
+	assert.Equal(t, &createdUser, data.User)
+}
+
+func TestOIDCLoginWithLinkedIdentity(t *testing.T) {
+	var (
+		provider, service, mocks = oidcServiceWithStubProvider(t)
+		user                     = models.User{ID: 5, Name: "Jane Doe", Username: "jane"}
+	)
+
+	authURL, signedState, err := service.AuthCodeURL(0)
+	assert.NoError(t, err)
+
+	state, code := provider.Authorize(t, authURL, jwt.MapClaims{"sub": "subject-1"})
+
+	mocks.identityRepo.EXPECT().GetByProviderSubject(gomock.Any(), "stub", "subject-1").Return(&models.Identity{UserID: 5}, nil).Times(1)
+	mocks.userRepo.EXPECT().GetById(gomock.Any(), uint(5)).Return(&user, nil).Times(1)
+	mocks.sessionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mocks.jwtHelper.EXPECT().CreateTokenWithOptions(5, gomock.Any()).Return("token", nil).Times(1)
+
+	data, err := service.HandleCallback(context.Background(), state, signedState, code, services.ClientInfo{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &user, data.User)
+
+	t.Run("State can't be reused", func(t *testing.T) {
+		_, err := service.HandleCallback(context.Background(), state, signedState, code, services.ClientInfo{})
+
+		assert.Equal(t, services.ErrInvalidOIDCState, err)
+	})
+}
+
+func TestOIDCLinkIdentity(t *testing.T) {
+	var (
+		provider, service, mocks = oidcServiceWithStubProvider(t)
+		user                     = models.User{ID: 5, Name: "Jane Doe", Username: "jane"}
+	)
+
+	t.Run("Link to the authenticated user", func(t *testing.T) {
+		authURL, signedState, err := service.AuthCodeURL(5)
+		assert.NoError(t, err)
+
+		state, code := provider.Authorize(t, authURL, jwt.MapClaims{"sub": "subject-2"})
+
+		mocks.identityRepo.EXPECT().GetByProviderSubject(gomock.Any(), "stub", "subject-2").Return(nil, gorm.ErrRecordNotFound).Times(1)
+		mocks.userRepo.EXPECT().GetById(gomock.Any(), uint(5)).Return(&user, nil).Times(1)
+		mocks.identityRepo.EXPECT().Create(gomock.Any(), &models.Identity{UserID: 5, Provider: "stub", Subject: "subject-2"}).Return(nil).Times(1)
+		mocks.sessionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		mocks.jwtHelper.EXPECT().CreateTokenWithOptions(5, gomock.Any()).Return("token", nil).Times(1)
+
+		_, err = service.HandleCallback(context.Background(), state, signedState, code, services.ClientInfo{})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Account already linked to another user", func(t *testing.T) {
+		authURL, signedState, err := service.AuthCodeURL(5)
+		assert.NoError(t, err)
+
+		state, code := provider.Authorize(t, authURL, jwt.MapClaims{"sub": "subject-3"})
+
+		mocks.identityRepo.EXPECT().GetByProviderSubject(gomock.Any(), "stub", "subject-3").Return(&models.Identity{UserID: 7}, nil).Times(1)
+
+		_, err = service.HandleCallback(context.Background(), state, signedState, code, services.ClientInfo{})
+
+		assert.Equal(t, services.ErrIdentityAlreadyLinked, err)
+	})
+}
+
+func TestOIDCUnknownState(t *testing.T) {
+	_, service, _ := oidcServiceWithStubProvider(t)
+
+	_, err := service.HandleCallback(context.Background(), "unknown", "unknown.signature", "code", services.ClientInfo{})
+
+	assert.Equal(t, services.ErrInvalidOIDCState, err)
+}
+
+func TestOIDCStateFromAnotherBrowser(t *testing.T) {
+	provider, service, _ := oidcServiceWithStubProvider(t)
+
+	// The attacker starts linking their own account and gets the victim to finish it
+	authURL, _, err := service.AuthCodeURL(5)
+	assert.NoError(t, err)
+
+	state, code := provider.Authorize(t, authURL, jwt.MapClaims{"sub": "victim"})
+
+	_, victimState, err := service.AuthCodeURL(0)
+	assert.NoError(t, err)
+
+	for _, signedState := range []string{"", victimState, state + ".forged"} {
+		_, err = service.HandleCallback(context.Background(), state, signedState, code, services.ClientInfo{})
+
+		assert.Equal(t, services.ErrInvalidOIDCState, err)
+	}
+}
+
+func TestOIDCFirstLoginIdentityFails(t *testing.T) {
+	var (
+		provider, service, mocks = oidcServiceWithStubProvider(t)
+		errFailed                = errors.New("failed")
+	)
+
+	authURL, signedState, err := service.AuthCodeURL(0)
+	assert.NoError(t, err)
+
+	state, code := provider.Authorize(t, authURL, jwt.MapClaims{"sub": "subject-1", "preferred_username": "jane"})
+
+	mocks.identityRepo.EXPECT().GetByProviderSubject(gomock.Any(), "stub", "subject-1").Return(nil, gorm.ErrRecordNotFound).Times(1)
+	mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+	mocks.passwordCrypto.EXPECT().HashPassword(gomock.Any()).Return("hashed", nil).Times(1)
+	mocks.userRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{ID: 5, Username: "jane"}, nil).Times(1)
+	mocks.identityRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(errFailed).Times(1)
+
+	_, err = service.HandleCallback(context.Background(), state, signedState, code, services.ClientInfo{})
+
+	assert.ErrorIs(t, err, errFailed)
+}
+
+func TestOIDCConcurrentFirstLogin(t *testing.T) {
+	var (
+		provider, service, mocks = oidcServiceWithStubProvider(t)
+		user                     = models.User{ID: 4, Name: "Jane Doe", Username: "jane"}
+	)
+
+	authURL, signedState, err := service.AuthCodeURL(0)
+	assert.NoError(t, err)
+
+	state, code := provider.Authorize(t, authURL, jwt.MapClaims{"sub": "subject-1", "preferred_username": "jane"})
+
+	gomock.InOrder(
+		mocks.identityRepo.EXPECT().GetByProviderSubject(gomock.Any(), "stub", "subject-1").Return(nil, gorm.ErrRecordNotFound),
+		// The other login linked the identity in the meantime
+		mocks.identityRepo.EXPECT().GetByProviderSubject(gomock.Any(), "stub", "subject-1").Return(&models.Identity{UserID: 4}, nil),
+	)
+	mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+	mocks.passwordCrypto.EXPECT().HashPassword(gomock.Any()).Return("hashed", nil).Times(1)
+	mocks.userRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	// Rolled back along with the identity
+	mocks.userRepo.EXPECT().GetByUsername(gomock.Any(), "jane").Return(&models.User{ID: 5, Username: "jane"}, nil).Times(1)
+	mocks.identityRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(gorm.ErrDuplicatedKey).Times(1)
+	mocks.userRepo.EXPECT().GetById(gomock.Any(), uint(4)).Return(&user, nil).Times(1)
+	mocks.sessionRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+	mocks.jwtHelper.EXPECT().CreateTokenWithOptions(4, gomock.Any()).Return("token", nil).Times(1)
+
+	data, err := service.HandleCallback(context.Background(), state, signedState, code, services.ClientInfo{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, &user, data.User)
+}
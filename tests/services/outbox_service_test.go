@@ -0,0 +1,269 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// fakeTransactor runs the transactions with the given repositories, nothing is rolled back. Nested transactions run
+// with the same repositories.
+type fakeTransactor struct {
+	repos repository.Repositories
+}
+
+func (t *fakeTransactor) RunInTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return fn(t.repos)
+}
+
+// transactorWith a transactor whose outbox keeps the recorded events.
+func transactorWith(repos repository.Repositories) (*fakeTransactor, *recordingOutbox) {
+	outbox := &recordingOutbox{}
+	repos.Outbox = outbox
+
+	transactor := &fakeTransactor{}
+	repos.Tx = transactor
+	transactor.repos = repos
+
+	return transactor, outbox
+}
+
+// recordingOutbox keeps the recorded events, the services only ever add events.
+type recordingOutbox struct {
+	repository.OutboxRepo
+	events []models.OutboxEvent
+}
+
+func (o *recordingOutbox) Add(ctx context.Context, event *models.OutboxEvent) error {
+	o.events = append(o.events, *event)
+	return nil
+}
+
+// recordingPublisher keeps the published events, failing with `err` when set.
+type recordingPublisher struct {
+	events []services.DomainEvent
+	err    error
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event services.DomainEvent) error {
+	p.events = append(p.events, event)
+	return p.err
+}
+
+// busWith a bus publishing every event to the subscriber.
+func busWith(subscriber services.EventPublisher) *services.EventBus {
+	bus := services.NewEventBus()
+	bus.Subscribe("subscriber", subscriber)
+	return bus
+}
+
+// outboxWithoutReceipts an outbox whose events weren't received by any subscriber yet.
+func outboxWithoutReceipts(ctrl *gomock.Controller) *mock_repository.MockOutboxRepo {
+	outboxRepo := mock_repository.NewMockOutboxRepo(ctrl)
+	outboxRepo.EXPECT().GetReceipts(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
+	outboxRepo.EXPECT().AddReceipt(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return outboxRepo
+}
+
+type panickingPublisher struct{}
+
+func (panickingPublisher) Publish(ctx context.Context, event services.DomainEvent) error {
+	panic(errors.New("boom"))
+}
+
+func TestEventBus(t *testing.T) {
+	var (
+		bus        = services.NewEventBus()
+		everything = &recordingPublisher{}
+		postsOnly  = &recordingPublisher{}
+		created    = services.PostCreated{EventMeta: services.EventMeta{PostID: 1}}
+		followed   = services.UserFollowed{EventMeta: services.EventMeta{UserID: 2}}
+	)
+
+	bus.Subscribe("everything", everything)
+	bus.Subscribe("posts", postsOnly, services.EventPostCreated, services.EventPostDeleted)
+
+	assert.NoError(t, bus.Publish(context.Background(), created))
+	assert.NoError(t, bus.Publish(context.Background(), followed))
+
+	assert.Equal(t, []services.DomainEvent{created, followed}, everything.events)
+	assert.Equal(t, []services.DomainEvent{created}, postsOnly.events)
+
+	t.Run("Failing subscriber", func(t *testing.T) {
+		var (
+			bus     = services.NewEventBus()
+			failing = &recordingPublisher{err: errUnexpected}
+			after   = &recordingPublisher{}
+		)
+
+		bus.Subscribe("failing", failing)
+		bus.Subscribe("after", after)
+
+		assert.ErrorIs(t, bus.Publish(context.Background(), created), errUnexpected)
+		// The following subscribers still receive the event
+		assert.Equal(t, []services.DomainEvent{created}, after.events)
+	})
+}
+
+func TestOutboxDispatch(t *testing.T) {
+	t.Run("Published in order", func(t *testing.T) {
+		var (
+			ctrl       = gomock.NewController(t)
+			outboxRepo = outboxWithoutReceipts(ctrl)
+			subscriber = &recordingPublisher{}
+			dispatcher = services.NewOutboxDispatcher(outboxRepo, busWith(subscriber))
+		)
+
+		outboxRepo.EXPECT().ClaimPending(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.OutboxEvent{
+			{ID: 1, Type: services.EventPostCreated, ActorID: 2, UserID: 2, PostID: 3, Data: `{"actor_id":2,"user_id":2,"post_id":3,"post":{"id":3,"title":"Title"}}`},
+			{ID: 2, Type: services.EventPostReacted, ActorID: 4, UserID: 2, PostID: 3, Kind: models.ReactionLike, Data: `{"actor_id":4,"user_id":2,"post_id":3,"kind":"like"}`},
+		}, nil).Times(1)
+		gomock.InOrder(
+			outboxRepo.EXPECT().MarkDispatched(gomock.Any(), uint(1), gomock.Any()).Return(nil),
+			outboxRepo.EXPECT().MarkDispatched(gomock.Any(), uint(2), gomock.Any()).Return(nil),
+		)
+
+		assert.Equal(t, 2, dispatcher.Dispatch(context.Background()))
+		// Decoded into the type of their event
+		assert.Equal(t, []services.DomainEvent{
+			services.PostCreated{EventMeta: services.EventMeta{ActorID: 2, UserID: 2, PostID: 3}, Post: models.Post{ID: 3, Title: "Title"}},
+			services.PostReacted{EventMeta: services.EventMeta{ActorID: 4, UserID: 2, PostID: 3}, Kind: models.ReactionLike},
+		}, subscriber.events)
+	})
+
+	t.Run("Unknown event type", func(t *testing.T) {
+		var (
+			ctrl       = gomock.NewController(t)
+			outboxRepo = outboxWithoutReceipts(ctrl)
+			subscriber = &recordingPublisher{}
+			dispatcher = services.NewOutboxDispatcher(outboxRepo, busWith(subscriber))
+		)
+
+		outboxRepo.EXPECT().ClaimPending(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.OutboxEvent{
+			{ID: 1, Type: "post.archived", Data: `{}`},
+		}, nil).Times(1)
+		outboxRepo.EXPECT().MarkFailed(gomock.Any(), uint(1), "unknown event type 'post.archived'", gomock.Any()).Return(nil).Times(1)
+
+		assert.Equal(t, 1, dispatcher.Dispatch(context.Background()))
+		assert.Empty(t, subscriber.events)
+	})
+
+	t.Run("Holds back the following events of a failing post", func(t *testing.T) {
+		var (
+			ctrl       = gomock.NewController(t)
+			outboxRepo = outboxWithoutReceipts(ctrl)
+			bus        = services.NewEventBus()
+			subscriber = &recordingPublisher{}
+			dispatcher = services.NewOutboxDispatcher(outboxRepo, bus)
+		)
+
+		bus.Subscribe("creations", panickingPublisher{}, services.EventPostCreated)
+		bus.Subscribe("subscriber", subscriber)
+
+		outboxRepo.EXPECT().ClaimPending(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.OutboxEvent{
+			{ID: 1, Type: services.EventPostCreated, UserID: 2, PostID: 3, Data: `{}`},
+			{ID: 2, Type: services.EventPostDeleted, UserID: 2, PostID: 3, Data: `{}`},
+			{ID: 3, Type: services.EventPostDeleted, UserID: 2, PostID: 4, Data: `{}`},
+		}, nil).Times(1)
+		outboxRepo.EXPECT().MarkFailed(gomock.Any(), uint(1), "subscriber panicked: boom", gomock.Any()).Return(nil).Times(1)
+		// The event of another post is still published
+		outboxRepo.EXPECT().MarkDispatched(gomock.Any(), uint(3), gomock.Any()).Return(nil).Times(1)
+		// Published after the failed event, without waiting for their lease to run out
+		outboxRepo.EXPECT().Release(gomock.Any(), []uint{2}).Return(nil).Times(1)
+
+		assert.Equal(t, 3, dispatcher.Dispatch(context.Background()))
+		assert.Equal(t, []services.DomainEvent{services.PostDeleted{}}, subscriber.events)
+	})
+
+	t.Run("Given up after the last attempt", func(t *testing.T) {
+		var (
+			ctrl       = gomock.NewController(t)
+			outboxRepo = outboxWithoutReceipts(ctrl)
+			dispatcher = services.NewOutboxDispatcher(outboxRepo, busWith(&recordingPublisher{err: errUnexpected}))
+		)
+
+		outboxRepo.EXPECT().ClaimPending(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.OutboxEvent{
+			{ID: 1, Type: services.EventPostCreated, Data: `{}`, Attempts: 9},
+		}, nil).Times(1)
+		outboxRepo.EXPECT().MarkDead(gomock.Any(), uint(1), errUnexpected.Error(), gomock.Any()).Return(nil).Times(1)
+
+		assert.Equal(t, 1, dispatcher.Dispatch(context.Background()))
+	})
+
+	t.Run("Left pending when a subscriber fails", func(t *testing.T) {
+		var (
+			ctrl       = gomock.NewController(t)
+			outboxRepo = outboxWithoutReceipts(ctrl)
+			subscriber = &recordingPublisher{err: errUnexpected}
+			dispatcher = services.NewOutboxDispatcher(outboxRepo, busWith(subscriber))
+		)
+
+		outboxRepo.EXPECT().ClaimPending(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.OutboxEvent{
+			{ID: 1, Type: services.EventPostCreated, Data: `{}`, Attempts: 2},
+			{ID: 2, Type: services.EventPostDeleted, Data: `{}`},
+		}, nil).Times(1)
+		outboxRepo.EXPECT().MarkFailed(gomock.Any(), uint(1), errUnexpected.Error(), gomock.Any()).DoAndReturn(func(ctx context.Context, id uint, lastError string, nextAttemptAt time.Time) error {
+			// Third failed dispatch, four times the base delay
+			assert.WithinDuration(t, time.Now().Add(20*time.Second), nextAttemptAt, 5*time.Second)
+			return nil
+		}).Times(1)
+		outboxRepo.EXPECT().MarkDispatched(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+		outboxRepo.EXPECT().Release(gomock.Any(), []uint{2}).Return(nil).Times(1)
+
+		assert.Equal(t, 2, dispatcher.Dispatch(context.Background()))
+		assert.Len(t, subscriber.events, 1)
+	})
+
+	t.Run("Published again when it can't be marked", func(t *testing.T) {
+		var (
+			ctrl       = gomock.NewController(t)
+			outboxRepo = outboxWithoutReceipts(ctrl)
+			subscriber = &recordingPublisher{}
+			dispatcher = services.NewOutboxDispatcher(outboxRepo, busWith(subscriber))
+			pending    = []models.OutboxEvent{{ID: 1, Type: services.EventPostCreated, Data: `{}`}}
+		)
+
+		outboxRepo.EXPECT().ClaimPending(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(pending, nil).Times(2)
+		outboxRepo.EXPECT().MarkDispatched(gomock.Any(), uint(1), gomock.Any()).Return(errUnexpected).Times(1)
+		outboxRepo.EXPECT().MarkDispatched(gomock.Any(), uint(1), gomock.Any()).Return(nil).Times(1)
+
+		dispatcher.Dispatch(context.Background())
+		dispatcher.Dispatch(context.Background())
+
+		assert.Len(t, subscriber.events, 2)
+	})
+
+	t.Run("Retried for the subscribers that didn't receive it", func(t *testing.T) {
+		var (
+			ctrl       = gomock.NewController(t)
+			outboxRepo = mock_repository.NewMockOutboxRepo(ctrl)
+			bus        = services.NewEventBus()
+			received   = &recordingPublisher{}
+			missed     = &recordingPublisher{}
+			dispatcher = services.NewOutboxDispatcher(outboxRepo, bus)
+		)
+
+		bus.Subscribe("notifications", received)
+		bus.Subscribe("webhooks", missed)
+
+		outboxRepo.EXPECT().ClaimPending(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.OutboxEvent{
+			{ID: 1, Type: services.EventPostCreated, Data: `{}`, Attempts: 1},
+		}, nil).Times(1)
+		outboxRepo.EXPECT().GetReceipts(gomock.Any(), uint(1)).Return([]string{"notifications"}, nil).Times(1)
+		outboxRepo.EXPECT().AddReceipt(gomock.Any(), uint(1), "webhooks").Return(nil).Times(1)
+		outboxRepo.EXPECT().MarkDispatched(gomock.Any(), uint(1), gomock.Any()).Return(nil).Times(1)
+
+		assert.Equal(t, 1, dispatcher.Dispatch(context.Background()))
+		assert.Empty(t, received.events)
+		assert.Len(t, missed.events, 1)
+	})
+
+}
@@ -1,9 +1,11 @@
 package services_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
 	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
 	"github.com/simple-crud-go/internal/services"
 	"github.com/stretchr/testify/assert"
@@ -11,22 +13,31 @@ import (
 	"gorm.io/gorm"
 )
 
-func postServiceWithMock(t *testing.T) (*mock_repository.MockPostRepo, *mock_repository.MockUserRepo, *services.PostService) {
+func postServiceWithMock(t *testing.T) (*mock_repository.MockPostRepo, *mock_repository.MockUserRepo, *services.PostService, *mock_repository.MockBookmarkRepo) {
 	ctrl := gomock.NewController(t)
 
 	userRepoMock := mock_repository.NewMockUserRepo(ctrl)
 	postRepoMock := mock_repository.NewMockPostRepo(ctrl)
+	auditRepoMock := mock_repository.NewMockAuditRepo(ctrl)
+	reactionRepoMock := mock_repository.NewMockReactionRepo(ctrl)
+	bookmarkRepoMock := mock_repository.NewMockBookmarkRepo(ctrl)
 
-	service := services.NewPostService(postRepoMock, userRepoMock)
+	// What gets recorded is covered by the audit tests
+	auditRepoMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	// The reaction counts are covered by the reaction tests
+	reactionRepoMock.EXPECT().CountByPostIds(gomock.Any(), gomock.Any()).Return(nil, nil).AnyTimes()
 
-	return postRepoMock, userRepoMock, service
+	transactor, _ := transactorWith(repository.Repositories{Posts: postRepoMock, Bookmarks: bookmarkRepoMock})
+	service := services.NewPostService(postRepoMock, userRepoMock, reactionRepoMock, auditRepoMock, transactor)
+
+	return postRepoMock, userRepoMock, service, bookmarkRepoMock
 }
 
 func TestGetPostById(t *testing.T) {
 	var (
-		id                   = 1
-		postRepo, _, service = postServiceWithMock(t)
-		foundPost            = models.Post{
+		id                      = 1
+		postRepo, _, service, _ = postServiceWithMock(t)
+		foundPost               = models.Post{
 			ID:     1,
 			Title:  "dummy title",
 			Body:   "dummy body",
@@ -43,7 +54,7 @@ func TestGetPostById(t *testing.T) {
 		{
 			"Post not found",
 			func() {
-				postRepo.EXPECT().GetById(id).Return(nil, gorm.ErrRecordNotFound).Times(1)
+				postRepo.EXPECT().GetById(gomock.Any(), id).Return(nil, gorm.ErrRecordNotFound).Times(1)
 			},
 			gorm.ErrRecordNotFound,
 			nil,
@@ -51,7 +62,7 @@ func TestGetPostById(t *testing.T) {
 		{
 			"Unexpected Error",
 			func() {
-				postRepo.EXPECT().GetById(id).Return(nil, errUnexpected).Times(1)
+				postRepo.EXPECT().GetById(gomock.Any(), id).Return(nil, errUnexpected).Times(1)
 			},
 			errUnexpected,
 			nil,
@@ -59,7 +70,7 @@ func TestGetPostById(t *testing.T) {
 		{
 			"Success",
 			func() {
-				postRepo.EXPECT().GetById(id).Return(&foundPost, nil).Times(1)
+				postRepo.EXPECT().GetById(gomock.Any(), id).Return(&foundPost, nil).Times(1)
 			},
 			nil,
 			&foundPost,
@@ -69,7 +80,7 @@ func TestGetPostById(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			p, err := service.GetPostById(id)
+			p, err := service.GetPostById(context.Background(), id, 0)
 
 			assert.Equal(t, err, c.err)
 			assert.Equal(t, p, c.post)
@@ -79,8 +90,8 @@ func TestGetPostById(t *testing.T) {
 
 func TestGetAllPost(t *testing.T) {
 	var (
-		postRepo, _, service = postServiceWithMock(t)
-		posts                = []models.Post{
+		postRepo, _, service, _ = postServiceWithMock(t)
+		posts                   = []models.Post{
 			{
 				ID:     1,
 				Title:  "dummy title",
@@ -105,7 +116,7 @@ func TestGetAllPost(t *testing.T) {
 		{
 			"Unexpected Error",
 			func() {
-				postRepo.EXPECT().GetAll().Return(nil, errUnexpected).Times(1)
+				postRepo.EXPECT().GetAll(gomock.Any()).Return(nil, errUnexpected).Times(1)
 			},
 			errUnexpected,
 			nil,
@@ -113,7 +124,7 @@ func TestGetAllPost(t *testing.T) {
 		{
 			"Success",
 			func() {
-				postRepo.EXPECT().GetAll().Return(posts, nil).Times(1)
+				postRepo.EXPECT().GetAll(gomock.Any()).Return(posts, nil).Times(1)
 			},
 			nil,
 			posts,
@@ -123,7 +134,7 @@ func TestGetAllPost(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			p, err := service.GetAllPost()
+			p, err := service.GetAllPost(context.Background(), "", 0)
 
 			assert.Equal(t, err, c.err)
 			assert.Equal(t, p, c.post)
@@ -133,8 +144,8 @@ func TestGetAllPost(t *testing.T) {
 
 func TestCreatePost(t *testing.T) {
 	var (
-		postRepo, userRepo, service = postServiceWithMock(t)
-		author                      = models.User{
+		postRepo, userRepo, service, _ = postServiceWithMock(t)
+		author                         = models.User{
 			ID:       2,
 			Name:     "Ibka",
 			Username: "ibkaanhar",
@@ -156,7 +167,7 @@ func TestCreatePost(t *testing.T) {
 		{
 			"Unknown Error on finding associated user",
 			func() {
-				userRepo.EXPECT().GetById(author.ID).Return(nil, errUnexpected)
+				userRepo.EXPECT().GetById(gomock.Any(), author.ID).Return(nil, errUnexpected)
 			},
 			errUnexpected,
 			nil,
@@ -164,8 +175,8 @@ func TestCreatePost(t *testing.T) {
 		{
 			"Unknown Error on creating the Post",
 			func() {
-				userRepo.EXPECT().GetById(author.ID).Return(&author, nil)
-				postRepo.EXPECT().Create(&newPost).Return(errUnexpected)
+				userRepo.EXPECT().GetById(gomock.Any(), author.ID).Return(&author, nil)
+				postRepo.EXPECT().Create(gomock.Any(), &newPost).Return(errUnexpected)
 			},
 			errUnexpected,
 			nil,
@@ -173,8 +184,8 @@ func TestCreatePost(t *testing.T) {
 		{
 			"Success",
 			func() {
-				userRepo.EXPECT().GetById(author.ID).Return(&author, nil)
-				postRepo.EXPECT().Create(&newPost).Return(nil)
+				userRepo.EXPECT().GetById(gomock.Any(), author.ID).Return(&author, nil)
+				postRepo.EXPECT().Create(gomock.Any(), &newPost).Return(nil)
 			},
 			nil,
 			&newPost,
@@ -184,7 +195,7 @@ func TestCreatePost(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			err := service.CreatePost(int(newPost.UserID), newPost.Title, newPost.Body)
+			err := service.CreatePost(context.Background(), int(newPost.UserID), newPost.Title, newPost.Body, services.ClientInfo{})
 
 			assert.Equal(t, err, c.err)
 		})
@@ -193,8 +204,8 @@ func TestCreatePost(t *testing.T) {
 
 func TestUpdatePost(t *testing.T) {
 	var (
-		postRepo, _, service = postServiceWithMock(t)
-		loggedInUser         = models.User{
+		postRepo, _, service, _ = postServiceWithMock(t)
+		loggedInUser            = models.User{
 			ID:       2,
 			Name:     "Ibka",
 			Username: "ibkaanhar",
@@ -229,7 +240,7 @@ func TestUpdatePost(t *testing.T) {
 		{
 			"User Not Found and Unexpected Error on finding associated user",
 			func() {
-				postRepo.EXPECT().GetById(int(newPost.ID)).Return(nil, errUnexpected)
+				postRepo.EXPECT().GetById(gomock.Any(), int(newPost.ID)).Return(nil, errUnexpected)
 			},
 			errUnexpected,
 			nil,
@@ -237,7 +248,7 @@ func TestUpdatePost(t *testing.T) {
 		{
 			"Current Logged in User ID mismatch with Post.UserID",
 			func() {
-				postRepo.EXPECT().GetById(int(newPost.ID)).Return(&postDiffUser, nil)
+				postRepo.EXPECT().GetById(gomock.Any(), int(newPost.ID)).Return(&postDiffUser, nil)
 			},
 			services.ErrMismatchAuthorID,
 			nil,
@@ -245,8 +256,9 @@ func TestUpdatePost(t *testing.T) {
 		{
 			"Unexpected Error when updating post",
 			func() {
-				postRepo.EXPECT().GetById(int(newPost.ID)).Return(&newPost, nil)
-				postRepo.EXPECT().Update(&newPost).Return(errUnexpected)
+				// Read again in the transaction
+				postRepo.EXPECT().GetById(gomock.Any(), int(newPost.ID)).Return(&newPost, nil).Times(2)
+				postRepo.EXPECT().Update(gomock.Any(), &newPost, "title", "body").Return(errUnexpected)
 			},
 			errUnexpected,
 			nil,
@@ -254,8 +266,8 @@ func TestUpdatePost(t *testing.T) {
 		{
 			"Success",
 			func() {
-				postRepo.EXPECT().GetById(int(newPost.ID)).Return(&newPost, nil)
-				postRepo.EXPECT().Update(&newPost).Return(nil)
+				postRepo.EXPECT().GetById(gomock.Any(), int(newPost.ID)).Return(&newPost, nil).Times(2)
+				postRepo.EXPECT().Update(gomock.Any(), &newPost, "title", "body").Return(nil)
 			},
 			nil,
 			&newPost,
@@ -265,7 +277,7 @@ func TestUpdatePost(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			err := service.UpdatePost(int(loggedInUser.ID), int(newPost.ID), newPost.Title, newPost.Body)
+			err := service.UpdatePost(context.Background(), int(loggedInUser.ID), int(newPost.ID), newPost.Title, newPost.Body, services.ClientInfo{})
 
 			assert.Equal(t, err, c.err)
 		})
@@ -274,8 +286,8 @@ func TestUpdatePost(t *testing.T) {
 
 func TestDeletePost(t *testing.T) {
 	var (
-		postRepo, _, service = postServiceWithMock(t)
-		loggedInUser         = models.User{
+		postRepo, _, service, bookmarkRepo = postServiceWithMock(t)
+		loggedInUser                       = models.User{
 			ID:       2,
 			Name:     "Ibka",
 			Username: "ibkaanhar",
@@ -303,30 +315,31 @@ func TestDeletePost(t *testing.T) {
 		{
 			"Unknown Error when getting post",
 			func() {
-				postRepo.EXPECT().GetById(int(toBeDeletedPost.ID)).Return(nil, errUnexpected)
+				postRepo.EXPECT().GetById(gomock.Any(), int(toBeDeletedPost.ID)).Return(nil, errUnexpected)
 			},
 			errUnexpected,
 		},
 		{
 			"Logged in user ID mismatch with the post Author/UserID to be deleted",
 			func() {
-				postRepo.EXPECT().GetById(int(toBeDeletedPost.ID)).Return(&diffUserAndPost, nil)
+				postRepo.EXPECT().GetById(gomock.Any(), int(toBeDeletedPost.ID)).Return(&diffUserAndPost, nil)
 			},
 			services.ErrMismatchAuthorID,
 		},
 		{
 			"Unknown Error when deleting the post",
 			func() {
-				postRepo.EXPECT().GetById(int(toBeDeletedPost.ID)).Return(&toBeDeletedPost, nil)
-				postRepo.EXPECT().Delete(toBeDeletedPost.ID).Return(errUnexpected)
+				postRepo.EXPECT().GetById(gomock.Any(), int(toBeDeletedPost.ID)).Return(&toBeDeletedPost, nil)
+				postRepo.EXPECT().Delete(gomock.Any(), toBeDeletedPost.ID).Return(errUnexpected)
 			},
 			errUnexpected,
 		},
 		{
 			"Success",
 			func() {
-				postRepo.EXPECT().GetById(int(toBeDeletedPost.ID)).Return(&toBeDeletedPost, nil)
-				postRepo.EXPECT().Delete(toBeDeletedPost.ID).Return(nil)
+				postRepo.EXPECT().GetById(gomock.Any(), int(toBeDeletedPost.ID)).Return(&toBeDeletedPost, nil)
+				postRepo.EXPECT().Delete(gomock.Any(), toBeDeletedPost.ID).Return(nil)
+				bookmarkRepo.EXPECT().DeleteByPostId(gomock.Any(), toBeDeletedPost.ID).Return(nil)
 			},
 			nil,
 		},
@@ -335,7 +348,7 @@ func TestDeletePost(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			err := service.DeletePostById(int(loggedInUser.ID), int(toBeDeletedPost.ID))
+			err := service.DeletePostById(context.Background(), int(loggedInUser.ID), int(toBeDeletedPost.ID), services.ClientInfo{})
 
 			assert.Equal(t, err, c.err)
 		})
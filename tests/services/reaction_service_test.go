@@ -0,0 +1,120 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+func reactionServiceWithMock(t *testing.T) (*mock_repository.MockReactionRepo, *mock_repository.MockPostRepo, *services.ReactionService, *recordingOutbox) {
+	ctrl := gomock.NewController(t)
+
+	reactionRepoMock := mock_repository.NewMockReactionRepo(ctrl)
+	postRepoMock := mock_repository.NewMockPostRepo(ctrl)
+	transactor, outbox := transactorWith(repository.Repositories{Reactions: reactionRepoMock})
+
+	return reactionRepoMock, postRepoMock, services.NewReactionService(reactionRepoMock, postRepoMock, transactor), outbox
+}
+
+func TestReact(t *testing.T) {
+	reactionRepo, postRepo, service, events := reactionServiceWithMock(t)
+
+	cases := []struct {
+		name     string
+		kind     string
+		mockFunc func()
+		err      error
+	}{
+		{
+			"Unknown kind",
+			"meh",
+			func() {},
+			&services.ValidationError{Fields: map[string][]string{"kind": {"must be one of like, love, laugh, wow, sad"}}},
+		},
+		{
+			"Post not found",
+			models.ReactionLike,
+			func() {
+				postRepo.EXPECT().GetById(gomock.Any(), 3).Return(nil, gorm.ErrRecordNotFound).Times(1)
+			},
+			gorm.ErrRecordNotFound,
+		},
+		{
+			"Success",
+			models.ReactionLike,
+			func() {
+				postRepo.EXPECT().GetById(gomock.Any(), 3).Return(&models.Post{ID: 3, UserID: 2}, nil).Times(1)
+				reactionRepo.EXPECT().Create(gomock.Any(), &models.Reaction{PostID: 3, UserID: 1, Kind: models.ReactionLike}).Return(true, nil).Times(1)
+			},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			err := service.React(context.Background(), 1, 3, c.kind)
+
+			assert.Equal(t, c.err, err)
+		})
+	}
+
+	assert.Equal(t, []models.OutboxEvent{{Type: services.EventPostReacted, ActorID: 1, UserID: 2, PostID: 3, Kind: models.ReactionLike, Data: `{"actor_id":1,"user_id":2,"post_id":3,"kind":"like"}`}}, events.events)
+}
+
+func TestUnreact(t *testing.T) {
+	reactionRepo, postRepo, service, _ := reactionServiceWithMock(t)
+
+	postRepo.EXPECT().GetById(gomock.Any(), 3).Return(&models.Post{ID: 3}, nil).Times(1)
+	reactionRepo.EXPECT().Delete(gomock.Any(), uint(3), uint(1), models.ReactionWow).Return(nil).Times(1)
+
+	assert.NoError(t, service.Unreact(context.Background(), 1, 3, models.ReactionWow))
+}
+
+func TestPostReactions(t *testing.T) {
+	var (
+		ctrl         = gomock.NewController(t)
+		postRepo     = mock_repository.NewMockPostRepo(ctrl)
+		reactionRepo = mock_repository.NewMockReactionRepo(ctrl)
+		service      = services.NewPostService(postRepo, mock_repository.NewMockUserRepo(ctrl), reactionRepo, mock_repository.NewMockAuditRepo(ctrl), &fakeTransactor{})
+	)
+
+	t.Run("Anonymous", func(t *testing.T) {
+		postRepo.EXPECT().GetMostReacted(gomock.Any()).Return([]models.Post{{ID: 1}, {ID: 2}}, nil).Times(1)
+		reactionRepo.EXPECT().CountByPostIds(gomock.Any(), []uint{1, 2}).Return([]repository.ReactionCount{
+			{PostID: 1, Kind: models.ReactionLike, Count: 4},
+			{PostID: 1, Kind: models.ReactionSad, Count: 1},
+		}, nil).Times(1)
+
+		posts, err := service.GetAllPost(context.Background(), services.PostSortMostReacted, 0)
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]int64{"like": 4, "love": 0, "laugh": 0, "wow": 0, "sad": 1}, posts[0].Reactions)
+		assert.Equal(t, int64(0), posts[1].Reactions[models.ReactionLike])
+		assert.Nil(t, posts[0].ViewerReaction)
+	})
+
+	t.Run("Authenticated", func(t *testing.T) {
+		postRepo.EXPECT().GetById(gomock.Any(), 1).Return(&models.Post{ID: 1}, nil).Times(1)
+		reactionRepo.EXPECT().CountByPostIds(gomock.Any(), []uint{1}).Return([]repository.ReactionCount{{PostID: 1, Kind: models.ReactionLike, Count: 1}}, nil).Times(1)
+		reactionRepo.EXPECT().GetByUserAndPostIds(gomock.Any(), uint(5), []uint{1}).Return([]models.Reaction{{PostID: 1, UserID: 5, Kind: models.ReactionLike}}, nil).Times(1)
+
+		post, err := service.GetPostById(context.Background(), 1, 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{models.ReactionLike}, post.ViewerReaction)
+	})
+
+	t.Run("Unknown sort", func(t *testing.T) {
+		_, err := service.GetAllPost(context.Background(), "oldest", 0)
+
+		assert.IsType(t, &services.ValidationError{}, err)
+	})
+}
@@ -0,0 +1,170 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+func sessionServiceWithMock(t *testing.T) (*mock_repository.MockSessionRepo, *services.SessionService) {
+	ctrl := gomock.NewController(t)
+
+	sessionRepoMock := mock_repository.NewMockSessionRepo(ctrl)
+
+	return sessionRepoMock, services.NewSessionService(sessionRepoMock)
+}
+
+func TestGetSessions(t *testing.T) {
+	sessionRepo, service := sessionServiceWithMock(t)
+
+	sessionRepo.EXPECT().GetActiveByUserId(gomock.Any(), uint(1), gomock.Any()).Return([]models.Session{{ID: 3}, {ID: 4}}, nil).Times(1)
+
+	sessions, err := service.GetSessions(context.Background(), 1, "4")
+
+	assert.NoError(t, err)
+	assert.False(t, sessions[0].Current)
+	assert.True(t, sessions[1].Current)
+}
+
+func TestRevokeSession(t *testing.T) {
+	var (
+		sessionRepo, service = sessionServiceWithMock(t)
+		revokedAt            = time.Now()
+	)
+
+	cases := []struct {
+		name     string
+		mockFunc func()
+		err      error
+	}{
+		{
+			"Session of another user",
+			func() {
+				sessionRepo.EXPECT().GetById(gomock.Any(), uint(3)).Return(&models.Session{ID: 3, UserID: 2}, nil).Times(1)
+			},
+			gorm.ErrRecordNotFound,
+		},
+		{
+			"Already revoked",
+			func() {
+				sessionRepo.EXPECT().GetById(gomock.Any(), uint(3)).Return(&models.Session{ID: 3, UserID: 1, RevokedAt: &revokedAt}, nil).Times(1)
+			},
+			gorm.ErrRecordNotFound,
+		},
+		{
+			"Success",
+			func() {
+				sessionRepo.EXPECT().GetById(gomock.Any(), uint(3)).Return(&models.Session{ID: 3, UserID: 1}, nil).Times(1)
+				sessionRepo.EXPECT().Revoke(gomock.Any(), uint(3), gomock.Any()).Return(nil).Times(1)
+			},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			err := service.RevokeSession(context.Background(), 1, 3)
+
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
+
+func TestRevokeOtherSessions(t *testing.T) {
+	sessionRepo, service := sessionServiceWithMock(t)
+
+	t.Run("Keeps the current session", func(t *testing.T) {
+		sessionRepo.EXPECT().RevokeAllByUserId(gomock.Any(), uint(1), uint(4), gomock.Any()).Return(nil).Times(1)
+
+		assert.NoError(t, service.RevokeOtherSessions(context.Background(), 1, "4"))
+	})
+
+	t.Run("Without a current session", func(t *testing.T) {
+		sessionRepo.EXPECT().RevokeAllByUserId(gomock.Any(), uint(1), uint(0), gomock.Any()).Return(nil).Times(1)
+
+		assert.NoError(t, service.RevokeOtherSessions(context.Background(), 1, ""))
+	})
+}
+
+func TestIsSessionActive(t *testing.T) {
+	var (
+		sessionRepo, service = sessionServiceWithMock(t)
+		now                  = time.Now()
+	)
+
+	cases := []struct {
+		name     string
+		mockFunc func()
+		active   bool
+		err      error
+	}{
+		{
+			"Unknown session",
+			func() {
+				sessionRepo.EXPECT().GetById(gomock.Any(), uint(3)).Return(&models.Session{}, gorm.ErrRecordNotFound).Times(1)
+			},
+			false,
+			nil,
+		},
+		{
+			"Revoked session",
+			func() {
+				sessionRepo.EXPECT().GetById(gomock.Any(), uint(3)).Return(&models.Session{ID: 3, LastSeenAt: now, ExpiresAt: now.Add(time.Hour), RevokedAt: &now}, nil).Times(1)
+			},
+			false,
+			nil,
+		},
+		{
+			"Expired session",
+			func() {
+				sessionRepo.EXPECT().GetById(gomock.Any(), uint(3)).Return(&models.Session{ID: 3, LastSeenAt: now, ExpiresAt: now.Add(-time.Hour)}, nil).Times(1)
+			},
+			false,
+			nil,
+		},
+		{
+			"Recently seen session",
+			func() {
+				sessionRepo.EXPECT().GetById(gomock.Any(), uint(3)).Return(&models.Session{ID: 3, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}, nil).Times(1)
+			},
+			true,
+			nil,
+		},
+		{
+			"Last seen is updated",
+			func() {
+				sessionRepo.EXPECT().GetById(gomock.Any(), uint(3)).Return(&models.Session{ID: 3, LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}, nil).Times(1)
+				sessionRepo.EXPECT().TouchLastSeen(gomock.Any(), uint(3), gomock.Any()).Return(nil).Times(1)
+			},
+			true,
+			nil,
+		},
+		{
+			"Database error",
+			func() {
+				sessionRepo.EXPECT().GetById(gomock.Any(), uint(3)).Return(nil, errors.New("connection refused")).Times(1)
+			},
+			false,
+			errors.New("connection refused"),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.mockFunc()
+			active, err := service.IsSessionActive(context.Background(), "3")
+
+			assert.Equal(t, c.active, active)
+			assert.Equal(t, c.err, err)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamDelivery(t *testing.T) {
+	var (
+		service      = services.NewStreamService(10)
+		anonymous, _ = service.Subscribe(0, "")
+		user, _      = service.Subscribe(2, "")
+	)
+
+	service.Publish(context.Background(), services.PostCreated{Post: models.Post{ID: 1, Title: "Hello"}})
+	service.Publish(context.Background(), services.NotificationCreated{EventMeta: services.EventMeta{UserID: 2}, Notification: models.Notification{ID: 4}})
+	// Not a stream event
+	service.Publish(context.Background(), services.UserFollowed{EventMeta: services.EventMeta{UserID: 2}})
+
+	event := <-anonymous.Events
+	assert.Equal(t, uint64(1), event.Seq)
+	assert.Regexp(t, `^\w+-1$`, event.ID)
+	assert.Equal(t, services.EventPostCreated, event.Type)
+	assert.Contains(t, string(event.Data), `"title":"Hello"`)
+	assert.Empty(t, anonymous.Events, "notifications are only sent to the notified user")
+
+	assert.Equal(t, services.EventPostCreated, (<-user.Events).Type)
+	assert.Equal(t, services.EventNotificationCreated, (<-user.Events).Type)
+	assert.Empty(t, user.Events)
+}
+
+func TestStreamResume(t *testing.T) {
+	var (
+		service  = services.NewStreamService(3)
+		first, _ = service.Subscribe(0, "")
+		ids      []string
+	)
+
+	for i := 1; i <= 5; i++ {
+		service.Publish(context.Background(), services.PostUpdated{Post: models.Post{ID: uint(i)}})
+		ids = append(ids, (<-first.Events).ID)
+	}
+
+	t.Run("Missed events are replayed", func(t *testing.T) {
+		_, missed := service.Subscribe(0, ids[2])
+
+		assert.Len(t, missed, 2)
+		assert.Equal(t, ids[3], missed[0].ID)
+	})
+
+	t.Run("Oldest buffered event is next", func(t *testing.T) {
+		_, missed := service.Subscribe(0, ids[1])
+
+		assert.Len(t, missed, 3)
+		assert.Equal(t, ids[2], missed[0].ID)
+	})
+
+	t.Run("Missed events fell out of the buffer", func(t *testing.T) {
+		_, missed := service.Subscribe(0, ids[0])
+
+		assert.Len(t, missed, 1)
+		assert.Equal(t, services.StreamEventReset, missed[0].Type)
+		assert.Equal(t, ids[4], missed[0].ID, "the client resumes from the reset next time")
+	})
+
+	t.Run("Id of a previous run", func(t *testing.T) {
+		restarted := services.NewStreamService(3)
+		restarted.Publish(context.Background(), services.PostUpdated{Post: models.Post{ID: 6}})
+
+		for _, lastEventId := range []string{ids[4], "500", "garbage"} {
+			_, missed := restarted.Subscribe(0, lastEventId)
+
+			assert.Len(t, missed, 1)
+			assert.Equal(t, services.StreamEventReset, missed[0].Type)
+		}
+	})
+
+	t.Run("Up to date", func(t *testing.T) {
+		_, missed := service.Subscribe(0, ids[4])
+
+		assert.Empty(t, missed)
+	})
+
+	t.Run("Not resuming", func(t *testing.T) {
+		_, missed := service.Subscribe(0, "")
+
+		assert.Empty(t, missed)
+	})
+}
+
+func TestStreamSlowClientIsDisconnected(t *testing.T) {
+	var (
+		service         = services.NewStreamService(1000)
+		subscription, _ = service.Subscribe(0, "")
+	)
+
+	for i := 0; i < 100; i++ {
+		service.Publish(context.Background(), services.PostDeleted{EventMeta: services.EventMeta{PostID: uint(i)}})
+	}
+
+	received := 0
+	for range subscription.Events {
+		received++
+	}
+
+	assert.Less(t, received, 100)
+	// Unsubscribing a disconnected client is a no-op
+	service.Unsubscribe(subscription)
+}
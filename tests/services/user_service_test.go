@@ -1,12 +1,14 @@
 package services_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	// "github.com/simple-crud-go/internal/helper"
 	mock_helper "github.com/simple-crud-go/internal/helper/mocks"
 	"github.com/simple-crud-go/internal/models"
+	"github.com/simple-crud-go/internal/repository"
 	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
 	"github.com/simple-crud-go/internal/services"
 	"github.com/stretchr/testify/assert"
@@ -16,15 +18,22 @@ import (
 
 var errUnexpected = errors.New("unexpected")
 
-func userServiceWithMock(t *testing.T) (*mock_repository.MockUserRepo, *services.UserService, *mock_helper.MockPasswordCrypto) {
+func userServiceWithMock(t *testing.T) (*mock_repository.MockUserRepo, *services.UserService, *mock_helper.MockPasswordCrypto, *mock_helper.MockPasswordPolicy, *mock_repository.MockFollowRepo) {
 	ctrl := gomock.NewController(t)
 
 	userRepoMock := mock_repository.NewMockUserRepo(ctrl)
 	passwordCryptoMock := mock_helper.NewMockPasswordCrypto(ctrl)
+	passwordPolicyMock := mock_helper.NewMockPasswordPolicy(ctrl)
+	auditRepoMock := mock_repository.NewMockAuditRepo(ctrl)
+	followRepoMock := mock_repository.NewMockFollowRepo(ctrl)
 
-	service := services.NewUserService(userRepoMock, passwordCryptoMock)
+	// What gets recorded is covered by the audit tests
+	auditRepoMock.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
 
-	return userRepoMock, service, passwordCryptoMock
+	transactor, _ := transactorWith(repository.Repositories{Users: userRepoMock})
+	service := services.NewUserService(userRepoMock, followRepoMock, auditRepoMock, passwordCryptoMock, passwordPolicyMock, transactor)
+
+	return userRepoMock, service, passwordCryptoMock, passwordPolicyMock, followRepoMock
 }
 
 func TestGetUserById(t *testing.T) {
@@ -36,7 +45,7 @@ func TestGetUserById(t *testing.T) {
 			Password: "dummy",
 		}
 
-		userRepoMock, service, _ = userServiceWithMock(t)
+		userRepoMock, service, _, _, _ = userServiceWithMock(t)
 	)
 
 	cases := []struct {
@@ -48,7 +57,7 @@ func TestGetUserById(t *testing.T) {
 		{
 			"User Not Found",
 			func() {
-				userRepoMock.EXPECT().GetById(uint(1)).Return(nil, gorm.ErrRecordNotFound).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(nil, gorm.ErrRecordNotFound).Times(1)
 			},
 			gorm.ErrRecordNotFound,
 			nil,
@@ -56,7 +65,7 @@ func TestGetUserById(t *testing.T) {
 		{
 			"Unknown Error",
 			func() {
-				userRepoMock.EXPECT().GetById(uint(1)).Return(nil, errUnexpected).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(nil, errUnexpected).Times(1)
 			},
 			errUnexpected,
 			nil,
@@ -64,7 +73,7 @@ func TestGetUserById(t *testing.T) {
 		{
 			"Success",
 			func() {
-				userRepoMock.EXPECT().GetById(uint(1)).Return(&user, nil).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(&user, nil).Times(1)
 			},
 			nil,
 			&user,
@@ -74,7 +83,7 @@ func TestGetUserById(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			u, err := service.GetUserById(1)
+			u, err := service.GetUserById(context.Background(), 1)
 			assert.Equal(t, err, c.err)
 			assert.Equal(t, u, c.user)
 		})
@@ -82,23 +91,23 @@ func TestGetUserById(t *testing.T) {
 
 	/// This Code below are the same as above code.
 	// // No user with the following id (Record Not Found)
-	// userRepoMock.EXPECT().GetById(uint(1)).Return(nil, gorm.ErrRecordNotFound).Times(1)
+	// userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(nil, gorm.ErrRecordNotFound).Times(1)
 	//
-	// _, err := service.GetUserById(1)
+	// _, err := service.GetUserById(context.Background(), 1)
 	//
 	// assert.Equal(t, err, gorm.ErrRecordNotFound)
 	//
 	// // Unknown Error
-	// userRepoMock.EXPECT().GetById(uint(1)).Return(nil, errUnexpected).Times(1)
+	// userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(nil, errUnexpected).Times(1)
 	//
-	// _, err = service.GetUserById(1)
+	// _, err = service.GetUserById(context.Background(), 1)
 	//
 	// assert.Equal(t, err, errUnexpected)
 	//
 	// // Success
-	// userRepoMock.EXPECT().GetById(uint(1)).Return(&user, nil).Times(1)
+	// userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(&user, nil).Times(1)
 	//
-	// res, err := service.GetUserById(1)
+	// res, err := service.GetUserById(context.Background(), 1)
 	//
 	// assert.Nil(t, err)
 	// assert.Equal(t, res.Username, user.Username)
@@ -114,7 +123,7 @@ func TestGetUserByUsername(t *testing.T) {
 			Password: "dummy",
 		}
 
-		userRepoMock, service, _ = userServiceWithMock(t)
+		userRepoMock, service, _, _, followRepoMock = userServiceWithMock(t)
 	)
 
 	cases := []struct {
@@ -126,7 +135,7 @@ func TestGetUserByUsername(t *testing.T) {
 		{
 			"User Not Found",
 			func() {
-				userRepoMock.EXPECT().GetByUsername(username).Return(nil, gorm.ErrRecordNotFound).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), username).Return(nil, gorm.ErrRecordNotFound).Times(1)
 			},
 			gorm.ErrRecordNotFound,
 			nil,
@@ -134,7 +143,7 @@ func TestGetUserByUsername(t *testing.T) {
 		{
 			"Unknown Error",
 			func() {
-				userRepoMock.EXPECT().GetByUsername(username).Return(nil, errUnexpected).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), username).Return(nil, errUnexpected).Times(1)
 			},
 			errUnexpected,
 			nil,
@@ -142,7 +151,9 @@ func TestGetUserByUsername(t *testing.T) {
 		{
 			"Success",
 			func() {
-				userRepoMock.EXPECT().GetByUsername(username).Return(&user, nil).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), username).Return(&user, nil).Times(1)
+				followRepoMock.EXPECT().CountFollowers(gomock.Any(), uint(1)).Return(int64(3), nil).Times(1)
+				followRepoMock.EXPECT().CountFollowing(gomock.Any(), uint(1)).Return(int64(5), nil).Times(1)
 			},
 			nil,
 			&user,
@@ -152,11 +163,14 @@ func TestGetUserByUsername(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			u, err := service.GetUserByUsername(username)
+			u, err := service.GetUserByUsername(context.Background(), username)
 			assert.Equal(t, err, c.err)
 			assert.Equal(t, u, c.user)
 		})
 	}
+
+	assert.Equal(t, int64(3), *user.FollowersCount)
+	assert.Equal(t, int64(5), *user.FollowingCount)
 }
 
 func TestGetAllUser(t *testing.T) {
@@ -176,7 +190,7 @@ func TestGetAllUser(t *testing.T) {
 			},
 		}
 
-		userRepoMock, service, _ = userServiceWithMock(t)
+		userRepoMock, service, _, _, _ = userServiceWithMock(t)
 	)
 
 	cases := []struct {
@@ -188,7 +202,7 @@ func TestGetAllUser(t *testing.T) {
 		{
 			"Unknown Error",
 			func() {
-				userRepoMock.EXPECT().GetAll().Return(nil, errUnexpected).Times(1)
+				userRepoMock.EXPECT().GetAll(gomock.Any()).Return(nil, errUnexpected).Times(1)
 			},
 			errUnexpected,
 			nil,
@@ -196,7 +210,7 @@ func TestGetAllUser(t *testing.T) {
 		{
 			"Success",
 			func() {
-				userRepoMock.EXPECT().GetAll().Return(users, nil).Times(1)
+				userRepoMock.EXPECT().GetAll(gomock.Any()).Return(users, nil).Times(1)
 			},
 			nil,
 			users,
@@ -206,7 +220,7 @@ func TestGetAllUser(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			u, err := service.GetAllUser()
+			u, err := service.GetAllUser(context.Background())
 			assert.Equal(t, err, c.err)
 			assert.Equal(t, u, c.users)
 		})
@@ -229,7 +243,7 @@ func TestCreateUser(t *testing.T) {
 			Password: hashedPass,
 		}
 
-		userRepoMock, service, passwordCryptoMock = userServiceWithMock(t)
+		userRepoMock, service, passwordCryptoMock, passwordPolicyMock, _ = userServiceWithMock(t)
 	)
 
 	cases := []struct {
@@ -240,31 +254,51 @@ func TestCreateUser(t *testing.T) {
 		{
 			"Unknown Error on Getting the username",
 			func() {
-				userRepoMock.EXPECT().GetByUsername(newUser.Username).Return(nil, errUnexpected).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newUser.Username).Return(nil, errUnexpected).Times(1)
 			},
 			errUnexpected,
 		},
 		{
 			"User with the same Username exists",
 			func() {
-				userRepoMock.EXPECT().GetByUsername(newUser.Username).Return(&foundUser, nil).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newUser.Username).Return(&foundUser, nil).Times(1)
 			},
 			services.ErrUserExist,
 		},
+		{
+			"Password violates the password policy",
+			func() {
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newUser.Username).Return(&models.User{ID: 0}, gorm.ErrRecordNotFound).Times(1)
+				passwordPolicyMock.EXPECT().Validate(newUser.Password, newUser.Username, newUser.Name).Return([]string{"must contain a digit"}, nil).Times(1)
+			},
+			&services.ValidationError{Fields: map[string][]string{"password": {"must contain a digit"}}},
+		},
 		{
 			"Unknown Error when Hasing the password",
 			func() {
-				userRepoMock.EXPECT().GetByUsername(newUser.Username).Return(&models.User{ID: 0}, gorm.ErrRecordNotFound).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newUser.Username).Return(&models.User{ID: 0}, gorm.ErrRecordNotFound).Times(1)
+				passwordPolicyMock.EXPECT().Validate(newUser.Password, newUser.Username, newUser.Name).Return([]string{}, nil).Times(1)
 				passwordCryptoMock.EXPECT().HashPassword(newUser.Password).Return("", errUnexpected).Times(1)
 			},
 			errUnexpected,
 		},
+		{
+			"Username taken since it was looked up",
+			func() {
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newUser.Username).Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+				passwordPolicyMock.EXPECT().Validate(newUser.Password, newUser.Username, newUser.Name).Return([]string{}, nil).Times(1)
+				passwordCryptoMock.EXPECT().HashPassword(newUser.Password).Return(hashedPass, nil).Times(1)
+				userRepoMock.EXPECT().Create(gomock.Any(), &newUser).Return(gorm.ErrDuplicatedKey).Times(1)
+			},
+			services.ErrUserExist,
+		},
 		{
 			"Success",
 			func() {
-				userRepoMock.EXPECT().GetByUsername(newUser.Username).Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newUser.Username).Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+				passwordPolicyMock.EXPECT().Validate(newUser.Password, newUser.Username, newUser.Name).Return([]string{}, nil).Times(1)
 				passwordCryptoMock.EXPECT().HashPassword(newUser.Password).Return(hashedPass, nil).Times(1)
-				userRepoMock.EXPECT().Create(newUser).Return(nil).Times(1)
+				userRepoMock.EXPECT().Create(gomock.Any(), &newUser).Return(nil).Times(1)
 			},
 			nil,
 		},
@@ -273,7 +307,7 @@ func TestCreateUser(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			err := service.CreateUser(newUser.Username, newUser.Name, newUser.Password)
+			err := service.CreateUser(context.Background(), newUser.Username, newUser.Name, newUser.Password)
 			assert.Equal(t, err, c.err)
 		})
 	}
@@ -283,7 +317,7 @@ func TestUpdateUser(t *testing.T) {
 	var (
 		hashedPass       = "dummy"
 		userSameUsername = models.User{
-			ID:       1,
+			ID:       3,
 			Name:     "Ibka",
 			Username: "ibkaanhar2",
 			Password: "abc",
@@ -307,7 +341,7 @@ func TestUpdateUser(t *testing.T) {
 			Password: hashedPass,
 		}
 
-		userRepoMock, service, passwordCryptoMock = userServiceWithMock(t)
+		userRepoMock, service, passwordCryptoMock, passwordPolicyMock, _ = userServiceWithMock(t)
 	)
 
 	cases := []struct {
@@ -318,38 +352,48 @@ func TestUpdateUser(t *testing.T) {
 		{
 			"User not found and unknown error on GetById",
 			func() {
-				userRepoMock.EXPECT().GetById(newDataUser.ID).Return(nil, gorm.ErrRecordNotFound).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), newDataUser.ID).Return(nil, gorm.ErrRecordNotFound).Times(1)
 			},
 			gorm.ErrRecordNotFound,
 		},
 		{
 			"Logged in user and User to be updated ID doesn't match",
 			func() {
-				userRepoMock.EXPECT().GetById(newDataUser.ID).Return(&userDiffID, nil).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), newDataUser.ID).Return(&userDiffID, nil).Times(1)
 			},
 			services.ErrMismatchID,
 		},
 		{
 			"Unknown Error when checking another user with the username",
 			func() {
-				userRepoMock.EXPECT().GetById(newDataUser.ID).Return(&userDiffUsername, nil).Times(1)
-				userRepoMock.EXPECT().GetByUsername(newDataUser.Username).Return(nil, errUnexpected).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), newDataUser.ID).Return(&userDiffUsername, nil).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newDataUser.Username).Return(nil, errUnexpected).Times(1)
 			},
 			errUnexpected,
 		},
 		{
 			"New Username already used by another user",
 			func() {
-				userRepoMock.EXPECT().GetById(newDataUser.ID).Return(&userDiffUsername, nil).Times(1)
-				userRepoMock.EXPECT().GetByUsername(newDataUser.Username).Return(&userSameUsername, nil).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), newDataUser.ID).Return(&userDiffUsername, nil).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newDataUser.Username).Return(&userSameUsername, nil).Times(1)
 			},
 			services.ErrUserExist,
 		},
+		{
+			"Password violates the password policy",
+			func() {
+				userRepoMock.EXPECT().GetById(gomock.Any(), newDataUser.ID).Return(&userDiffUsername, nil).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newDataUser.Username).Return(&models.User{}, nil).Times(1)
+				passwordPolicyMock.EXPECT().Validate(newDataUser.Password, newDataUser.Username, newDataUser.Name).Return([]string{"must not contain your username or name"}, nil).Times(1)
+			},
+			&services.ValidationError{Fields: map[string][]string{"password": {"must not contain your username or name"}}},
+		},
 		{
 			"Unknown error when hashing password",
 			func() {
-				userRepoMock.EXPECT().GetById(newDataUser.ID).Return(&userDiffUsername, nil).Times(1)
-				userRepoMock.EXPECT().GetByUsername(newDataUser.Username).Return(&models.User{}, nil).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), newDataUser.ID).Return(&userDiffUsername, nil).Times(1)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newDataUser.Username).Return(&models.User{}, nil).Times(1)
+				passwordPolicyMock.EXPECT().Validate(newDataUser.Password, newDataUser.Username, newDataUser.Name).Return([]string{}, nil).Times(1)
 				passwordCryptoMock.EXPECT().HashPassword(newDataUser.Password).Return("", errUnexpected).Times(1)
 			},
 			errUnexpected,
@@ -357,11 +401,12 @@ func TestUpdateUser(t *testing.T) {
 		{
 			"Success",
 			func() {
-				userDiffUsername.Username = "ibkaanhar2" // reset
-				userRepoMock.EXPECT().GetById(newDataUser.ID).Return(&userDiffUsername, nil).Times(1)
-				userRepoMock.EXPECT().GetByUsername(newDataUser.Username).Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+				// Read again in the transaction
+				userRepoMock.EXPECT().GetById(gomock.Any(), newDataUser.ID).Return(&userDiffUsername, nil).Times(2)
+				userRepoMock.EXPECT().GetByUsername(gomock.Any(), newDataUser.Username).Return(&models.User{}, gorm.ErrRecordNotFound).Times(1)
+				passwordPolicyMock.EXPECT().Validate(newDataUser.Password, newDataUser.Username, newDataUser.Name).Return([]string{}, nil).Times(1)
 				passwordCryptoMock.EXPECT().HashPassword(newDataUser.Password).Return(hashedPass, nil).Times(1)
-				userRepoMock.EXPECT().Update(newDataUser).Return(nil).Times(1)
+				userRepoMock.EXPECT().Update(gomock.Any(), newDataUser, "username", "name", "password").Return(nil).Times(1)
 			},
 			nil,
 		},
@@ -370,7 +415,7 @@ func TestUpdateUser(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			err := service.UpdateUser(int(newDataUser.ID), newDataUser.Username, newDataUser.Name, newDataUser.Password)
+			err := service.UpdateUser(context.Background(), int(newDataUser.ID), newDataUser.Username, newDataUser.Name, newDataUser.Password, services.ClientInfo{})
 			assert.Equal(t, err, c.err)
 		})
 	}
@@ -392,7 +437,7 @@ func TestDeleteUser(t *testing.T) {
 			Password: hashedPass,
 		}
 
-		userRepoMock, service, _ = userServiceWithMock(t)
+		userRepoMock, service, _, _, _ = userServiceWithMock(t)
 	)
 
 	cases := []struct {
@@ -403,30 +448,30 @@ func TestDeleteUser(t *testing.T) {
 		{
 			"Unknown error when getting the user with the passed in id",
 			func() {
-				userRepoMock.EXPECT().GetById(uint(1)).Return(nil, errUnexpected).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(nil, errUnexpected).Times(1)
 			},
 			errUnexpected,
 		},
 		{
 			"Logged in user and User to be deleted ID doesn't match",
 			func() {
-				userRepoMock.EXPECT().GetById(uint(1)).Return(&existingDiffUser, nil).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(&existingDiffUser, nil).Times(1)
 			},
 			services.ErrMismatchID,
 		},
 		{
 			"Unknown Error when deleting the user",
 			func() {
-				userRepoMock.EXPECT().GetById(uint(1)).Return(&existingUser, nil).Times(1)
-				userRepoMock.EXPECT().DeleteById(uint(1)).Return(errUnexpected).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(&existingUser, nil).Times(1)
+				userRepoMock.EXPECT().DeleteById(gomock.Any(), uint(1)).Return(errUnexpected).Times(1)
 			},
 			errUnexpected,
 		},
 		{
 			"Success",
 			func() {
-				userRepoMock.EXPECT().GetById(uint(1)).Return(&existingUser, nil).Times(1)
-				userRepoMock.EXPECT().DeleteById(uint(1)).Return(nil).Times(1)
+				userRepoMock.EXPECT().GetById(gomock.Any(), uint(1)).Return(&existingUser, nil).Times(1)
+				userRepoMock.EXPECT().DeleteById(gomock.Any(), uint(1)).Return(nil).Times(1)
 			},
 			nil,
 		},
@@ -435,7 +480,7 @@ func TestDeleteUser(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			c.mockFunc()
-			err := service.DeleteUserById(1)
+			err := service.DeleteUserById(context.Background(), 1, services.ClientInfo{})
 
 			assert.Equal(t, err, c.err)
 		})
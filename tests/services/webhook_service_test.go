@@ -0,0 +1,318 @@
+package services_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/simple-crud-go/internal/models"
+	mock_repository "github.com/simple-crud-go/internal/repository/mocks"
+	"github.com/simple-crud-go/internal/services"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
+)
+
+func webhookServiceWithMock(t *testing.T) (*mock_repository.MockWebhookRepo, *services.WebhookService) {
+	ctrl := gomock.NewController(t)
+
+	webhookRepoMock := mock_repository.NewMockWebhookRepo(ctrl)
+
+	return webhookRepoMock, services.NewWebhookService(webhookRepoMock, services.WebhookConfig{
+		MaxAttempts:  3,
+		RetryBase:    time.Minute,
+		DisableAfter: 2,
+		Timeout:      time.Second,
+		// The receivers of the tests listen on the loopback
+		AllowPrivateNetworks: true,
+	})
+}
+
+// webhookReceiver records the requests it receives and answers with `status`.
+func webhookReceiver(t *testing.T, status int) (*httptest.Server, *[]*http.Request, *[]string) {
+	var (
+		requests []*http.Request
+		bodies   []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests, &bodies
+}
+
+func TestCreateWebhook(t *testing.T) {
+	webhookRepo, service := webhookServiceWithMock(t)
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := service.CreateWebhook(context.Background(), 2, "ftp://example.com", []string{"post.created", "post.viewed"}, false)
+
+		var validationErr *services.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Contains(t, validationErr.Fields, "url")
+		assert.Len(t, validationErr.Fields["events"], 1)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		webhookRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		data, err := service.CreateWebhook(context.Background(), 2, "https://example.com/hook", []string{services.EventPostCreated}, false)
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(data.Secret, "whsec_"))
+		assert.Equal(t, data.Secret, data.Webhook.Secret)
+		assert.Equal(t, uint(2), data.Webhook.UserID)
+	})
+}
+
+func TestWebhookPrivateNetworks(t *testing.T) {
+	var (
+		ctrl        = gomock.NewController(t)
+		webhookRepo = mock_repository.NewMockWebhookRepo(ctrl)
+		service     = services.NewWebhookService(webhookRepo, services.WebhookConfig{MaxAttempts: 3, RetryBase: time.Minute, Timeout: time.Second})
+	)
+
+	t.Run("Registration", func(t *testing.T) {
+		for _, rawURL := range []string{
+			"http://127.0.0.1/hook",
+			"http://localhost:8080/hook",
+			"http://169.254.169.254/latest/meta-data",
+			"http://10.0.0.1/hook",
+			"http://192.168.1.1/hook",
+			"http://0.0.0.0/hook",
+			"http://[::1]/hook",
+			"http://[::ffff:127.0.0.1]/hook",
+			"http://[fe80::1]/hook",
+		} {
+			_, err := service.CreateWebhook(context.Background(), 2, rawURL, []string{services.EventPostCreated}, false)
+
+			var validationErr *services.ValidationError
+			if assert.ErrorAs(t, err, &validationErr, rawURL) {
+				assert.Equal(t, []string{"must point to a public address"}, validationErr.Fields["url"], rawURL)
+			}
+		}
+	})
+
+	t.Run("Delivery", func(t *testing.T) {
+		var (
+			// Registered while it resolved to a public address
+			server, requests, _ = webhookReceiver(t, http.StatusOK)
+			webhook             = models.Webhook{ID: 1, URL: server.URL}
+		)
+
+		webhookRepo.EXPECT().ClaimDueDeliveries(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.WebhookDelivery{
+			{ID: 5, WebhookID: 1, Webhook: &webhook, Status: models.WebhookDeliveryPending},
+		}, nil).Times(1)
+		webhookRepo.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, delivery *models.WebhookDelivery) error {
+			assert.Nil(t, delivery.ResponseStatus)
+			assert.Contains(t, delivery.Error, "the webhook address isn't public")
+			return nil
+		}).Times(1)
+		webhookRepo.EXPECT().RecordFailure(gomock.Any(), uint(1), 0, gomock.Any()).Return(&models.Webhook{ID: 1, ConsecutiveFailures: 1}, nil).Times(1)
+
+		service.DeliverDue(context.Background())
+
+		assert.Empty(t, *requests)
+	})
+}
+
+func TestWebhookPublish(t *testing.T) {
+	webhookRepo, service := webhookServiceWithMock(t)
+
+	t.Run("Not a webhook event", func(t *testing.T) {
+		assert.NoError(t, service.Publish(context.Background(), services.NotificationCreated{EventMeta: services.EventMeta{UserID: 2}}))
+	})
+
+	t.Run("Subscribed webhooks involving their owner", func(t *testing.T) {
+		webhookRepo.EXPECT().GetEnabled(gomock.Any()).Return([]models.Webhook{
+			{ID: 1, UserID: 2, Events: []string{services.EventPostCreated}},
+			{ID: 2, UserID: 2, Events: []string{services.EventPostDeleted}},
+			{ID: 3, UserID: 3, Events: []string{services.EventPostCreated}},
+			{ID: 4, UserID: 4, Events: []string{services.EventPostCreated}, Global: true},
+		}, nil).Times(1)
+
+		var delivered []uint
+		webhookRepo.EXPECT().CreateDeliveries(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, deliveries []models.WebhookDelivery) error {
+			for _, delivery := range deliveries {
+				assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+				assert.Contains(t, delivery.Payload, `"event":"post.created"`)
+				delivered = append(delivered, delivery.WebhookID)
+			}
+			return nil
+		}).Times(1)
+
+		assert.NoError(t, service.Publish(context.Background(), services.PostCreated{EventMeta: services.EventMeta{ActorID: 2, UserID: 2, PostID: 7}, Post: models.Post{ID: 7}}))
+
+		assert.Equal(t, []uint{1, 4}, delivered)
+	})
+
+	t.Run("Deliveries can't be queued", func(t *testing.T) {
+		webhookRepo.EXPECT().GetEnabled(gomock.Any()).Return([]models.Webhook{{ID: 1, UserID: 2, Events: []string{services.EventPostCreated}}}, nil).Times(1)
+		webhookRepo.EXPECT().CreateDeliveries(gomock.Any(), gomock.Any()).Return(errUnexpected).Times(1)
+
+		// Returned so the event is published again
+		assert.ErrorIs(t, service.Publish(context.Background(), services.PostCreated{EventMeta: services.EventMeta{UserID: 2}}), errUnexpected)
+	})
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	t.Run("Signed payload", func(t *testing.T) {
+		var (
+			webhookRepo, service     = webhookServiceWithMock(t)
+			server, requests, bodies = webhookReceiver(t, http.StatusNoContent)
+			webhook                  = models.Webhook{ID: 1, URL: server.URL, Secret: "whsec_secret", ConsecutiveFailures: 1}
+			delivery                 = models.WebhookDelivery{ID: 5, WebhookID: 1, Webhook: &webhook, Event: services.EventPostCreated, Payload: `{"event":"post.created"}`, Status: models.WebhookDeliveryPending}
+		)
+
+		webhookRepo.EXPECT().ClaimDueDeliveries(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.WebhookDelivery{delivery}, nil).Times(1)
+		webhookRepo.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, delivery *models.WebhookDelivery) error {
+			assert.Equal(t, models.WebhookDeliverySucceeded, delivery.Status)
+			assert.Equal(t, http.StatusNoContent, *delivery.ResponseStatus)
+			assert.Equal(t, 1, delivery.Attempts)
+			assert.Nil(t, delivery.NextAttemptAt)
+			return nil
+		}).Times(1)
+		webhookRepo.EXPECT().ResetFailures(gomock.Any(), uint(1)).Return(nil).Times(1)
+
+		assert.Equal(t, 1, service.DeliverDue(context.Background()))
+		assert.Len(t, *requests, 1)
+
+		req := (*requests)[0]
+		timestamp, err := strconv.ParseInt(req.Header.Get(services.WebhookTimestampHeader), 10, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, services.SignWebhookPayload("whsec_secret", timestamp, []byte((*bodies)[0])), req.Header.Get(services.WebhookSignatureHeader))
+		assert.Equal(t, services.EventPostCreated, req.Header.Get(services.WebhookEventHeader))
+		assert.Equal(t, "5", req.Header.Get(services.WebhookDeliveryHeader))
+		assert.Equal(t, delivery.Payload, (*bodies)[0])
+		assert.Equal(t, 0, webhook.ConsecutiveFailures)
+	})
+
+	t.Run("Retried with a backoff", func(t *testing.T) {
+		var (
+			webhookRepo, service = webhookServiceWithMock(t)
+			server, _, _         = webhookReceiver(t, http.StatusInternalServerError)
+			webhook              = models.Webhook{ID: 1, URL: server.URL}
+			delivery             = models.WebhookDelivery{ID: 5, WebhookID: 1, Webhook: &webhook, Attempts: 1, Status: models.WebhookDeliveryPending}
+		)
+
+		webhookRepo.EXPECT().ClaimDueDeliveries(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.WebhookDelivery{delivery}, nil).Times(1)
+		webhookRepo.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, delivery *models.WebhookDelivery) error {
+			assert.Equal(t, models.WebhookDeliveryPending, delivery.Status)
+			assert.Equal(t, http.StatusInternalServerError, *delivery.ResponseStatus)
+			assert.Equal(t, "unexpected response status 500", delivery.Error)
+			// Second failed attempt, twice the base delay
+			assert.WithinDuration(t, time.Now().Add(2*time.Minute), *delivery.NextAttemptAt, 5*time.Second)
+			return nil
+		}).Times(1)
+		webhookRepo.EXPECT().RecordFailure(gomock.Any(), uint(1), 2, gomock.Any()).Return(&models.Webhook{ID: 1, ConsecutiveFailures: 1}, nil).Times(1)
+
+		service.DeliverDue(context.Background())
+
+		assert.Equal(t, 1, webhook.ConsecutiveFailures)
+		assert.Nil(t, webhook.DisabledAt)
+	})
+
+	t.Run("Given up after the last attempt", func(t *testing.T) {
+		var (
+			webhookRepo, service = webhookServiceWithMock(t)
+			webhook              = models.Webhook{ID: 1, URL: "http://127.0.0.1:0"}
+			delivery             = models.WebhookDelivery{ID: 5, WebhookID: 1, Webhook: &webhook, Attempts: 2, Status: models.WebhookDeliveryPending}
+		)
+
+		webhookRepo.EXPECT().ClaimDueDeliveries(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.WebhookDelivery{delivery}, nil).Times(1)
+		webhookRepo.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, delivery *models.WebhookDelivery) error {
+			assert.Equal(t, models.WebhookDeliveryFailed, delivery.Status)
+			assert.Nil(t, delivery.ResponseStatus)
+			assert.NotEmpty(t, delivery.Error)
+			assert.Nil(t, delivery.NextAttemptAt)
+			return nil
+		}).Times(1)
+		webhookRepo.EXPECT().RecordFailure(gomock.Any(), uint(1), 2, gomock.Any()).Return(&models.Webhook{ID: 1, ConsecutiveFailures: 1}, nil).Times(1)
+
+		service.DeliverDue(context.Background())
+	})
+
+	t.Run("Disabled after repeated failures", func(t *testing.T) {
+		var (
+			webhookRepo, service = webhookServiceWithMock(t)
+			server, requests, _  = webhookReceiver(t, http.StatusGone)
+			webhook              = models.Webhook{ID: 1, URL: server.URL, ConsecutiveFailures: 1}
+		)
+
+		webhookRepo.EXPECT().ClaimDueDeliveries(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return([]models.WebhookDelivery{
+			{ID: 5, WebhookID: 1, Webhook: &webhook, Status: models.WebhookDeliveryPending},
+			{ID: 6, WebhookID: 1, Webhook: &models.Webhook{ID: 1, URL: server.URL, ConsecutiveFailures: 1}, Status: models.WebhookDeliveryPending},
+		}, nil).Times(1)
+		webhookRepo.EXPECT().UpdateDelivery(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+		webhookRepo.EXPECT().RecordFailure(gomock.Any(), uint(1), 2, gomock.Any()).DoAndReturn(func(ctx context.Context, id uint, disableAfter int, disabledAt time.Time) (*models.Webhook, error) {
+			return &models.Webhook{ID: 1, URL: server.URL, ConsecutiveFailures: 2, DisabledAt: &disabledAt}, nil
+		}).Times(1)
+		// Not left claimed by the lease
+		webhookRepo.EXPECT().ReleaseDeliveries(gomock.Any(), []uint{6}, gomock.Any()).Return(nil).Times(1)
+
+		service.DeliverDue(context.Background())
+
+		assert.Equal(t, 2, webhook.ConsecutiveFailures)
+		assert.NotNil(t, webhook.DisabledAt)
+		// The second delivery waits until the webhook is enabled again
+		assert.Len(t, *requests, 1)
+	})
+}
+
+func TestWebhookRedeliver(t *testing.T) {
+	var (
+		webhookRepo, service = webhookServiceWithMock(t)
+		webhook              = models.Webhook{ID: 1, UserID: 2}
+	)
+
+	t.Run("Webhook of another user", func(t *testing.T) {
+		webhookRepo.EXPECT().GetById(gomock.Any(), uint(1)).Return(&webhook, nil).Times(1)
+
+		_, err := service.Redeliver(context.Background(), 3, 1, 5)
+
+		assert.Equal(t, gorm.ErrRecordNotFound, err)
+	})
+
+	t.Run("Delivery of another webhook", func(t *testing.T) {
+		webhookRepo.EXPECT().GetById(gomock.Any(), uint(1)).Return(&webhook, nil).Times(1)
+		webhookRepo.EXPECT().GetDeliveryById(gomock.Any(), uint(5)).Return(&models.WebhookDelivery{ID: 5, WebhookID: 9}, nil).Times(1)
+
+		_, err := service.Redeliver(context.Background(), 2, 1, 5)
+
+		assert.Equal(t, gorm.ErrRecordNotFound, err)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		webhookRepo.EXPECT().GetById(gomock.Any(), uint(1)).Return(&webhook, nil).Times(1)
+		webhookRepo.EXPECT().GetDeliveryById(gomock.Any(), uint(5)).Return(&models.WebhookDelivery{ID: 5, WebhookID: 1, Event: services.EventPostCreated, Payload: "{}", Status: models.WebhookDeliveryFailed, Attempts: 3}, nil).Times(1)
+		webhookRepo.EXPECT().CreateDelivery(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+		redelivery, err := service.Redeliver(context.Background(), 2, 1, 5)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.WebhookDeliveryPending, redelivery.Status)
+		assert.Equal(t, 0, redelivery.Attempts)
+		assert.Equal(t, "{}", redelivery.Payload)
+		assert.Equal(t, uint(5), *redelivery.RedeliveryOf)
+	})
+
+	t.Run("Disabled webhook", func(t *testing.T) {
+		now := time.Now()
+		webhookRepo.EXPECT().GetById(gomock.Any(), uint(1)).Return(&models.Webhook{ID: 1, UserID: 2, DisabledAt: &now}, nil).Times(1)
+
+		_, err := service.Redeliver(context.Background(), 2, 1, 5)
+
+		var validationErr *services.ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+	})
+}